@@ -0,0 +1,125 @@
+// Command import sends drafts played outside the tool, e.g. in the game
+// client during an outage, to the import API of a running server. Files are
+// drafts in the JSON or CSV export format; CSV files only hold the actions,
+// the flags fill in the rest.
+//
+//	import -server http://localhost:8080 -token $ADMIN_TOKEN game1.json game2.csv
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "base URL of the server")
+	path := flag.String("path", "/imports", "path of the import API (IMPORTS_PATH)")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "admin token, ADMIN_TOKEN by default")
+	tournament := flag.String("tournament", "", "CSV: tournament of the game")
+	match := flag.String("match", "", "CSV: match of the game")
+	patch := flag.String("patch", "", "CSV: game patch")
+	winner := flag.String("winner", "", "CSV: winning side, blue or red")
+	duration := flag.Int("duration", 0, "CSV: game time in seconds")
+	completedAt := flag.String("completed-at", "", "CSV: date (YYYY-MM-DD) or RFC 3339 time the game was played")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] draft.json|draft.csv...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"tournament":   *tournament,
+		"match":        *match,
+		"patch":        *patch,
+		"winner":       *winner,
+		"completed_at": *completedAt,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if *duration > 0 {
+		query.Set("duration", strconv.Itoa(*duration))
+	}
+	endpoint := strings.TrimSuffix(*server, "/") + *path + "/drafts"
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	failed := false
+	for _, file := range flag.Args() {
+		roomId, err := importFile(client, endpoint, query, *token, file)
+		if err != nil {
+			log.Printf("%s: %v", file, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: imported as room %s\n", file, roomId)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// importFile posts a draft file and returns the ID of the room it was stored as
+func importFile(client *http.Client, endpoint string, query url.Values, token, file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	contentType := "application/json"
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		contentType = "text/csv"
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		var apiErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+			return "", fmt.Errorf("%s: %s", apiErr.Code, apiErr.Message)
+		}
+		return "", fmt.Errorf("server answered %s", resp.Status)
+	}
+	var draft struct {
+		RoomId string `json:"room_id"`
+	}
+	if err := json.Unmarshal(body, &draft); err != nil {
+		return "", fmt.Errorf("unexpected response: %v", err)
+	}
+	return draft.RoomId, nil
+}
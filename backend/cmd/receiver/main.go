@@ -0,0 +1,123 @@
+// Command receiver is a local webhook endpoint for testing the webhooks of a
+// running server. It checks the signature of every delivery and prints its
+// event; -fail answers 503 to the first deliveries to exercise the retries.
+//
+//	receiver -addr 127.0.0.1:9000 -secret $WEBHOOK_SECRET
+//
+// Register it with POST /webhooks {"url": "http://127.0.0.1:9000/"}, passing
+// the same secret or reading the generated one from the response.
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:9000", "address to listen on")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "webhook secret, WEBHOOK_SECRET by default; signatures are not checked if empty")
+	fail := flag.Int("fail", 0, "answer 503 to this many deliveries before accepting them")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of a delivery timestamp")
+	verbose := flag.Bool("v", false, "print the body of every delivery")
+	flag.Parse()
+	if *secret == "" {
+		log.Println("Warning: no secret, signatures are not checked")
+	}
+
+	var mu sync.Mutex
+	failed := 0
+	http.HandleFunc("POST /", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		event, delivery := r.Header.Get("X-Webhook-Event"), r.Header.Get("X-Webhook-Delivery")
+		if *secret != "" {
+			if err := verify(*secret, r.Header.Get("X-Webhook-Timestamp"), r.Header.Get("X-Webhook-Signature"), body, *tolerance); err != nil {
+				log.Printf("%s %s rejected: %v", delivery, event, err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		mu.Lock()
+		refuse := failed < *fail
+		if refuse {
+			failed++
+		}
+		mu.Unlock()
+		if refuse {
+			log.Printf("%s %s answered 503 (%d/%d)", delivery, event, failed, *fail)
+			http.Error(w, "failing on purpose", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Printf("%s %s %s", delivery, event, summary(body))
+		if *verbose {
+			fmt.Println(string(body))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	log.Printf("Listening on http://%s/", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// verify checks the signature of a delivery and the age of its timestamp
+func verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid timestamp")
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("timestamp is %s old", age.Round(time.Second))
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// summary describes an event in one line: its room and, for locks, the
+// turn, champion and time used
+func summary(body []byte) string {
+	var event struct {
+		RoomId string `json:"room_id"`
+		Data   struct {
+			Phase    string `json:"phase"`
+			Team     string `json:"team"`
+			Champion string `json:"champion"`
+			TimeUsed int    `json:"time_used"`
+			TimedOut bool   `json:"timed_out"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return "(invalid JSON: " + err.Error() + ")"
+	}
+	if event.RoomId == "" {
+		return ""
+	}
+	if event.Data.Phase == "" {
+		return "room " + event.RoomId
+	}
+	line := fmt.Sprintf("room %s %s %s %q in %dms", event.RoomId, event.Data.Phase, event.Data.Team, event.Data.Champion, event.Data.TimeUsed)
+	if event.Data.TimedOut {
+		line += " (timed out)"
+	}
+	return line
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"picks3w2a/internal/config"
 	"picks3w2a/internal/handlers"
+	"picks3w2a/internal/metrics"
+	"picks3w2a/internal/middleware"
 	"picks3w2a/internal/services"
 )
 
@@ -22,16 +25,99 @@ func main() {
 	}
 
 	// Initialize services
-	roomService := services.NewRoomService(firebaseService)
+	roomService := services.NewRoomService(firebaseService, cfg)
+	tournamentService, err := services.NewTournamentService(cfg.TournamentsFile, roomService)
+	if err != nil {
+		log.Fatalf("Error loading tournaments: %v", err)
+	}
+
+	// Finished drafts are read back from Firestore, or kept locally without it
+	var draftStore services.DraftStore
+	if firebaseService != nil {
+		draftStore = firebaseService
+	} else {
+		localStore, err := services.NewLocalDraftStore(cfg.DraftsFile, roomService)
+		if err != nil {
+			log.Fatalf("Error loading drafts: %v", err)
+		}
+		draftStore = localStore
+	}
+	roomService.SetDraftStore(draftStore)
+	analyticsService := services.NewAnalyticsService(draftStore)
+	exportService := services.NewExportService(draftStore, tournamentService)
+	championCatalog, err := services.LoadChampionCatalog(cfg.ChampionsFile)
+	if err != nil {
+		log.Fatalf("Error loading champion catalog: %v", err)
+	}
+	importService := services.NewImportService(roomService, draftStore, tournamentService, championCatalog)
+	historyService := services.NewHistoryService(draftStore, championCatalog)
+	webhookService, err := services.NewWebhookService(cfg.WebhooksFile, services.WebhookOptions{
+		Timeout:     cfg.WebhookTimeout,
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Backoff:     cfg.WebhookRetryBackoff,
+		LogSize:     cfg.WebhookLogSize,
+	}, roomService, tournamentService)
+	if err != nil {
+		log.Fatalf("Error loading webhooks: %v", err)
+	}
+
+	stopJanitor := roomService.StartJanitor(cfg.JanitorInterval)
+	defer stopJanitor()
+
+	// Origins allowed for both CORS and WebSocket upgrades
+	origins := middleware.NewOriginPolicy(cfg.AllowedOrigins)
+	if origins.AllowsAll() {
+		log.Println("Warning: ALLOWED_ORIGINS contains *, any website can open WebSocket connections")
+	}
 
 	// Initialize handlers
-	wsHandler := handlers.NewWebSocketHandler(roomService)
+	wsHandler := handlers.NewWebSocketHandler(roomService, tournamentService, cfg, origins)
+	schemaHandler := handlers.NewSchemaHandler(cfg.SchemaPath)
+	inviteHandler := handlers.NewInviteHandler(roomService)
+	tournamentHandler := handlers.NewTournamentHandler(tournamentService, cfg.AdminToken)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, cfg.AdminToken)
+	exportHandler := handlers.NewExportHandler(exportService)
+	importHandler := handlers.NewImportHandler(importService, cfg.AdminToken)
+	historyHandler := handlers.NewHistoryHandler(historyService, cfg.AdminToken)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, cfg.AdminToken)
 
 	// Setup routes
-	http.HandleFunc(cfg.WSPath, wsHandler.Handle)
+	mux := http.NewServeMux()
+	mux.HandleFunc(cfg.WSPath, wsHandler.Handle)
+	mux.HandleFunc(cfg.SchemaPath, schemaHandler.Handle)
+	mux.HandleFunc(cfg.SchemaPath+"/", schemaHandler.Handle)
+	mux.Handle(cfg.MetricsPath, metrics.Default.Handler())
+	mux.Handle(cfg.InvitesPath, middleware.RequireAdmin(cfg.AdminToken, http.HandlerFunc(inviteHandler.Handle)))
+	tournamentRoutes := tournamentHandler.Routes(cfg.TournamentsPath)
+	mux.Handle(cfg.TournamentsPath, tournamentRoutes)
+	mux.Handle(cfg.TournamentsPath+"/", tournamentRoutes)
+	mux.Handle(cfg.AnalyticsPath+"/", analyticsHandler.Routes(cfg.AnalyticsPath))
+	mux.Handle(cfg.ExportsPath+"/", exportHandler.Routes(cfg.ExportsPath))
+	mux.Handle(cfg.ImportsPath+"/", importHandler.Routes(cfg.ImportsPath))
+	mux.Handle(cfg.HistoryPath+"/", historyHandler.Routes(cfg.HistoryPath))
+	webhookRoutes := webhookHandler.Routes(cfg.WebhooksPath)
+	mux.Handle(cfg.WebhooksPath, webhookRoutes)
+	mux.Handle(cfg.WebhooksPath+"/", webhookRoutes)
+
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           middleware.CORS(origins, mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
 
 	// Start server
-	address := ":" + cfg.Port
+	if cfg.TLSEnabled() {
+		tlsConfig, err := cfg.TLSConfig()
+		if err != nil {
+			log.Fatalf("Error loading TLS configuration: %v", err)
+		}
+		server.TLSConfig = tlsConfig
+		fmt.Printf("Servidor WebSocket en wss://%s%s\n", cfg.GetAddress(), cfg.WSPath)
+		log.Fatal(server.ListenAndServeTLS("", ""))
+	}
+
+	log.Println("Warning: TLS is not configured, serving plain HTTP; use TLS_CERT_FILE/TLS_KEY_FILE or TLS_CERT_DIR in production")
 	fmt.Printf("Servidor WebSocket en http://%s%s\n", cfg.GetAddress(), cfg.WSPath)
-	log.Fatal(http.ListenAndServe(address, nil))
+	log.Fatal(server.ListenAndServe())
 }
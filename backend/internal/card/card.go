@@ -0,0 +1,145 @@
+// Package card draws simple summary cards, made of filled rectangles and
+// lines of text, as SVG or PNG. Both formats share the layout: text is set in
+// a monospace font whose characters advance 6 units per unit of scale, the
+// same as the bitmap font used for PNG.
+package card
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Align is the horizontal alignment of a Text relative to its X
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Card is a picture of Width by Height pixels
+type Card struct {
+	Width      int
+	Height     int
+	Background color.RGBA
+	Rects      []Rect
+	Texts      []Text
+}
+
+// Rect is a filled rectangle
+type Rect struct {
+	X, Y, W, H int
+	Fill       color.RGBA
+}
+
+// Text is a line of text. Y is the top of the characters, which are 7 times
+// Scale pixels tall.
+type Text struct {
+	X, Y  int
+	Scale int
+	Align Align
+	Color color.RGBA
+	Value string
+}
+
+// Hex parses a "#rrggbb" color, black if it is malformed
+func Hex(s string) color.RGBA {
+	var r, g, b uint8
+	fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b)
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}
+}
+
+// Fit upper-cases s and cuts it to at most n characters, ending it with a
+// dot when cut
+func Fit(s string, n int) string {
+	s = strings.ToUpper(s)
+	if utf8.RuneCountInString(s) <= n || n < 1 {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "."
+}
+
+// TextWidth returns the width in pixels of s at scale
+func TextWidth(s string, scale int) int {
+	n := utf8.RuneCountInString(s)
+	if n == 0 {
+		return 0
+	}
+	return (n*glyphAdvance - 1) * scale
+}
+
+// left returns the left edge of a text once aligned
+func (t Text) left() int {
+	switch t.Align {
+	case AlignCenter:
+		return t.X - TextWidth(t.Value, t.Scale)/2
+	case AlignRight:
+		return t.X - TextWidth(t.Value, t.Scale)
+	}
+	return t.X
+}
+
+// WriteSVG writes the card as an SVG document
+func (c Card) WriteSVG(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		c.Width, c.Height, c.Width, c.Height)
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(c.Background))
+	for _, r := range c.Rects {
+		fmt.Fprintf(out, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", r.X, r.Y, r.W, r.H, svgColor(r.Fill))
+	}
+	for _, t := range c.Texts {
+		// A monospace font of size 10 advances about 6 units per character,
+		// like the PNG font; textLength absorbs the difference
+		fmt.Fprintf(out, `<text x="%d" y="%d" font-family="DejaVu Sans Mono, Menlo, Consolas, monospace" font-size="%d" textLength="%d" fill="%s">%s</text>`+"\n",
+			t.left(), t.Y+glyphHeight*t.Scale, 10*t.Scale, TextWidth(t.Value, t.Scale), svgColor(t.Color), html.EscapeString(t.Value))
+	}
+	fmt.Fprintln(out, `</svg>`)
+	return out.Flush()
+}
+
+// svgColor formats a color for SVG attributes
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// WritePNG writes the card as a PNG image
+func (c Card) WritePNG(w io.Writer) error {
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c.Background), image.Point{}, draw.Src)
+	for _, r := range c.Rects {
+		draw.Draw(img, image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H), image.NewUniform(r.Fill), image.Point{}, draw.Src)
+	}
+	for _, t := range c.Texts {
+		drawText(img, t)
+	}
+	return png.Encode(w, img)
+}
+
+// drawText draws a text with the bitmap font
+func drawText(img *image.RGBA, t Text) {
+	fill := image.NewUniform(t.Color)
+	x := t.left()
+	for _, r := range strings.ToUpper(t.Value) {
+		for row, line := range glyph(r) {
+			for col, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+				px, py := x+col*t.Scale, t.Y+row*t.Scale
+				draw.Draw(img, image.Rect(px, py, px+t.Scale, py+t.Scale), fill, image.Point{}, draw.Src)
+			}
+		}
+		x += glyphAdvance * t.Scale
+	}
+}
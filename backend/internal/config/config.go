@@ -2,15 +2,83 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration
 type Config struct {
-	Port   string
-	Host   string
-	WSPath string
+	Port                    string
+	Host                    string
+	WSPath                  string
+	SchemaPath              string
 	FirebaseCredentialsPath string
 	FirebaseProjectID       string
+	MaxMessageBytes         int64 // Maximum size of an inbound WebSocket message
+	MetricsPath             string
+
+	// Security
+	AllowedOrigins []string // Browser origins allowed for CORS and WebSockets
+	TLSCertFile    string
+	TLSKeyFile     string
+	TLSCertDir     string // Directory of per-host certificates, autocert cache format
+	InviteSecret   string // HMAC key for invite tokens, random per process if empty
+	MaxInviteTTL   time.Duration
+	AdminToken     string // Bearer token for the admin HTTP API, disabled if empty
+	InvitesPath    string
+
+	// Tournament registry
+	TournamentsFile string // JSON file, kept in memory only if empty
+	TournamentsPath string
+
+	// Finished drafts: analytics, exports, imports and history
+	DraftsFile    string // JSON lines file used when Firestore is disabled, kept in memory only if empty
+	GamePatch     string // Patch of the rooms that do not set one
+	AnalyticsPath string
+	ExportsPath   string
+	ImportsPath   string
+	HistoryPath   string
+	ChampionsFile string // Data Dragon champion.json checked by imports, any champion is accepted if empty
+
+	// Webhooks notified of the draft lifecycle
+	WebhooksFile        string // JSON file, kept in memory only if empty
+	WebhooksPath        string
+	WebhookTimeout      time.Duration // Of each delivery request
+	WebhookMaxAttempts  int
+	WebhookRetryBackoff time.Duration // Before the first retry, doubled on each of the next ones
+	WebhookLogSize      int           // Deliveries kept in the delivery log
+
+	// Abuse protection
+	MaxRooms          int  // Rooms held in memory at once
+	MaxClientsPerRoom int  // Connections per room, spectators included
+	TrustProxyHeaders bool // Take the client IP from X-Forwarded-For
+	CreateRatePerIP   int  // Room creations per minute
+	CreateRatePerConn int  // Room creations per minute
+	JoinRatePerIP     int  // Joins per minute
+	JoinRatePerConn   int  // Joins per minute
+	ActionRatePerIP   int  // Actions per second
+	ActionRatePerConn int  // Actions per second
+	ChatRatePerIP     int  // Team chat lines and suggestions per minute
+	ChatRatePerConn   int  // Team chat lines and suggestions per minute
+
+	// Time between both teams being ready and the first turn, 0 to disable
+	StartCountdown time.Duration
+
+	// Room lifecycle, idle time after which the janitor evicts a room
+	JanitorInterval time.Duration
+	RoomTTLLobby    time.Duration // Waiting for both teams to be ready
+	RoomTTLDrafting time.Duration // Stalled in the middle of the draft
+	RoomTTLFinished time.Duration // Kept after finishing so clients see the result
+	RoomTTLEmpty    time.Duration // No clients connected, in any state
+
+	// Finished tournament games whose result has not been reported yet, with
+	// or without clients
+	RoomTTLUnreported time.Duration
+
+	// Spectators waiting for a room that does not exist yet
+	MaxPendingSubscriptions int
+	PendingSubscriptionTTL  time.Duration
 }
 
 // NewConfig creates a new configuration instance
@@ -19,8 +87,54 @@ func NewConfig() *Config {
 		Port:                    getEnv("PORT", "8080"),
 		Host:                    getEnv("HOST", "localhost"),
 		WSPath:                  getEnv("WS_PATH", "/ws"),
+		SchemaPath:              getEnv("SCHEMA_PATH", "/schema"),
 		FirebaseCredentialsPath: getEnv("FIREBASE_CREDENTIALS_PATH", ""),
 		FirebaseProjectID:       getEnv("FIREBASE_PROJECT_ID", ""),
+		MaxMessageBytes:         int64(getEnvInt("MAX_MESSAGE_BYTES", 4096)),
+		MetricsPath:             getEnv("METRICS_PATH", "/metrics"),
+		AllowedOrigins:          getEnvList("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+		TLSCertFile:             getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:              getEnv("TLS_KEY_FILE", ""),
+		TLSCertDir:              getEnv("TLS_CERT_DIR", ""),
+		InviteSecret:            getEnv("INVITE_SECRET", ""),
+		MaxInviteTTL:            getEnvDuration("MAX_INVITE_TTL", 7*24*time.Hour),
+		AdminToken:              getEnv("ADMIN_TOKEN", ""),
+		InvitesPath:             getEnv("INVITES_PATH", "/invites"),
+		TournamentsFile:         getEnv("TOURNAMENTS_FILE", "data/tournaments.json"),
+		TournamentsPath:         getEnv("TOURNAMENTS_PATH", "/tournaments"),
+		DraftsFile:              getEnv("DRAFTS_FILE", "data/drafts.jsonl"),
+		GamePatch:               getEnv("GAME_PATCH", ""),
+		AnalyticsPath:           getEnv("ANALYTICS_PATH", "/analytics"),
+		ExportsPath:             getEnv("EXPORTS_PATH", "/exports"),
+		ImportsPath:             getEnv("IMPORTS_PATH", "/imports"),
+		HistoryPath:             getEnv("HISTORY_PATH", "/history"),
+		ChampionsFile:           getEnv("CHAMPIONS_FILE", ""),
+		WebhooksFile:            getEnv("WEBHOOKS_FILE", "data/webhooks.json"),
+		WebhooksPath:            getEnv("WEBHOOKS_PATH", "/webhooks"),
+		WebhookTimeout:          getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:      getEnvInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookRetryBackoff:     getEnvDuration("WEBHOOK_RETRY_BACKOFF", 2*time.Second),
+		WebhookLogSize:          getEnvInt("WEBHOOK_LOG_SIZE", 1000),
+		MaxRooms:                getEnvInt("MAX_ROOMS", 1000),
+		MaxClientsPerRoom:       getEnvInt("MAX_CLIENTS_PER_ROOM", 50),
+		TrustProxyHeaders:       getEnvBool("TRUST_PROXY_HEADERS", false),
+		CreateRatePerIP:         getEnvInt("CREATE_RATE_PER_IP", 10),
+		CreateRatePerConn:       getEnvInt("CREATE_RATE_PER_CONN", 3),
+		JoinRatePerIP:           getEnvInt("JOIN_RATE_PER_IP", 60),
+		JoinRatePerConn:         getEnvInt("JOIN_RATE_PER_CONN", 20),
+		ActionRatePerIP:         getEnvInt("ACTION_RATE_PER_IP", 20),
+		ActionRatePerConn:       getEnvInt("ACTION_RATE_PER_CONN", 5),
+		ChatRatePerIP:           getEnvInt("CHAT_RATE_PER_IP", 120),
+		ChatRatePerConn:         getEnvInt("CHAT_RATE_PER_CONN", 30),
+		StartCountdown:          getEnvDuration("START_COUNTDOWN", 5*time.Second),
+		JanitorInterval:         getEnvDuration("JANITOR_INTERVAL", time.Minute),
+		RoomTTLLobby:            getEnvDuration("ROOM_TTL_LOBBY", 2*time.Hour),
+		RoomTTLDrafting:         getEnvDuration("ROOM_TTL_DRAFTING", 30*time.Minute),
+		RoomTTLFinished:         getEnvDuration("ROOM_TTL_FINISHED", 10*time.Minute),
+		RoomTTLEmpty:            getEnvDuration("ROOM_TTL_EMPTY", 15*time.Minute),
+		RoomTTLUnreported:       getEnvDuration("ROOM_TTL_UNREPORTED", 24*time.Hour),
+		MaxPendingSubscriptions: getEnvInt("MAX_PENDING_SUBSCRIPTIONS", 500),
+		PendingSubscriptionTTL:  getEnvDuration("PENDING_SUBSCRIPTION_TTL", 2*time.Hour),
 	}
 }
 
@@ -32,6 +146,51 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList gets a comma-separated environment variable or returns a default value
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvInt gets an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBool gets a boolean environment variable or returns a default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "30m") or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 // GetAddress returns the full server address
 func (c *Config) GetAddress() string {
 	return c.Host + ":" + c.Port
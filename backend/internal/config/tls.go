@@ -0,0 +1,135 @@
+package config
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLSEnabled reports whether the server should listen with TLS
+func (c *Config) TLSEnabled() bool {
+	return (c.TLSCertFile != "" && c.TLSKeyFile != "") || c.TLSCertDir != ""
+}
+
+// TLSConfig builds the server TLS configuration. Certificates come either
+// from a cert/key file pair or from a directory in the format of an autocert
+// cache, where each file is named after a host and holds the private key
+// followed by the certificate chain. Certificates are reloaded when their
+// files change so renewals do not need a restart.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	switch {
+	case c.TLSCertFile != "" && c.TLSKeyFile != "":
+		loader := &certLoader{load: func(string) (string, string, error) {
+			return c.TLSCertFile, c.TLSKeyFile, nil
+		}}
+		if _, err := loader.certificate(""); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return loader.certificate("")
+		}
+	case c.TLSCertDir != "":
+		loader := &certLoader{load: func(host string) (string, string, error) {
+			if host == "" || strings.ContainsAny(host, `/\`) || strings.HasPrefix(host, ".") {
+				return "", "", fmt.Errorf("invalid server name %q", host)
+			}
+			path := filepath.Join(c.TLSCertDir, host)
+			return path, path, nil
+		}}
+		tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return loader.certificate(strings.ToLower(hello.ServerName))
+		}
+	default:
+		return nil, fmt.Errorf("TLS is not configured")
+	}
+
+	return tlsConfig, nil
+}
+
+// certLoader caches certificates by host and reloads them when the files change
+type certLoader struct {
+	load  func(host string) (certPath, keyPath string, err error)
+	mu    sync.Mutex
+	cache map[string]cachedCert
+}
+
+type cachedCert struct {
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (l *certLoader) certificate(host string) (*tls.Certificate, error) {
+	certPath, keyPath, err := l.load(host)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate for %q: %v", host, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cached, ok := l.cache[host]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.cert, nil
+	}
+
+	cert, err := loadKeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	if l.cache == nil {
+		l.cache = make(map[string]cachedCert)
+	}
+	l.cache[host] = cachedCert{cert: cert, modTime: info.ModTime()}
+	return cert, nil
+}
+
+// loadKeyPair loads a certificate and key that may live in the same PEM file
+func loadKeyPair(certPath, keyPath string) (*tls.Certificate, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate: %v", err)
+	}
+	keyPEM := certPEM
+	if keyPath != certPath {
+		if keyPEM, err = os.ReadFile(keyPath); err != nil {
+			return nil, fmt.Errorf("error reading private key: %v", err)
+		}
+	}
+
+	// Separar los bloques para soportar ficheros combinados clave + cadena
+	var certBlocks, keyBlocks []byte
+	for _, data := range [][]byte{certPEM, keyPEM} {
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type == "CERTIFICATE" {
+				certBlocks = append(certBlocks, pem.EncodeToMemory(block)...)
+			} else if strings.Contains(block.Type, "PRIVATE KEY") {
+				keyBlocks = append(keyBlocks, pem.EncodeToMemory(block)...)
+			}
+		}
+		if keyPath == certPath {
+			break
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certBlocks, keyBlocks)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate: %v", err)
+	}
+	return &cert, nil
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"picks3w2a/internal/middleware"
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// dateLayout is the layout of dates without time in query parameters
+const dateLayout = "2006-01-02"
+
+// AnalyticsHandler serves the champion statistics and team scouting reports
+// of the finished drafts
+type AnalyticsHandler struct {
+	analytics  *services.AnalyticsService
+	adminToken string
+}
+
+// NewAnalyticsHandler creates the analytics handler
+func NewAnalyticsHandler(analytics *services.AnalyticsService, adminToken string) *AnalyticsHandler {
+	return &AnalyticsHandler{analytics: analytics, adminToken: adminToken}
+}
+
+// Routes returns the analytics API mounted under prefix. Every route reads
+// the whole draft store and includes scrims, so they require the admin token.
+func (h *AnalyticsHandler) Routes(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/champions", h.champions)
+	mux.HandleFunc("GET "+prefix+"/champions.csv", h.championsCSV)
+	mux.HandleFunc("GET "+prefix+"/scouting", h.scouting)
+	mux.HandleFunc("GET "+prefix+"/timing", h.timing)
+	mux.HandleFunc("GET "+prefix+"/timing.csv", h.timingCSV)
+	return middleware.RequireAdmin(h.adminToken, mux)
+}
+
+func (h *AnalyticsHandler) champions(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.championReport(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *AnalyticsHandler) championsCSV(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.championReport(w, r)
+	if !ok {
+		return
+	}
+	header := []string{
+		"champion", "picks", "bans", "presence", "pick_rate", "ban_rate", "presence_rate",
+		"wins", "decided", "win_rate", "first_picks", "first_pick_rate", "avg_pick_turn",
+		"blue_picks", "blue_bans", "blue_wins", "blue_decided", "blue_win_rate",
+		"red_picks", "red_bans", "red_wins", "red_decided", "red_win_rate",
+	}
+	rows := make([][]string, 0, len(report.Champions))
+	for _, c := range report.Champions {
+		rows = append(rows, []string{
+			c.Champion, itoa(c.Picks), itoa(c.Bans), itoa(c.Presence), ftoa(c.PickRate), ftoa(c.BanRate), ftoa(c.PresenceRate),
+			itoa(c.Wins), itoa(c.Decided), ftoa(c.WinRate), itoa(c.FirstPicks), ftoa(c.FirstPickRate), ftoa(c.AvgPickTurn),
+			itoa(c.Blue.Picks), itoa(c.Blue.Bans), itoa(c.Blue.Wins), itoa(c.Blue.Decided), ftoa(c.Blue.WinRate),
+			itoa(c.Red.Picks), itoa(c.Red.Bans), itoa(c.Red.Wins), itoa(c.Red.Decided), ftoa(c.Red.WinRate),
+		})
+	}
+	writeCSV(w, "champions.csv", header, rows)
+}
+
+func (h *AnalyticsHandler) scouting(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDraftFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	opponent := r.URL.Query().Get("opponent")
+	if utf8.RuneCountInString(opponent) > models.MaxTeamNameLength {
+		writeError(w, models.NewProtocolError(models.ErrValidation, "opponent: must be at most %d characters", models.MaxTeamNameLength).
+			WithDetail(models.DetailField, "opponent"))
+		return
+	}
+	report, err := h.analytics.ScoutingReport(filter, opponent)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *AnalyticsHandler) timing(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.timingReport(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *AnalyticsHandler) timingCSV(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.timingReport(w, r)
+	if !ok {
+		return
+	}
+	header := []string{
+		"team", "drafts", "picks", "avg_pick_time", "max_pick_time",
+		"bans", "avg_ban_time", "max_ban_time", "timeouts", "avg_draft_time",
+	}
+	rows := make([][]string, 0, len(report.Teams))
+	for _, t := range report.Teams {
+		rows = append(rows, []string{
+			t.Team, itoa(t.Drafts), itoa(t.Picks), ftoa(t.AvgPickTime), ftoa(t.MaxPickTime),
+			itoa(t.Bans), ftoa(t.AvgBanTime), ftoa(t.MaxBanTime), itoa(t.Timeouts), ftoa(t.AvgDraftTime),
+		})
+	}
+	writeCSV(w, "timing.csv", header, rows)
+}
+
+// timingReport computes the timing report selected by the query
+// parameters, writing the error response and returning false if it fails
+func (h *AnalyticsHandler) timingReport(w http.ResponseWriter, r *http.Request) (models.TimingReport, bool) {
+	filter, err := parseDraftFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return models.TimingReport{}, false
+	}
+	report, err := h.analytics.TimingReport(filter)
+	if err != nil {
+		writeError(w, err)
+		return models.TimingReport{}, false
+	}
+	return report, true
+}
+
+// championReport computes the report selected by the query parameters,
+// writing the error response and returning false if it fails
+func (h *AnalyticsHandler) championReport(w http.ResponseWriter, r *http.Request) (models.ChampionReport, bool) {
+	filter, err := parseDraftFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return models.ChampionReport{}, false
+	}
+	report, err := h.analytics.ChampionReport(filter)
+	if err != nil {
+		writeError(w, err)
+		return models.ChampionReport{}, false
+	}
+	return report, true
+}
+
+// parseDraftFilter reads the tournament, team, patch, from and to query
+// parameters. Dates are RFC 3339 times or plain dates; a plain to date
+// includes the whole day.
+func parseDraftFilter(r *http.Request) (models.DraftFilter, error) {
+	query := r.URL.Query()
+	filter := models.DraftFilter{
+		TournamentId: query.Get("tournament"),
+		Team:         query.Get("team"),
+		Patch:        query.Get("patch"),
+	}
+	var err error
+	if filter.From, err = parseDate("from", query.Get("from"), false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseDate("to", query.Get("to"), true); err != nil {
+		return filter, err
+	}
+	return filter, filter.Validate()
+}
+
+// parseDate parses a date query parameter into Unix seconds, 0 if empty
+func parseDate(field, value string, endOfDay bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return 0, models.NewProtocolError(models.ErrValidation, "%s: must be a date (YYYY-MM-DD) or an RFC 3339 time", field).
+			WithDetail(models.DetailField, field)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.Unix(), nil
+}
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"picks3w2a/internal/models"
+)
+
+// decodeInbound parses and validates a raw client message. Besides the typed
+// message it returns the request it refers to, so that an error response can
+// be correlated even when decoding fails halfway.
+func decodeInbound(msgBytes []byte) (requestInfo, models.Validator, error) {
+	// Primero intentamos determinar el tipo de mensaje
+	var baseMsg struct {
+		Type      string `json:"type"`
+		RequestId string `json:"request_id"`
+		Action    string `json:"action"`
+	}
+	if err := json.Unmarshal(msgBytes, &baseMsg); err != nil {
+		return requestInfo{}, nil, models.NewProtocolError(models.ErrInvalidMessage, "invalid message format")
+	}
+
+	req := requestInfo{Type: baseMsg.Type, Action: baseMsg.Action}
+	if len(baseMsg.RequestId) <= models.MaxRequestIdLength {
+		req.RequestId = baseMsg.RequestId
+	}
+
+	var msg models.Validator
+	switch baseMsg.Type {
+	case "hello":
+		msg = &models.HelloMessage{}
+	case "create":
+		msg = &models.CreateMessage{}
+	case "join":
+		msg = &models.JoinMessage{}
+	case "action":
+		msg = &models.ActionMessage{}
+	case "team_chat":
+		msg = &models.TeamChatMessage{}
+	case "suggest":
+		msg = &models.SuggestMessage{}
+	case "watch_tournament":
+		msg = &models.WatchTournamentMessage{}
+	default:
+		return req, nil, models.NewProtocolError(models.ErrUnknownMessageType, "unknown message type: %s", baseMsg.Type).
+			WithDetail(models.DetailType, baseMsg.Type)
+	}
+
+	// Decodificación estricta: campos desconocidos o datos sobrantes son un error
+	decoder := json.NewDecoder(bytes.NewReader(msgBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(msg); err != nil {
+		return req, nil, models.NewProtocolError(models.ErrInvalidMessage, "invalid %s message: %v", baseMsg.Type, err).
+			WithDetail(models.DetailType, baseMsg.Type)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return req, nil, models.NewProtocolError(models.ErrInvalidMessage, "invalid %s message: trailing data", baseMsg.Type).
+			WithDetail(models.DetailType, baseMsg.Type)
+	}
+
+	if err := msg.Validate(); err != nil {
+		return req, nil, err
+	}
+	return req, msg, nil
+}
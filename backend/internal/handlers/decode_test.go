@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"picks3w2a/internal/models"
+)
+
+func FuzzDecodeInbound(f *testing.F) {
+	seeds := []string{
+		`{"type":"hello","versions":[1],"client":"fuzz"}`,
+		`{"type":"create","blue_team_name":"A","red_team_name":"B","blue_team_has_bans":true,"red_team_has_bans":true,"time_per_pick":30,"time_per_ban":30,"fearless_bans":["266"]}`,
+		`{"type":"join","room_id":"abcd1234","key":"deadbeef","request_id":"r1"}`,
+		`{"type":"action","action":"champ_pick","champion":"266","request_id":"r2"}`,
+		`{"type":"action","action":"ready"}`,
+		`{"type":"create","time_per_pick":-1}`,
+		`{"type":"join","room_id":"x","unknown":1}`,
+		`{"type":"action","action":"ready"} {}`,
+		`{"type":1}`,
+		`[]`,
+		``,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		req, msg, err := decodeInbound(data)
+		if err != nil {
+			var protocolErr *models.ProtocolError
+			if !errors.As(err, &protocolErr) {
+				t.Fatalf("decodeInbound(%q) returned a non-protocol error: %v", data, err)
+			}
+			if msg != nil {
+				t.Fatalf("decodeInbound(%q) returned a message along with error %v", data, err)
+			}
+			return
+		}
+
+		if msg == nil {
+			t.Fatalf("decodeInbound(%q) returned neither message nor error", data)
+		}
+		if err := msg.Validate(); err != nil {
+			t.Fatalf("decoded message %#v does not validate: %v", msg, err)
+		}
+		if len(req.RequestId) > models.MaxRequestIdLength {
+			t.Fatalf("request id of %d bytes echoed back", len(req.RequestId))
+		}
+	})
+}
+
+func TestDecodeInboundRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		code models.ErrorCode
+	}{
+		{"malformed json", `{"type":`, models.ErrInvalidMessage},
+		{"unknown type", `{"type":"nope"}`, models.ErrUnknownMessageType},
+		{"unknown field", `{"type":"join","room_id":"abc","extra":true}`, models.ErrInvalidMessage},
+		{"trailing data", `{"type":"join","room_id":"abc"}{"type":"join"}`, models.ErrInvalidMessage},
+		{"trailing brace", `{"type":"join","room_id":"abc"}}`, models.ErrInvalidMessage},
+		{"trailing value", `{"type":"join","room_id":"abc"} 1`, models.ErrInvalidMessage},
+		{"negative pick time", `{"type":"create","blue_team_name":"A","red_team_name":"B","time_per_pick":-5,"time_per_ban":30}`, models.ErrValidation},
+		{"zero ban time", `{"type":"create","blue_team_name":"A","red_team_name":"B","time_per_pick":30,"time_per_ban":0}`, models.ErrValidation},
+		{"empty team name", `{"type":"create","blue_team_name":"  ","red_team_name":"B","time_per_pick":30,"time_per_ban":30}`, models.ErrValidation},
+		{"missing room id", `{"type":"join"}`, models.ErrValidation},
+		{"unknown action", `{"type":"action","action":"dance"}`, models.ErrUnknownAction},
+		{"pick without champion", `{"type":"action","action":"champ_pick"}`, models.ErrValidation},
+		{"hello without versions", `{"type":"hello","versions":[]}`, models.ErrValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeInbound([]byte(tt.data))
+			var protocolErr *models.ProtocolError
+			if !errors.As(err, &protocolErr) {
+				t.Fatalf("expected protocol error, got %v", err)
+			}
+			if protocolErr.Code != tt.code {
+				t.Fatalf("expected code %s, got %s (%s)", tt.code, protocolErr.Code, protocolErr.Message)
+			}
+		})
+	}
+}
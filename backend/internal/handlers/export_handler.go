@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"picks3w2a/internal/card"
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// ExportHandler serves downloads of finished drafts and tournament series
+type ExportHandler struct {
+	exports *services.ExportService
+}
+
+// NewExportHandler creates the export handler
+func NewExportHandler(exports *services.ExportService) *ExportHandler {
+	return &ExportHandler{exports: exports}
+}
+
+// Routes returns the export API mounted under prefix. Files are named after
+// the room or match with the format as extension: json, csv, svg or png.
+// Exports are public, like the draft they show to spectators.
+func (h *ExportHandler) Routes(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/rooms/{file}", h.room)
+	mux.HandleFunc("GET "+prefix+"/matches/{tournament}/{file}", h.series)
+	return mux
+}
+
+func (h *ExportHandler) room(w http.ResponseWriter, r *http.Request) {
+	roomId, format, err := parseExportFile(r.PathValue("file"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	draft, err := h.exports.DraftExport(roomId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeExport(w, roomId, format, draft, services.DraftCSVRows(draft), services.DraftCard(draft))
+}
+
+func (h *ExportHandler) series(w http.ResponseWriter, r *http.Request) {
+	matchId, format, err := parseExportFile(r.PathValue("file"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tournamentId := r.PathValue("tournament")
+	series, err := h.exports.SeriesExport(tournamentId, matchId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeExport(w, tournamentId+"-"+matchId, format, series, services.DraftCSVRows(series.Games...), services.SeriesCard(series))
+}
+
+// parseExportFile splits a file name into the ID it exports and its format
+func parseExportFile(file string) (id, format string, err error) {
+	dot := strings.LastIndexByte(file, '.')
+	if dot > 0 {
+		id, format = file[:dot], file[dot+1:]
+		switch format {
+		case "json", "csv", "svg", "png":
+			return id, format, nil
+		}
+	}
+	return "", "", models.NewProtocolError(models.ErrValidation, "file: must end with .json, .csv, .svg or .png").
+		WithDetail(models.DetailField, "file")
+}
+
+// writeExport writes an export as a download in the requested format. Cards
+// are rendered before writing the headers so failures can still be reported.
+func writeExport(w http.ResponseWriter, name, format string, export any, rows [][]string, summary card.Card) {
+	filename := name + "." + format
+	var buf bytes.Buffer
+	var contentType string
+	switch format {
+	case "csv":
+		writeCSV(w, filename, services.DraftCSVHeader, rows)
+		return
+	case "json":
+		contentType = "application/json"
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			writeError(w, err)
+			return
+		}
+	case "svg":
+		contentType = "image/svg+xml"
+		if err := summary.WriteSVG(&buf); err != nil {
+			writeError(w, err)
+			return
+		}
+	case "png":
+		contentType = "image/png"
+		if err := summary.WritePNG(&buf); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
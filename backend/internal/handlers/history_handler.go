@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"picks3w2a/internal/middleware"
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// HistoryHandler lists the finished drafts
+type HistoryHandler struct {
+	history    *services.HistoryService
+	adminToken string
+}
+
+// NewHistoryHandler creates the history handler
+func NewHistoryHandler(history *services.HistoryService, adminToken string) *HistoryHandler {
+	return &HistoryHandler{history: history, adminToken: adminToken}
+}
+
+// Routes returns the history API mounted under prefix. Listing drafts
+// reveals the IDs of every room, scrims included, so it requires the admin
+// token.
+func (h *HistoryHandler) Routes(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/drafts", h.drafts)
+	return middleware.RequireAdmin(h.adminToken, mux)
+}
+
+// drafts returns a page of the drafts selected by the analytics filters and
+// the champion, sort, limit and cursor query parameters
+func (h *HistoryHandler) drafts(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDraftFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	draftQuery := models.DraftQuery{
+		DraftFilter: filter,
+		Champion:    query.Get("champion"),
+		Sort:        query.Get("sort"),
+		Cursor:      query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if draftQuery.Limit, err = strconv.Atoi(limit); err != nil || draftQuery.Limit < 1 {
+			writeError(w, models.NewProtocolError(models.ErrValidation, "limit: must be between 1 and %d", models.MaxHistoryLimit).
+				WithDetail(models.DetailField, "limit"))
+			return
+		}
+	}
+	if err := draftQuery.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	page, err := h.history.Drafts(draftQuery)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"picks3w2a/internal/models"
+)
+
+// maxRequestBodyBytes caps the body of the HTTP API requests
+const maxRequestBodyBytes = 1 << 20
+
+// httpError is the body of an HTTP API error, with the same codes as the
+// WebSocket ErrorMessage
+type httpError struct {
+	Code    models.ErrorCode  `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// decodeJSONBody strictly decodes a request body into v
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return models.NewProtocolError(models.ErrInvalidMessage, "invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// decodeRequest decodes and validates a request body, writing the error
+// response and returning false if it is invalid
+func decodeRequest(w http.ResponseWriter, r *http.Request, req models.Validator) bool {
+	if err := decodeJSONBody(w, r, req); err != nil {
+		writeError(w, err)
+		return false
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, err)
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+// writeCSV writes a CSV attachment with a header row
+func writeCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		log.Printf("Error writing CSV response: %v", err)
+	}
+}
+
+// writeError writes err as an HTTP API error. Errors that are not a
+// ProtocolError are logged and reported as internal errors.
+func writeError(w http.ResponseWriter, err error) {
+	var protocolErr *models.ProtocolError
+	if !errors.As(err, &protocolErr) {
+		log.Printf("Unexpected HTTP API error: %v", err)
+		protocolErr = models.NewProtocolError(models.ErrInternal, "internal server error")
+	}
+	writeJSON(w, httpStatus(protocolErr.Code), httpError{
+		Code:    protocolErr.Code,
+		Message: protocolErr.Message,
+		Details: protocolErr.Details,
+	})
+}
+
+// httpStatus maps an error code to the HTTP status of the response
+func httpStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrRoomNotFound, models.ErrTournamentNotFound, models.ErrTeamNotFound, models.ErrMatchNotFound, models.ErrWebhookNotFound:
+		return http.StatusNotFound
+	case models.ErrRoomExists, models.ErrDuplicateId, models.ErrGameInProgress, models.ErrSeriesOver, models.ErrResultExists:
+		return http.StatusConflict
+	case models.ErrRateLimited:
+		return http.StatusTooManyRequests
+	case models.ErrInternal:
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
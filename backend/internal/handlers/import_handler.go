@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"picks3w2a/internal/middleware"
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// ImportHandler takes in drafts played outside the tool
+type ImportHandler struct {
+	imports    *services.ImportService
+	adminToken string
+}
+
+// NewImportHandler creates the import handler
+func NewImportHandler(imports *services.ImportService, adminToken string) *ImportHandler {
+	return &ImportHandler{imports: imports, adminToken: adminToken}
+}
+
+// Routes returns the import API mounted under prefix. Imported drafts enter
+// the history and the tournaments, so it requires the admin token.
+func (h *ImportHandler) Routes(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+prefix+"/drafts", h.importDraft)
+	return middleware.RequireAdmin(h.adminToken, mux)
+}
+
+// importDraft imports a draft sent in the JSON export format or, with a
+// text/csv body, in the CSV one. The CSV format only has the actions: the
+// tournament, match, patch, winner, duration and completed_at query
+// parameters fill in the rest.
+func (h *ImportHandler) importDraft(w http.ResponseWriter, r *http.Request) {
+	var draft models.DraftExport
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		var err error
+		if draft, err = parseCSVImport(w, r); err != nil {
+			writeError(w, err)
+			return
+		}
+	} else if !decodeRequest(w, r, &draft) {
+		return
+	}
+
+	export, err := h.imports.ImportDraft(draft)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, export)
+}
+
+// parseCSVImport reads a CSV draft and the query parameters that complete it
+func parseCSVImport(w http.ResponseWriter, r *http.Request) (models.DraftExport, error) {
+	draft, err := services.ParseDraftCSV(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	if err != nil {
+		return draft, err
+	}
+
+	query := r.URL.Query()
+	if tournament := query.Get("tournament"); tournament != "" {
+		if draft.Match == nil {
+			draft.Match = &models.MatchRef{}
+		}
+		draft.Match.TournamentId, draft.Match.MatchId = tournament, query.Get("match")
+	} else {
+		draft.Match = nil
+	}
+	draft.Patch = query.Get("patch")
+	if winner := query.Get("winner"); winner != "" {
+		draft.Result = &models.GameResult{Winner: winner}
+		if duration := query.Get("duration"); duration != "" {
+			if draft.Result.Duration, err = strconv.Atoi(duration); err != nil {
+				return draft, models.NewProtocolError(models.ErrValidation, "duration: must be a number of seconds").
+					WithDetail(models.DetailField, "duration")
+			}
+		}
+	}
+	if draft.CompletedAt, err = parseDate("completed_at", query.Get("completed_at"), false); err != nil {
+		return draft, err
+	}
+	return draft, draft.Validate()
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// InviteHandler issues signed invite links ahead of a match
+type InviteHandler struct {
+	roomService *services.RoomService
+}
+
+// NewInviteHandler creates the invite handler
+func NewInviteHandler(roomService *services.RoomService) *InviteHandler {
+	return &InviteHandler{roomService: roomService}
+}
+
+// Handle issues an invite for a POSTed models.InviteRequest
+func (h *InviteHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.InviteRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	invite, err := h.roomService.IssueInvite(req.RoomId, req.Seat, time.Duration(req.ExpiresIn)*time.Second)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, invite)
+}
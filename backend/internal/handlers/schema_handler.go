@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/schema"
+)
+
+// SchemaHandler serves the JSON Schema of the WebSocket protocol, generated
+// from the Go message models at startup
+type SchemaHandler struct {
+	basePath string
+	inbound  []byte
+	outbound []byte
+	index    []byte
+}
+
+// NewSchemaHandler generates the protocol schemas served under basePath
+func NewSchemaHandler(basePath string) *SchemaHandler {
+	basePath = strings.TrimSuffix(basePath, "/")
+	h := &SchemaHandler{basePath: basePath}
+
+	h.inbound = h.generate("inbound.json", "Client to server messages", models.InboundMessages)
+	h.outbound = h.generate("outbound.json", "Server to client messages", models.OutboundMessages)
+	h.index, _ = json.MarshalIndent(map[string]interface{}{
+		"version":            models.ProtocolVersion,
+		"supported_versions": models.SupportedProtocolVersions,
+		"inbound":            basePath + "/inbound.json",
+		"outbound":           basePath + "/outbound.json",
+	}, "", "  ")
+	return h
+}
+
+func (h *SchemaHandler) generate(name, title string, messages map[string]interface{}) []byte {
+	gen := schema.NewGenerator()
+	phases := make([]string, len(models.AllPhases))
+	for i, phase := range models.AllPhases {
+		phases[i] = string(phase)
+	}
+	gen.Enums[reflect.TypeOf(models.Phase(""))] = phases
+
+	doc := gen.Document(h.basePath+"/"+name, title, messages)
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Printf("Error generating schema %s: %v", name, err)
+	}
+	return data
+}
+
+// Handle serves the schema index at the base path and each schema below it
+func (h *SchemaHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case h.basePath:
+		body = h.index
+	case h.basePath + "/inbound.json":
+		body = h.inbound
+	case h.basePath + "/outbound.json":
+		body = h.outbound
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(body)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestSchemaGolden pins the served protocol schema, so that a change to the
+// message models shows up as a change to the schema clients are built from.
+// Run with -update after changing the models on purpose.
+func TestSchemaGolden(t *testing.T) {
+	h := NewSchemaHandler("/schema")
+
+	tests := []struct {
+		path   string
+		golden string
+	}{
+		{"/schema", "index.json"},
+		{"/schema/inbound.json", "inbound.json"},
+		{"/schema/outbound.json", "outbound.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != 200 {
+				t.Fatalf("GET %s = %d", tt.path, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/schema+json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+
+			golden := filepath.Join("testdata", "schema", tt.golden)
+			body := append(rec.Body.Bytes(), '\n')
+			if *updateGolden {
+				if err := os.WriteFile(golden, body, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading golden file: %v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(body, want) {
+				t.Errorf("GET %s differs from %s; if the models changed on purpose, run go test ./internal/handlers -run TestSchemaGolden -update", tt.path, golden)
+			}
+		})
+	}
+}
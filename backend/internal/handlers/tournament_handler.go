@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+
+	"picks3w2a/internal/middleware"
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// TournamentHandler serves the tournament registry over HTTP
+type TournamentHandler struct {
+	tournaments *services.TournamentService
+	adminToken  string
+}
+
+// NewTournamentHandler creates the tournament handler
+func NewTournamentHandler(tournaments *services.TournamentService, adminToken string) *TournamentHandler {
+	return &TournamentHandler{tournaments: tournaments, adminToken: adminToken}
+}
+
+// Routes returns the tournament API mounted under prefix. Reads are public so
+// tournament sites and stream graphics can use them; changes and game rooms,
+// whose response carries the team keys, require the admin token.
+func (h *TournamentHandler) Routes(prefix string) http.Handler {
+	admin := func(handler http.HandlerFunc) http.Handler {
+		return middleware.RequireAdmin(h.adminToken, handler)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix, h.list)
+	mux.Handle("POST "+prefix, admin(h.create))
+	mux.HandleFunc("GET "+prefix+"/{tournament}", h.get)
+	mux.Handle("POST "+prefix+"/{tournament}/teams", admin(h.registerTeam))
+	mux.Handle("POST "+prefix+"/{tournament}/matches", admin(h.scheduleMatch))
+	mux.Handle("POST "+prefix+"/{tournament}/brackets", admin(h.createBracket))
+	mux.HandleFunc("GET "+prefix+"/{tournament}/standings", h.standings)
+	mux.HandleFunc("GET "+prefix+"/{tournament}/matches/{match}", h.getMatch)
+	mux.Handle("POST "+prefix+"/{tournament}/matches/{match}/games", admin(h.startGame))
+	mux.Handle("POST "+prefix+"/{tournament}/matches/{match}/games/{room}/result", admin(h.submitResult))
+	return mux
+}
+
+func (h *TournamentHandler) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.tournaments.Tournaments())
+}
+
+func (h *TournamentHandler) get(w http.ResponseWriter, r *http.Request) {
+	tournament, err := h.tournaments.Tournament(r.PathValue("tournament"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tournament)
+}
+
+func (h *TournamentHandler) getMatch(w http.ResponseWriter, r *http.Request) {
+	match, err := h.tournaments.Match(r.PathValue("tournament"), r.PathValue("match"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, match)
+}
+
+func (h *TournamentHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTournamentRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	tournament, err := h.tournaments.CreateTournament(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tournament)
+}
+
+func (h *TournamentHandler) registerTeam(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterTeamRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	team, err := h.tournaments.RegisterTeam(r.PathValue("tournament"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, team)
+}
+
+func (h *TournamentHandler) scheduleMatch(w http.ResponseWriter, r *http.Request) {
+	var req models.ScheduleMatchRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	match, err := h.tournaments.ScheduleMatch(r.PathValue("tournament"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, match)
+}
+
+func (h *TournamentHandler) createBracket(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateBracketRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	bracket, err := h.tournaments.CreateBracket(r.PathValue("tournament"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, bracket)
+}
+
+func (h *TournamentHandler) standings(w http.ResponseWriter, r *http.Request) {
+	standings, err := h.tournaments.Standings(r.PathValue("tournament"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, standings)
+}
+
+func (h *TournamentHandler) startGame(w http.ResponseWriter, r *http.Request) {
+	var req models.StartGameRequest
+	if r.ContentLength != 0 && !decodeRequest(w, r, &req) {
+		return
+	}
+	game, err := h.tournaments.StartGame(r.PathValue("tournament"), r.PathValue("match"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, game)
+}
+
+func (h *TournamentHandler) submitResult(w http.ResponseWriter, r *http.Request) {
+	var req models.SubmitResultRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	match, err := h.tournaments.SubmitResult(r.PathValue("tournament"), r.PathValue("match"), r.PathValue("room"), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, match)
+}
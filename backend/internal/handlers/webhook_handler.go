@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"picks3w2a/internal/middleware"
+	"picks3w2a/internal/models"
+	"picks3w2a/internal/services"
+)
+
+// WebhookHandler manages the webhooks and reads their delivery log
+type WebhookHandler struct {
+	webhooks   *services.WebhookService
+	adminToken string
+}
+
+// NewWebhookHandler creates the webhook handler
+func NewWebhookHandler(webhooks *services.WebhookService, adminToken string) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks, adminToken: adminToken}
+}
+
+// Routes returns the webhook API mounted under prefix. Webhooks receive
+// every room of their scope, scrims included, so all routes require the
+// admin token.
+func (h *WebhookHandler) Routes(prefix string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix, h.list)
+	mux.HandleFunc("POST "+prefix, h.create)
+	mux.HandleFunc("GET "+prefix+"/deliveries", h.deliveries)
+	mux.HandleFunc("GET "+prefix+"/{webhook}", h.get)
+	mux.HandleFunc("DELETE "+prefix+"/{webhook}", h.delete)
+	mux.HandleFunc("POST "+prefix+"/{webhook}/ping", h.ping)
+	return middleware.RequireAdmin(h.adminToken, mux)
+}
+
+func (h *WebhookHandler) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.webhooks.Webhooks())
+}
+
+func (h *WebhookHandler) get(w http.ResponseWriter, r *http.Request) {
+	webhook, err := h.webhooks.Webhook(r.PathValue("webhook"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, webhook)
+}
+
+func (h *WebhookHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	webhook, err := h.webhooks.CreateWebhook(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.webhooks.DeleteWebhook(r.PathValue("webhook")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ping queues a ping event and returns its delivery, still pending
+func (h *WebhookHandler) ping(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.webhooks.Ping(r.PathValue("webhook"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// deliveries returns the delivery log, newest first, filtered by the
+// webhook, event and status query parameters
+func (h *WebhookHandler) deliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	event, status := query.Get("event"), query.Get("status")
+	if event != "" && event != models.EventPing && !slices.Contains(models.WebhookEvents, event) {
+		writeError(w, models.NewProtocolError(models.ErrValidation, "event: unknown event %q", event).
+			WithDetail(models.DetailField, "event"))
+		return
+	}
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed:
+	default:
+		writeError(w, models.NewProtocolError(models.ErrValidation, "status: must be %s, %s or %s",
+			models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed).
+			WithDetail(models.DetailField, "status"))
+		return
+	}
+	limit := models.DefaultDeliveryLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > models.MaxDeliveryLimit {
+			writeError(w, models.NewProtocolError(models.ErrValidation, "limit: must be between 1 and %d", models.MaxDeliveryLimit).
+				WithDetail(models.DetailField, "limit"))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, h.webhooks.Deliveries(query.Get("webhook"), event, status, limit))
+}
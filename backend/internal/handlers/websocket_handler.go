@@ -1,108 +1,338 @@
 package handlers
 
 import (
-	"encoding/json"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gorilla/websocket"
-	"picks3w2a/internal/services"
+	"picks3w2a/internal/config"
+	"picks3w2a/internal/metrics"
+	"picks3w2a/internal/middleware"
 	"picks3w2a/internal/models"
+	"picks3w2a/internal/ratelimit"
+	"picks3w2a/internal/services"
 
 	wsUpgrader "picks3w2a/pkg/websocket"
 )
 
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
-	roomService *services.RoomService
-	userRooms   map[*websocket.Conn]string // Track which room each connection is in
+	roomService     *services.RoomService
+	tournaments     *services.TournamentService
+	upgrader        *websocket.Upgrader
+	userRooms       map[*websocket.Conn]string // Track which room each connection is in
+	requests        *services.RequestCache     // Outcomes of recent creates and actions, for retries
+	schemaPath      string
+	maxMessageBytes int64
+	sessionSecret   []byte // Signs sessions, so a hello only resumes one issued here
+
+	// Rate limits por tipo de mensaje
+	limits            map[string]messageLimit
+	trustProxyHeaders bool
+}
+
+// messageLimit is the rate limit applied to one inbound message type, both
+// per client IP and per connection
+type messageLimit struct {
+	perIP   *ratelimit.Limiter
+	perConn int
+	period  time.Duration
 }
 
+// rejectedRequests counts inbound messages answered with an error
+var rejectedRequests = metrics.Default.NewCounterVec("draft_rejected_requests_total",
+	"Inbound messages answered with an error, by message type and error code", "type", "code")
+
+// requestInfo identifies the inbound message a response refers to
+type requestInfo struct {
+	Type      string
+	Action    string
+	RequestId string
+}
+
+// requestRetryWindow is how long a retried request_id is recognised
+const requestRetryWindow = 5 * time.Minute
+
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(roomService *services.RoomService) *WebSocketHandler {
+func NewWebSocketHandler(roomService *services.RoomService, tournaments *services.TournamentService, cfg *config.Config, origins *middleware.OriginPolicy) *WebSocketHandler {
+	chatLimiter := ratelimit.NewLimiter(cfg.ChatRatePerIP, time.Minute)
 	return &WebSocketHandler{
-		roomService: roomService,
-		userRooms:   make(map[*websocket.Conn]string),
+		roomService:     roomService,
+		tournaments:     tournaments,
+		upgrader:        wsUpgrader.NewUpgrader(origins.CheckOrigin),
+		userRooms:       make(map[*websocket.Conn]string),
+		requests:        services.NewRequestCache(requestRetryWindow),
+		sessionSecret:   newSessionSecret(),
+		schemaPath:      cfg.SchemaPath,
+		maxMessageBytes: cfg.MaxMessageBytes,
+		limits: map[string]messageLimit{
+			"create": {ratelimit.NewLimiter(cfg.CreateRatePerIP, time.Minute), cfg.CreateRatePerConn, time.Minute},
+			"join":   {ratelimit.NewLimiter(cfg.JoinRatePerIP, time.Minute), cfg.JoinRatePerConn, time.Minute},
+			"action": {ratelimit.NewLimiter(cfg.ActionRatePerIP, time.Second), cfg.ActionRatePerConn, time.Second},
+			// Chat y sugerencias comparten el límite por IP
+			"team_chat": {chatLimiter, cfg.ChatRatePerConn, time.Minute},
+			"suggest":   {chatLimiter, cfg.ChatRatePerConn, time.Minute},
+		},
+		trustProxyHeaders: cfg.TrustProxyHeaders,
 	}
 }
 
 // Handle handles WebSocket connections
 func (h *WebSocketHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	conn, err := wsUpgrader.Upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Error upgrade:", err)
 		return
 	}
+	services.TrackConn(conn)
 	defer func() {
 		// Cleanup when connection closes
 		if roomId, exists := h.userRooms[conn]; exists {
 			h.roomService.RemoveClient(roomId, conn)
 			delete(h.userRooms, conn)
 		}
+		h.tournaments.Unwatch(conn)
 		conn.Close()
+		services.ForgetConn(conn)
 	}()
 
+	// Los mensajes más grandes que el límite cierran la conexión
+	conn.SetReadLimit(h.maxMessageBytes)
+
+	ip := h.clientIP(r)
+	connBuckets := make(map[string]*ratelimit.Bucket)
+	for msgType, limit := range h.limits {
+		connBuckets[msgType] = ratelimit.NewBucket(limit.perConn, limit.period)
+	}
+
+	// Versión negociada en el handshake; los clientes que no envían hello
+	// hablan la versión 1 del protocolo
+	version := 0
+	// Sesión con la que se reconocen los reintentos de la conexión; el hello
+	// puede retomar la de una conexión anterior
+	session := h.newSession()
+
 	for {
 		_, msgBytes, err := conn.ReadMessage()
 		if err != nil {
 			break
 		}
 
-		// Primero intentamos determinar el tipo de mensaje
-		var baseMsg struct {
-			Type string `json:"type"`
+		req, msg, err := decodeInbound(msgBytes)
+		if err != nil {
+			h.sendErrorResponse(conn, req, err)
+			continue
 		}
-		if err := json.Unmarshal(msgBytes, &baseMsg); err != nil {
-			h.sendErrorResponse(conn, "Invalid message format")
+		if err := h.checkRateLimit(ip, connBuckets, req.Type); err != nil {
+			h.sendErrorResponse(conn, req, err)
 			continue
 		}
-		log.Println("baseMsg", baseMsg)
 
 		// Procesar según el tipo de mensaje
-		switch baseMsg.Type {
-		case "create":
-			h.handleCreateRoom(conn, msgBytes)
-		case "join":
-			h.handleJoinRoom(conn, msgBytes)
-		case "action":
-			h.handleAction(conn, msgBytes)
-		default:
-			h.sendErrorResponse(conn, "Unknown message type")
+		switch m := msg.(type) {
+		case *models.HelloMessage:
+			if version != 0 {
+				h.sendErrorResponse(conn, req, models.NewProtocolError(models.ErrHandshakeRepeated, "protocol version already negotiated: %d", version))
+				continue
+			}
+			negotiated, resumed, ok := h.handleHello(conn, req, *m, session)
+			if !ok {
+				return
+			}
+			version, session = negotiated, resumed
+		case *models.CreateMessage:
+			h.handleCreateRoom(conn, req, session, *m)
+		case *models.JoinMessage:
+			h.tournaments.Unwatch(conn)
+			h.handleJoinRoom(conn, req, *m)
+		case *models.ActionMessage:
+			h.handleAction(conn, req, session, *m)
+		case *models.TeamChatMessage:
+			h.handleTeamChannel(conn, req, func(roomId string) error {
+				return h.roomService.TeamChat(roomId, conn, *m)
+			})
+		case *models.SuggestMessage:
+			h.handleTeamChannel(conn, req, func(roomId string) error {
+				return h.roomService.Suggest(roomId, conn, *m)
+			})
+		case *models.WatchTournamentMessage:
+			h.handleWatchTournament(conn, req, *m)
 		}
 	}
 }
 
-func (h *WebSocketHandler) handleCreateRoom(conn *websocket.Conn, msgBytes []byte) {
-	var createMsg models.CreateMessage
-	log.Println("createMsg", createMsg)
-	if err := json.Unmarshal(msgBytes, &createMsg); err != nil {
-		h.sendErrorResponse(conn, "Invalid create message format")
-		return
+// clientIP returns the address used for per-IP rate limiting
+func (h *WebSocketHandler) clientIP(r *http.Request) string {
+	if h.trustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-	log.Println("message OK. unmarshalled")
+	return host
+}
+
+// checkRateLimit consumes a token for msgType from the connection and IP
+// buckets, in that order, so a single noisy connection does not use up the
+// allowance of everyone behind the same IP
+func (h *WebSocketHandler) checkRateLimit(ip string, connBuckets map[string]*ratelimit.Bucket, msgType string) error {
+	limit, limited := h.limits[msgType]
+	if !limited {
+		return nil
+	}
+	if ok, wait := connBuckets[msgType].Allow(); !ok {
+		return rateLimitError(msgType, "connection", wait)
+	}
+	if ok, wait := limit.perIP.Allow(ip); !ok {
+		return rateLimitError(msgType, "ip", wait)
+	}
+	return nil
+}
+
+func rateLimitError(msgType, scope string, wait time.Duration) error {
+	return models.NewProtocolError(models.ErrRateLimited, "too many %s messages, slow down", msgType).
+		WithDetail(models.DetailType, msgType).
+		WithDetail(models.DetailScope, scope).
+		WithDetail(models.DetailRetryMs, strconv.FormatInt(wait.Milliseconds(), 10))
+}
+
+// handleHello negotiates the protocol version and returns it with the session
+// of the connection, the one given by the client if it resumes a previous
+// connection. It returns false if the client speaks no supported version,
+// after telling it so and closing the connection.
+func (h *WebSocketHandler) handleHello(conn *websocket.Conn, req requestInfo, helloMsg models.HelloMessage, session string) (int, string, bool) {
+	version, ok := models.NegotiateProtocolVersion(helloMsg.Versions)
+	if !ok {
+		supported := fmt.Sprint(models.SupportedProtocolVersions)
+		h.sendErrorResponse(conn, req, models.NewProtocolError(models.ErrUnsupportedVersion,
+			"unsupported protocol versions %v, server supports %s", helloMsg.Versions, supported).
+			WithDetail(models.DetailVersions, supported))
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseProtocolError, "unsupported protocol version")
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		return 0, "", false
+	}
+	if helloMsg.Session != "" && h.validSession(helloMsg.Session) {
+		session = helloMsg.Session
+	}
+	log.Printf("Client %q negotiated protocol version %d", helloMsg.Client, version)
+
+	response := models.WelcomeMessage{
+		Type:              "welcome",
+		RequestId:         req.RequestId,
+		Version:           version,
+		SupportedVersions: models.SupportedProtocolVersions,
+		SchemaPath:        h.schemaPath,
+		Session:           session,
+	}
+	if err := services.WriteJSON(conn, response); err != nil {
+		log.Printf("Error sending welcome: %v", err)
+	}
+	return version, session, true
+}
+
+// newSessionSecret returns the key that signs sessions. It is random: like
+// the request cache, sessions do not survive a restart.
+func newSessionSecret() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
+
+// newSession returns a random, signed session for a new connection
+func (h *WebSocketHandler) newSession() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	encoded := base64.RawURLEncoding.EncodeToString(random)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(h.signSession(encoded))
+}
+
+// validSession reports whether a session given in a hello was issued by
+// this server. Others are ignored: a guessed session would get the replies
+// of another client, keys included.
+func (h *WebSocketHandler) validSession(session string) bool {
+	encoded, signature, found := strings.Cut(session, ".")
+	if !found {
+		return false
+	}
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	return err == nil && hmac.Equal(expected, h.signSession(encoded))
+}
+
+func (h *WebSocketHandler) signSession(encoded string) []byte {
+	mac := hmac.New(sha256.New, h.sessionSecret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+func (h *WebSocketHandler) handleCreateRoom(conn *websocket.Conn, req requestInfo, session string, createMsg models.CreateMessage) {
+	// Un create reintentado con el mismo request_id recibe la room del
+	// primero en vez de crear otra
+	cacheKey := ""
+	if req.RequestId != "" {
+		cacheKey = "create/" + session + "/" + req.RequestId
+		if outcome, replay := h.requests.Claim(cacheKey); replay {
+			if outcome.Err != nil {
+				h.sendErrorResponse(conn, req, outcome.Err)
+				return
+			}
+			response := *outcome.Response.(*models.CreateResponseMessage)
+			response.Duplicate = true
+			if err := services.WriteJSON(conn, response); err != nil {
+				log.Printf("Error sending create response: %v", err)
+			}
+			return
+		}
+	}
+
 	// Crear la room usando el servicio
 	response, err := h.roomService.CreateRoom(createMsg)
+	if err == nil {
+		response.RequestId = req.RequestId
+	}
+	if cacheKey != "" {
+		h.requests.Finish(cacheKey, services.RequestOutcome{Response: response, Err: err})
+	}
 	if err != nil {
-		h.sendErrorResponse(conn, "Failed to create room")
+		h.sendErrorResponse(conn, req, err)
 		return
 	}
 
 	// Enviar la respuesta
-	if err := conn.WriteJSON(response); err != nil {
+	if err := services.WriteJSON(conn, response); err != nil {
 		log.Printf("Error sending create response: %v", err)
 	}
 }
 
-func (h *WebSocketHandler) handleJoinRoom(conn *websocket.Conn, msgBytes []byte) {
-	var joinMsg models.JoinMessage
-	if err := json.Unmarshal(msgBytes, &joinMsg); err != nil {
-		h.sendErrorResponse(conn, "Invalid join message format")
-		return
+func (h *WebSocketHandler) handleJoinRoom(conn *websocket.Conn, req requestInfo, joinMsg models.JoinMessage) {
+	// Una conexión solo está en una room a la vez
+	if previous, exists := h.userRooms[conn]; exists && previous != joinMsg.RoomId {
+		h.roomService.RemoveClient(previous, conn)
+		delete(h.userRooms, conn)
 	}
 
 	// Unirse a la room usando el servicio
-	_, err := h.roomService.JoinRoom(joinMsg.RoomId, conn, joinMsg.Key)
+	_, err := h.roomService.JoinRoom(conn, joinMsg)
 	if err != nil {
-		h.sendErrorResponse(conn, err.Error())
+		// Los espectadores pueden esperar a una room reservada que aún no existe
+		var protocolErr *models.ProtocolError
+		if joinMsg.Key == "" && joinMsg.Invite == "" && errors.As(err, &protocolErr) && protocolErr.Code == models.ErrRoomNotFound {
+			h.handleSubscribe(conn, req, joinMsg.RoomId)
+			return
+		}
+		h.sendErrorResponse(conn, req, err)
 		return
 	}
 
@@ -112,135 +342,170 @@ func (h *WebSocketHandler) handleJoinRoom(conn *websocket.Conn, msgBytes []byte)
 	// Obtener la room para enviar el estado
 	room, err := h.roomService.GetRoom(joinMsg.RoomId)
 	if err != nil {
-		h.sendErrorResponse(conn, "Room not found")
+		h.sendErrorResponse(conn, req, err)
 		return
 	}
 
-	// Crear mensaje de estado para enviar al cliente que se une
-	room.TimerMutex.RLock()
-	statusMsg := models.StatusMessage{
-		Type:          "status",
-		CurrentPhase:  room.CurrentPhase,
-		TimePerPick:   room.TimePerPick,
-		TimePerBan:    room.TimePerBan,
-		TimeRemaining: room.TimeRemaining,
-		TimerActive:   room.TimerActive,
-		BlueTeam:      h.convertTeamToTeamStatus(room.BlueTeam),
-		RedTeam:       h.convertTeamToTeamStatus(room.RedTeam),
-		FearlessBans:  h.extractChampionNames(room.FearlessBans),
-	}
-	room.TimerMutex.RUnlock()
-
 	// Enviar el estado actual de la room al cliente que se une
-	if err := conn.WriteJSON(statusMsg); err != nil {
+	statusMsg := h.roomService.RoomStatus(room)
+	if err := services.WriteJSON(conn, statusMsg); err != nil {
 		log.Printf("Error sending room status: %v", err)
 		return
 	}
 
-	// Enviar el estado actualizado de la room a todos los clientes conectados
-	room.TimerMutex.RLock()
-	updatedStatusMsg := models.StatusMessage{
-		Type:          "status",
-		CurrentPhase:  room.CurrentPhase,
-		TimePerPick:   room.TimePerPick,
-		TimePerBan:    room.TimePerBan,
-		TimeRemaining: room.TimeRemaining,
-		TimerActive:   room.TimerActive,
-		BlueTeam:      h.convertTeamToTeamStatus(room.BlueTeam),
-		RedTeam:       h.convertTeamToTeamStatus(room.RedTeam),
-		FearlessBans:  h.extractChampionNames(room.FearlessBans),
-	}
-	room.TimerMutex.RUnlock()
-
 	// Broadcast del estado actual a todos los clientes en la room
-	h.roomService.BroadcastToRoom(joinMsg.RoomId, updatedStatusMsg)
+	h.roomService.BroadcastToRoom(joinMsg.RoomId, h.roomService.RoomStatus(room))
+	h.sendAck(conn, req, false)
 }
 
-func (h *WebSocketHandler) handleAction(conn *websocket.Conn, msgBytes []byte) {
-	var actionMsg models.ActionMessage
-	if err := json.Unmarshal(msgBytes, &actionMsg); err != nil {
-		h.sendErrorResponse(conn, "Invalid action message format")
+// handleSubscribe makes a spectator wait for a room that does not exist yet
+func (h *WebSocketHandler) handleSubscribe(conn *websocket.Conn, req requestInfo, roomId string) {
+	expiresAt, err := h.roomService.SubscribeToRoom(roomId, conn)
+	if err != nil {
+		h.sendErrorResponse(conn, req, err)
+		return
+	}
+	h.userRooms[conn] = roomId
+
+	waitingMsg := models.WaitingForRoomMessage{
+		Type:      "waiting_for_room",
+		RoomId:    roomId,
+		ExpiresAt: expiresAt.Unix(),
+	}
+	if err := services.WriteJSON(conn, waitingMsg); err != nil {
+		log.Printf("Error sending waiting message: %v", err)
 		return
 	}
+	h.sendAck(conn, req, false)
+}
+
+func (h *WebSocketHandler) handleAction(conn *websocket.Conn, req requestInfo, session string, actionMsg models.ActionMessage) {
 
 	// Obtener la room del cliente
 	roomId, exists := h.userRooms[conn]
 	if !exists {
-		h.sendErrorResponse(conn, "You are not in a room")
+		h.sendErrorResponse(conn, req, models.NewProtocolError(models.ErrNotInRoom, "you are not in a room"))
 		return
 	}
 
+	// Un reintento con un request_id ya procesado recibe la misma respuesta
+	// sin volver a aplicarse. La clave es la sesión y no el asiento, que
+	// comparten capitán, jugadores y coach; tras reconectar, el cliente
+	// retoma su sesión en el hello. Un duplicado que llega mientras el
+	// original aún se procesa espera a su resultado.
+	cacheKey := ""
+	if req.RequestId != "" && replayable(actionMsg.Action) {
+		cacheKey = roomId + "/" + session + "/" + req.RequestId
+		if outcome, replay := h.requests.Claim(cacheKey); replay {
+			if outcome.Err != nil {
+				h.sendErrorResponse(conn, req, outcome.Err)
+			} else {
+				h.sendAck(conn, req, true)
+			}
+			return
+		}
+	}
+
 	// Procesar la acción usando el servicio de room
 	err := h.roomService.ProcessAction(roomId, conn, actionMsg)
+	if cacheKey != "" {
+		h.requests.Finish(cacheKey, services.RequestOutcome{Err: err})
+	}
 	if err != nil {
-		h.sendErrorResponse(conn, err.Error())
+		h.sendErrorResponse(conn, req, err)
 		return
 	}
 
 	// Obtener el estado actualizado de la room
 	room, err := h.roomService.GetRoom(roomId)
 	if err != nil {
-		h.sendErrorResponse(conn, "Failed to get room state")
+		h.sendErrorResponse(conn, req, err)
 		return
 	}
 
-	// Crear mensaje de estado actualizado
-	room.TimerMutex.RLock()
-	statusMsg := models.StatusMessage{
-		Type:          "status",
-		CurrentPhase:  room.CurrentPhase,
-		TimePerPick:   room.TimePerPick,
-		TimePerBan:    room.TimePerBan,
-		TimeRemaining: room.TimeRemaining,
-		TimerActive:   room.TimerActive,
-		BlueTeam:      h.convertTeamToTeamStatus(room.BlueTeam),
-		RedTeam:       h.convertTeamToTeamStatus(room.RedTeam),
-		FearlessBans:  h.extractChampionNames(room.FearlessBans),
-	}
-	room.TimerMutex.RUnlock()
-
 	// Broadcast del nuevo estado a todos los clientes en la room
-	h.roomService.BroadcastToRoom(roomId, statusMsg)
+	h.roomService.BroadcastToRoom(roomId, h.roomService.RoomStatus(room))
+	h.sendAck(conn, req, false)
 }
 
-func (h *WebSocketHandler) sendErrorResponse(conn *websocket.Conn, message string) {
-	response := map[string]string{
-		"type":  "error",
-		"message": message,
+// replayable reports whether a retried action can be answered from the
+// request cache. rotate_key and issue_invite are not: their key or invite is
+// only sent in their response, so a retry whose response was lost must run
+// again to get a new one.
+func replayable(action string) bool {
+	return action != "rotate_key" && action != "issue_invite"
+}
+
+// handleWatchTournament subscribes the connection to the standings of a
+// tournament. Like joining another room, it leaves the current one.
+func (h *WebSocketHandler) handleWatchTournament(conn *websocket.Conn, req requestInfo, watchMsg models.WatchTournamentMessage) {
+	if roomId, exists := h.userRooms[conn]; exists {
+		h.roomService.RemoveClient(roomId, conn)
+		delete(h.userRooms, conn)
 	}
-	if err := conn.WriteJSON(response); err != nil {
-		log.Printf("Error sending error response: %v", err)
+	if err := h.tournaments.Watch(conn, watchMsg.TournamentId); err != nil {
+		h.sendErrorResponse(conn, req, err)
+		return
 	}
+	h.sendAck(conn, req, false)
 }
 
-func (h *WebSocketHandler) sendSuccessResponse(conn *websocket.Conn, message string) {
-	response := map[string]string{
-		"type":  "success",
-		"message": message,
+// handleTeamChannel runs a team chat or suggestion in the client's room. The
+// message itself only reaches the team; the sender also gets an ack.
+func (h *WebSocketHandler) handleTeamChannel(conn *websocket.Conn, req requestInfo, post func(roomId string) error) {
+	roomId, exists := h.userRooms[conn]
+	if !exists {
+		h.sendErrorResponse(conn, req, models.NewProtocolError(models.ErrNotInRoom, "you are not in a room"))
+		return
 	}
-	if err := conn.WriteJSON(response); err != nil {
-		log.Printf("Error sending success response: %v", err)
+	if err := post(roomId); err != nil {
+		h.sendErrorResponse(conn, req, err)
+		return
 	}
+	h.sendAck(conn, req, false)
 }
 
-// convertTeamToTeamStatus converts a Team object to TeamStatus with only champion names
-func (h *WebSocketHandler) convertTeamToTeamStatus(team models.Team) models.TeamStatus {
-	return models.TeamStatus{
-		Name:  team.Name,
-		Bans:  h.extractChampionNames(team.Bans),
-		Picks: h.extractChampionNames(team.Picks),
+// sendErrorResponse sends err to the client as an ErrorMessage correlated with
+// the request that caused it. Errors that are not a ProtocolError are logged
+// and reported as internal errors so no implementation details leak.
+func (h *WebSocketHandler) sendErrorResponse(conn *websocket.Conn, req requestInfo, err error) {
+	var protocolErr *models.ProtocolError
+	if !errors.As(err, &protocolErr) {
+		log.Printf("Unexpected error handling %q message: %v", req.Type, err)
+		protocolErr = models.NewProtocolError(models.ErrInternal, "internal server error")
+	}
+
+	// Los tipos desconocidos se agrupan para no disparar la cardinalidad
+	metricType := req.Type
+	if _, known := models.InboundMessages[metricType]; !known {
+		metricType = "unknown"
+	}
+	rejectedRequests.Inc(metricType, string(protocolErr.Code))
+
+	response := models.ErrorMessage{
+		Type:        "error",
+		RequestId:   req.RequestId,
+		Code:        protocolErr.Code,
+		Message:     protocolErr.Message,
+		Details:     protocolErr.Details,
+		RequestType: req.Type,
+		Action:      req.Action,
+	}
+	if err := services.WriteJSON(conn, response); err != nil {
+		log.Printf("Error sending error response: %v", err)
 	}
 }
 
-// extractChampionNames extracts only the names from a slice of Champion objects
-func (h *WebSocketHandler) extractChampionNames(champions []models.Champion) []string {
-	names := make([]string, len(champions))
-	for i, champion := range champions {
-		if champion.Name == "-1" {
-			names[i] = "" // Posición vacía
-		} else {
-			names[i] = champion.Name
-		}
+// sendAck confirms to the client that the request was applied
+func (h *WebSocketHandler) sendAck(conn *websocket.Conn, req requestInfo, duplicate bool) {
+	response := models.AckMessage{
+		Type:        "ack",
+		RequestId:   req.RequestId,
+		RequestType: req.Type,
+		Action:      req.Action,
+		Duplicate:   duplicate,
+	}
+	if err := services.WriteJSON(conn, response); err != nil {
+		log.Printf("Error sending ack: %v", err)
 	}
-	return names
 }
@@ -4,11 +4,18 @@ import (
 	"net/http"
 )
 
-// CORS middleware to handle Cross-Origin Resource Sharing
-func CORS(next http.Handler) http.Handler {
+// CORS middleware to handle Cross-Origin Resource Sharing for the origins
+// allowed by policy
+func CORS(policy *OriginPolicy, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		// Set CORS headers only for allowed origins
+		origin := r.Header.Get("Origin")
+		if policy.AllowsAll() {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origin != "" && policy.Allowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Add("Vary", "Origin")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization")
 
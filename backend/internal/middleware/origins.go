@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginPolicy decides which browser origins may use the API and open
+// WebSocket connections. It is shared by CORS and the WebSocket upgrader so
+// both enforce the same allow-list.
+type OriginPolicy struct {
+	allowAll  bool
+	origins   map[string]bool
+	wildcards []string // Suffixes such as ".example.com" from "https://*.example.com"
+}
+
+// NewOriginPolicy creates a policy from a list of origins such as
+// "https://draft.example.com". "*" allows every origin and "https://*.example.com"
+// allows any subdomain over that scheme. Same-origin requests are always allowed.
+func NewOriginPolicy(allowed []string) *OriginPolicy {
+	p := &OriginPolicy{origins: make(map[string]bool)}
+	for _, origin := range allowed {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "":
+		case origin == "*":
+			p.allowAll = true
+		case strings.Contains(origin, "://*."):
+			p.wildcards = append(p.wildcards, strings.Replace(origin, "://*.", "://.", 1))
+		default:
+			p.origins[origin] = true
+		}
+	}
+	return p
+}
+
+// AllowsAll reports whether every origin is allowed
+func (p *OriginPolicy) AllowsAll() bool {
+	return p.allowAll
+}
+
+// Allowed reports whether origin may access the server
+func (p *OriginPolicy) Allowed(origin string) bool {
+	if p.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, wildcard := range p.wildcards {
+		// wildcard es "scheme://.dominio"
+		scheme, suffix, _ := strings.Cut(wildcard, "://")
+		if u.Scheme == scheme && strings.HasSuffix(u.Host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin is a websocket.Upgrader CheckOrigin function. Requests without
+// an Origin header come from non-browser clients such as bots and are allowed.
+func (p *OriginPolicy) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return p.Allowed(origin)
+}
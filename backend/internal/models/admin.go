@@ -0,0 +1,26 @@
+package models
+
+// InviteRequest asks the admin HTTP API for an invite to a seat of a room,
+// which does not need to exist yet
+type InviteRequest struct {
+	RoomId    string `json:"room_id"`
+	Seat      string `json:"seat"`
+	ExpiresIn int    `json:"expires_in,omitempty"` // Seconds, defaults to a day
+}
+
+// Validate checks the room and seat of an invite request
+func (m InviteRequest) Validate() error {
+	if err := checkRequired("room_id", m.RoomId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	if err := checkRoomId("room_id", m.RoomId); err != nil {
+		return err
+	}
+	if err := checkSeat("seat", m.Seat, SeatBlue, SeatRed, SeatReferee); err != nil {
+		return err
+	}
+	if m.ExpiresIn < 0 {
+		return validationError("expires_in", "must not be negative")
+	}
+	return nil
+}
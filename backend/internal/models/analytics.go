@@ -0,0 +1,177 @@
+package models
+
+// DraftFilter selects finished drafts from the draft store. Empty fields
+// match every draft.
+type DraftFilter struct {
+	TournamentId string `json:"tournament_id,omitempty"`
+	Team         string `json:"team,omitempty"` // Team name on either side, case-insensitive
+	Patch        string `json:"patch,omitempty"`
+	From         int64  `json:"from,omitempty"` // Unix seconds, inclusive
+	To           int64  `json:"to,omitempty"`   // Unix seconds, exclusive
+}
+
+// Validate checks the lengths and the date range of a filter
+func (f DraftFilter) Validate() error {
+	if err := checkLength("tournament", f.TournamentId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	if err := checkLength("team", f.Team, MaxTeamNameLength); err != nil {
+		return err
+	}
+	if err := checkLength("patch", f.Patch, MaxPatchLength); err != nil {
+		return err
+	}
+	if f.From != 0 && f.To != 0 && f.To <= f.From {
+		return validationError("to", "must be after from")
+	}
+	return nil
+}
+
+// SideStats counts the drafts of a champion on one side
+type SideStats struct {
+	Picks   int     `json:"picks"`
+	Bans    int     `json:"bans"` // Bans made by the team on this side
+	Wins    int     `json:"wins"`
+	Decided int     `json:"decided"` // Picks in drafts with a reported result
+	WinRate float64 `json:"win_rate"`
+}
+
+// ChampionStats are the statistics of a champion over the selected drafts.
+// Rates are over the number of drafts, win rates over decided picks.
+type ChampionStats struct {
+	Champion      string    `json:"champion"`
+	Picks         int       `json:"picks"`
+	Bans          int       `json:"bans"`
+	Presence      int       `json:"presence"` // Drafts where it was picked or banned
+	PickRate      float64   `json:"pick_rate"`
+	BanRate       float64   `json:"ban_rate"`
+	PresenceRate  float64   `json:"presence_rate"`
+	Wins          int       `json:"wins"`
+	Decided       int       `json:"decided"`
+	WinRate       float64   `json:"win_rate"`
+	FirstPicks    int       `json:"first_picks"` // Times it was the first pick of the draft
+	FirstPickRate float64   `json:"first_pick_rate"`
+	AvgPickTurn   float64   `json:"avg_pick_turn,omitempty"` // From 1 to 6 across both teams, lower is picked earlier
+	Blue          SideStats `json:"blue"`
+	Red           SideStats `json:"red"`
+}
+
+// ChampionReport is the champion analytics of the drafts matching Filter.
+// With a team in the filter only the picks and bans of that team count.
+type ChampionReport struct {
+	Filter    DraftFilter     `json:"filter"`
+	Drafts    int             `json:"drafts"`
+	Decided   int             `json:"decided"`   // Drafts with a reported result
+	Champions []ChampionStats `json:"champions"` // By presence, then picks, then name
+}
+
+// TeamTiming is how long a team takes over its turns, in seconds. Only turns
+// with a recorded time count, so drafts played before turn times were
+// recorded are left out; a turn the timer ran out counts its whole time.
+type TeamTiming struct {
+	Team         string  `json:"team"`
+	Drafts       int     `json:"drafts"` // Drafts with turn times
+	Picks        int     `json:"picks"`
+	AvgPickTime  float64 `json:"avg_pick_time"`
+	MaxPickTime  float64 `json:"max_pick_time"`
+	Bans         int     `json:"bans"`
+	AvgBanTime   float64 `json:"avg_ban_time"`
+	MaxBanTime   float64 `json:"max_ban_time"`
+	Timeouts     int     `json:"timeouts"`       // Turns locked by the timer
+	AvgDraftTime float64 `json:"avg_draft_time"` // From the first turn to the end of the draft, trade phase included
+}
+
+// TimingReport is the turn timing of every team in the drafts matching
+// Filter. With a team in the filter it only has that team.
+type TimingReport struct {
+	Filter DraftFilter  `json:"filter"`
+	Drafts int          `json:"drafts"`
+	Teams  []TeamTiming `json:"teams"` // By name
+}
+
+// Results of a draft from the point of view of a team in a scouting report
+const (
+	DraftWon  = "win"
+	DraftLost = "loss"
+)
+
+// ChampionCount is how often a champion appears in a list of a scouting
+// report. Rate is over the drafts of the team, or over the drafts of the
+// phase in a PhaseTendency.
+type ChampionCount struct {
+	Champion string  `json:"champion"`
+	Count    int     `json:"count"`
+	Rate     float64 `json:"rate"`
+}
+
+// PhaseTendency lists the champions a team locked in a phase of the draft.
+// Drafts counts the drafts where the phase was the team's turn.
+type PhaseTendency struct {
+	Phase     Phase           `json:"phase"`
+	Drafts    int             `json:"drafts"`
+	Champions []ChampionCount `json:"champions"`
+}
+
+// SeriesGame is a game of a fearless series in a scouting report
+type SeriesGame struct {
+	Game         int      `json:"game"`
+	RoomId       string   `json:"room_id"`
+	Side         string   `json:"side"`          // Played by the team
+	FearlessBans int      `json:"fearless_bans"` // Champions disabled at the start of the game
+	Picks        []string `json:"picks"`         // Of the team
+	Result       string   `json:"result,omitempty"`
+}
+
+// SeriesDepletion follows the champion pool of a team through a fearless
+// series. Depleted lists the champions the team used up, in order.
+type SeriesDepletion struct {
+	TournamentId string       `json:"tournament_id"`
+	MatchId      string       `json:"match_id"`
+	Opponent     string       `json:"opponent"`
+	Games        []SeriesGame `json:"games"`
+	Depleted     []string     `json:"depleted"`
+}
+
+// DraftSummary is a draft of the team in its head-to-head history
+type DraftSummary struct {
+	RoomId        string    `json:"room_id"`
+	CompletedAt   int64     `json:"completed_at"` // Unix seconds
+	Patch         string    `json:"patch,omitempty"`
+	Match         *MatchRef `json:"match,omitempty"`
+	Side          string    `json:"side"`
+	Result        string    `json:"result,omitempty"` // "win" or "loss" once reported
+	Picks         []string  `json:"picks"`
+	Bans          []string  `json:"bans"`
+	OpponentPicks []string  `json:"opponent_picks"`
+	OpponentBans  []string  `json:"opponent_bans"`
+}
+
+// HeadToHead is the record of the team against an opponent, newest draft
+// first
+type HeadToHead struct {
+	Opponent string         `json:"opponent"`
+	Drafts   int            `json:"drafts"`
+	Wins     int            `json:"wins"`
+	Losses   int            `json:"losses"`
+	History  []DraftSummary `json:"history"`
+}
+
+// ScoutingReport sums up how a team drafts, from its finished drafts
+// matching Filter. Champion lists are sorted by count, then name.
+type ScoutingReport struct {
+	Team          string            `json:"team"`
+	Filter        DraftFilter       `json:"filter"`
+	Drafts        int               `json:"drafts"`
+	Wins          int               `json:"wins"`
+	Losses        int               `json:"losses"`
+	BlueDrafts    int               `json:"blue_drafts"`
+	RedDrafts     int               `json:"red_drafts"`
+	Picks         []ChampionCount   `json:"picks"`
+	FirstPicks    []ChampionCount   `json:"first_picks"` // Earliest pick of the team in each draft
+	Bans          []ChampionCount   `json:"bans"`
+	BannedAgainst []ChampionCount   `json:"banned_against"` // Bans of its opponents
+	Phases        []PhaseTendency   `json:"phases"`         // In draft order, blue and red turns apart
+	Series        []SeriesDepletion `json:"series"`         // Fearless series, oldest first
+	HeadToHead    []HeadToHead      `json:"head_to_head"`   // By drafts played, then opponent
+	Timing        TeamTiming        `json:"timing"`
+}
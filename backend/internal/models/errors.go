@@ -0,0 +1,111 @@
+package models
+
+import "fmt"
+
+// ErrorCode is the machine-readable identifier of a protocol error
+type ErrorCode string
+
+const (
+	// Message decoding and routing
+	ErrInvalidMessage     ErrorCode = "invalid_message"
+	ErrUnknownMessageType ErrorCode = "unknown_message_type"
+	ErrUnknownAction      ErrorCode = "unknown_action"
+	ErrValidation         ErrorCode = "validation_failed"
+	ErrRateLimited        ErrorCode = "rate_limited"
+
+	// Protocol handshake
+	ErrUnsupportedVersion ErrorCode = "unsupported_version"
+	ErrHandshakeRepeated  ErrorCode = "handshake_repeated"
+
+	// Room membership
+	ErrRoomNotFound             ErrorCode = "room_not_found"
+	ErrNotInRoom                ErrorCode = "not_in_room"
+	ErrClientNotFound           ErrorCode = "client_not_found"
+	ErrInvalidKey               ErrorCode = "invalid_key"
+	ErrSpectatorAction          ErrorCode = "spectator_action"
+	ErrRoomLimitReached         ErrorCode = "room_limit_reached"
+	ErrRoomFull                 ErrorCode = "room_full"
+	ErrRoomExists               ErrorCode = "room_exists"
+	ErrSubscriptionLimitReached ErrorCode = "subscription_limit_reached"
+	ErrInvalidInvite            ErrorCode = "invalid_invite"
+	ErrInviteExpired            ErrorCode = "invite_expired"
+	ErrNotReferee               ErrorCode = "not_referee"
+	ErrSeatTaken                ErrorCode = "seat_taken"
+	ErrCaptainOnly              ErrorCode = "captain_only"
+
+	// Draft rules
+	ErrInvalidPhase      ErrorCode = "invalid_phase"
+	ErrAlreadyReady      ErrorCode = "already_ready"
+	ErrNotReady          ErrorCode = "not_ready"
+	ErrNotYourTurn       ErrorCode = "not_your_turn"
+	ErrChampionRequired  ErrorCode = "champion_required"
+	ErrChampionBanned    ErrorCode = "champion_banned"
+	ErrChampionPicked    ErrorCode = "champion_picked"
+	ErrChampionFearless  ErrorCode = "champion_fearless_banned"
+	ErrInvalidTrade      ErrorCode = "invalid_trade"
+	ErrChampionNotInPool ErrorCode = "champion_not_in_pool"
+	ErrResultExists      ErrorCode = "result_exists"
+	ErrUnknownChampion   ErrorCode = "unknown_champion"
+	ErrInvalidDraft      ErrorCode = "invalid_draft"
+
+	// Tournament registry
+	ErrTournamentNotFound ErrorCode = "tournament_not_found"
+	ErrTeamNotFound       ErrorCode = "team_not_found"
+	ErrMatchNotFound      ErrorCode = "match_not_found"
+	ErrDuplicateId        ErrorCode = "duplicate_id"
+	ErrGameInProgress     ErrorCode = "game_in_progress"
+	ErrSeriesOver         ErrorCode = "series_over"
+	ErrMatchNotReady      ErrorCode = "match_not_ready"
+
+	// Webhooks
+	ErrWebhookNotFound ErrorCode = "webhook_not_found"
+
+	// Fallback for unexpected failures
+	ErrInternal ErrorCode = "internal_error"
+)
+
+// Keys used in ProtocolError.Details
+const (
+	DetailChampion = "champion"
+	DetailPhase    = "phase"
+	DetailTeam     = "team"
+	DetailAction   = "action"
+	DetailRoomId   = "room_id"
+	DetailType     = "type"
+	DetailVersions = "supported_versions"
+	DetailField    = "field"
+	DetailScope    = "scope"
+	DetailRetryMs  = "retry_after_ms"
+	DetailRole     = "role"
+	DetailId       = "id"
+)
+
+// ProtocolError is an error returned by the services that is sent back to
+// the client as an ErrorMessage
+type ProtocolError struct {
+	Code    ErrorCode
+	Message string
+	Details map[string]string
+}
+
+// NewProtocolError creates a ProtocolError with a formatted message
+func NewProtocolError(code ErrorCode, format string, args ...interface{}) *ProtocolError {
+	return &ProtocolError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// WithDetail adds a detail to the error and returns it for chaining
+func (e *ProtocolError) WithDetail(key, value string) *ProtocolError {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// Error implements the error interface
+func (e *ProtocolError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,210 @@
+package models
+
+// ExportSchemaVersion is the version of the DraftExport and SeriesExport
+// formats, bumped on incompatible changes
+const ExportSchemaVersion = 1
+
+// Slots of a team in a draft
+const (
+	TeamBans  = 5
+	TeamPicks = 3
+)
+
+// Kinds of DraftAction
+const (
+	ActionBan  = "ban"
+	ActionPick = "pick"
+)
+
+// DraftAction is a turn of a draft. Champion is empty if the turn ran out
+// without a champion. The timing fields are 0 for drafts played before they
+// were recorded.
+type DraftAction struct {
+	Seq      int    `json:"seq"` // From 1, in draft order
+	Phase    Phase  `json:"phase"`
+	Side     string `json:"side"`
+	Team     string `json:"team"`
+	Kind     string `json:"kind"` // "ban" or "pick"
+	Champion string `json:"champion"`
+	Player   string `json:"player,omitempty"`    // Assigned to a pick during the trade phase
+	LockedAt int64  `json:"locked_at,omitempty"` // Unix milliseconds
+	TimeUsed int    `json:"time_used,omitempty"` // Milliseconds
+	TimedOut bool   `json:"timed_out,omitempty"` // Locked by the timer
+}
+
+// ExportPick is a pick of a team in its final order
+type ExportPick struct {
+	Champion string `json:"champion"`
+	Turn     int    `json:"turn,omitempty"` // Order of the pick in the draft across both teams
+	Player   string `json:"player,omitempty"`
+	PlayerId string `json:"player_id,omitempty"`
+}
+
+// ExportTeam is a team of an exported draft
+type ExportTeam struct {
+	Name   string       `json:"name"`
+	Roster []Player     `json:"roster,omitempty"`
+	Bans   []string     `json:"bans"`
+	Picks  []ExportPick `json:"picks"`
+}
+
+// DraftExport is the canonical export of a finished draft
+type DraftExport struct {
+	SchemaVersion int           `json:"schema_version"`
+	RoomId        string        `json:"room_id"`
+	Patch         string        `json:"patch,omitempty"`
+	Match         *MatchRef     `json:"match,omitempty"`
+	CompletedAt   int64         `json:"completed_at"` // Unix seconds
+	Result        *GameResult   `json:"result,omitempty"`
+	Imported      bool          `json:"imported,omitempty"` // Entered after the game, see DraftExport.Validate
+	Times         *DraftTimes   `json:"times,omitempty"`    // Unset for drafts played before they were recorded
+	BlueTeam      ExportTeam    `json:"blue_team"`
+	RedTeam       ExportTeam    `json:"red_team"`
+	FearlessBans  []string      `json:"fearless_bans"`
+	Actions       []DraftAction `json:"actions"`
+}
+
+// SeriesExport is the export of the finished games of a tournament match.
+// Team names are those of its blue and red teams in the first game.
+type SeriesExport struct {
+	SchemaVersion int           `json:"schema_version"`
+	TournamentId  string        `json:"tournament_id"`
+	Tournament    string        `json:"tournament"`
+	MatchId       string        `json:"match_id"`
+	BestOf        int           `json:"best_of"`
+	Fearless      bool          `json:"fearless,omitempty"`
+	BlueTeam      string        `json:"blue_team"`
+	RedTeam       string        `json:"red_team"`
+	BlueWins      int           `json:"blue_wins"`
+	RedWins       int           `json:"red_wins"`
+	Winner        string        `json:"winner,omitempty"` // Team name, once decided
+	Games         []DraftExport `json:"games"`
+}
+
+// Validate checks the format of a draft to import. Drafts are imported from
+// their actions if there are any, from the bans and picks of the teams
+// otherwise; the champions are checked against the catalog by the import.
+func (d DraftExport) Validate() error {
+	if d.SchemaVersion != 0 && d.SchemaVersion != ExportSchemaVersion {
+		return validationError("schema_version", "must be %d", ExportSchemaVersion)
+	}
+	if err := checkRoomId("room_id", d.RoomId); err != nil {
+		return err
+	}
+	if err := checkLength("patch", d.Patch, MaxPatchLength); err != nil {
+		return err
+	}
+	if d.CompletedAt < 0 {
+		return validationError("completed_at", "must be a Unix time in seconds")
+	}
+	if d.Times != nil {
+		for _, time := range []struct {
+			field string
+			at    int64
+		}{
+			{"times.created_at", d.Times.CreatedAt},
+			{"times.blue_ready_at", d.Times.BlueReadyAt},
+			{"times.red_ready_at", d.Times.RedReadyAt},
+			{"times.started_at", d.Times.StartedAt},
+			{"times.finished_at", d.Times.FinishedAt},
+		} {
+			if time.at < 0 {
+				return validationError(time.field, "must be a Unix time in milliseconds")
+			}
+		}
+	}
+	if d.Match != nil {
+		if err := checkRequired("match.tournament_id", d.Match.TournamentId, MaxRoomIdLength); err != nil {
+			return err
+		}
+		if err := checkRequired("match.match_id", d.Match.MatchId, MaxMatchIdLength); err != nil {
+			return err
+		}
+		if err := checkRange("match.game", d.Match.Game, 0, MaxBestOf); err != nil {
+			return err
+		}
+	}
+	if d.Result != nil {
+		if err := checkSeat("result.winner", d.Result.Winner, SeatBlue, SeatRed); err != nil {
+			return err
+		}
+		if err := checkRange("result.duration", d.Result.Duration, 0, MaxGameDuration); err != nil {
+			return err
+		}
+		if err := checkLength("result.notes", d.Result.Notes, MaxResultNotesLength); err != nil {
+			return err
+		}
+	}
+	if err := d.BlueTeam.validate("blue_team", len(d.Actions) > 0); err != nil {
+		return err
+	}
+	if err := d.RedTeam.validate("red_team", len(d.Actions) > 0); err != nil {
+		return err
+	}
+	if err := checkChampionList("fearless_bans", d.FearlessBans, MaxFearlessBans); err != nil {
+		return err
+	}
+
+	if len(d.Actions) > len(DraftSequence(true)) {
+		return validationError("actions", "must have at most %d entries", len(DraftSequence(true)))
+	}
+	for _, action := range d.Actions {
+		if err := checkLength("actions.team", action.Team, MaxTeamNameLength); err != nil {
+			return err
+		}
+		if err := checkLength("actions.champion", action.Champion, MaxChampionNameLength); err != nil {
+			return err
+		}
+		if err := checkLength("actions.player", action.Player, MaxNicknameLength); err != nil {
+			return err
+		}
+		if action.LockedAt < 0 {
+			return validationError("actions.locked_at", "must be a Unix time in milliseconds")
+		}
+		if err := checkRange("actions.time_used", action.TimeUsed, 0, MaxTimePerAction*1000); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks a team of a draft to import. Its name may come from the
+// actions instead, which also replace its bans and picks.
+func (t ExportTeam) validate(field string, fromActions bool) error {
+	if !fromActions {
+		if err := checkRequired(field+".name", t.Name, MaxTeamNameLength); err != nil {
+			return err
+		}
+	} else if err := checkLength(field+".name", t.Name, MaxTeamNameLength); err != nil {
+		return err
+	}
+	if err := checkRoster(field+".roster", t.Roster); err != nil {
+		return err
+	}
+	if len(t.Bans) > TeamBans {
+		return validationError(field+".bans", "must have at most %d entries", TeamBans)
+	}
+	for _, ban := range t.Bans {
+		if err := checkLength(field+".bans", ban, MaxChampionNameLength); err != nil {
+			return err
+		}
+	}
+	if len(t.Picks) > TeamPicks {
+		return validationError(field+".picks", "must have at most %d entries", TeamPicks)
+	}
+	for _, pick := range t.Picks {
+		if err := checkRequired(field+".picks.champion", pick.Champion, MaxChampionNameLength); err != nil {
+			return err
+		}
+		if err := checkRange(field+".picks.turn", pick.Turn, 0, len(PickPhases)); err != nil {
+			return err
+		}
+		if err := checkLength(field+".picks.player", pick.Player, MaxNicknameLength); err != nil {
+			return err
+		}
+		if err := checkLength(field+".picks.player_id", pick.PlayerId, MaxPlayerIdLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
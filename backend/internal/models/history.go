@@ -0,0 +1,47 @@
+package models
+
+// Orders of the draft history, by completion time
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+	MaxCursorLength     = 256
+)
+
+// DraftQuery selects a page of the finished drafts
+type DraftQuery struct {
+	DraftFilter
+	Champion string `json:"champion,omitempty"` // Picked or banned by either team
+	Sort     string `json:"sort,omitempty"`     // "newest" (default) or "oldest"
+	Limit    int    `json:"limit,omitempty"`    // Drafts per page, DefaultHistoryLimit if 0
+	Cursor   string `json:"cursor,omitempty"`   // NextCursor of the previous page
+}
+
+// Validate checks the filter, the order and the page size of a query
+func (q DraftQuery) Validate() error {
+	if err := q.DraftFilter.Validate(); err != nil {
+		return err
+	}
+	if err := checkLength("champion", q.Champion, MaxChampionNameLength); err != nil {
+		return err
+	}
+	if q.Sort != "" {
+		if err := checkSeat("sort", q.Sort, SortNewest, SortOldest); err != nil {
+			return err
+		}
+	}
+	if err := checkRange("limit", q.Limit, 0, MaxHistoryLimit); err != nil {
+		return err
+	}
+	return checkLength("cursor", q.Cursor, MaxCursorLength)
+}
+
+// HistoryPage is a page of the draft history
+type HistoryPage struct {
+	Drafts     []DraftExport `json:"drafts"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Empty on the last page
+}
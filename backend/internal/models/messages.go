@@ -10,42 +10,83 @@ type CreateMessage struct {
 	TimePerPick int 					`json:"time_per_pick"`
 	TimePerBan int 					`json:"time_per_ban"`
 	FearlessBans []string 		`json:"fearless_bans,omitempty"`
+	ChampionPool []string `json:"champion_pool,omitempty"` // Champions allowed in the draft, all if empty
+	Patch string `json:"patch,omitempty"` // Game patch, the server default if empty
+	BlueRoster []Player `json:"blue_roster,omitempty"`
+	RedRoster []Player `json:"red_roster,omitempty"`
+	TradePhase bool `json:"trade_phase,omitempty"` // Let teams reorder and assign picks after the draft
+	TradeTime int `json:"trade_time,omitempty"` // Seconds, defaults to 60
+	RoomId string `json:"room_id,omitempty"` // Set by the tournament API for game rooms; rejected from clients
+	SideSelection bool `json:"side_selection,omitempty"` // Start with a side selection phase
+	SideChooser string `json:"side_chooser,omitempty"` // "blue" or "red" picks side, e.g. the higher seed; a coin flip decides if empty
+	RequestId string `json:"request_id,omitempty"`
 }
 
 type CreateResponseMessage struct {
 	Type string        `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
 	RoomId string 	`json:"room_id"`
 	RedTeamKey   string `json:"red_team_key"`
 	BlueTeamKey   string `json:"blue_team_key"`
+	RefereeKey string `json:"referee_key"` // Only sent here, the server keeps a hash
+	CoinFlipCommitment string `json:"coin_flip_commitment,omitempty"` // Published before the coin flip is revealed
+	Duplicate bool `json:"duplicate,omitempty"` // A retried create, answered with the room created by the first one
 }
 
 type JoinMessage struct {
 	Type string        `json:"type"`
 	RoomId string 	`json:"room_id"`
 	Key   string `json:"key,omitempty"`
+	Invite string `json:"invite,omitempty"` // Signed invite token, used instead of Key
+	Role string `json:"role,omitempty"` // "captain", "player" or "coach"; defaults to captain if the seat is free
+	Nickname string `json:"nickname,omitempty"` // Shown in the presence list
+	RequestId string `json:"request_id,omitempty"`
 }
 
 type ActionMessage struct {
 	Type string        `json:"type"`
-	Action string      `json:"action"` // "ready", "champ_select", "champ_pick"
+	Action string      `json:"action"` // "ready", "unready", "champ_select", "champ_pick", "choose_side", "trade_reorder", "trade_assign", "trade_confirm", "force_start", "report_result", "rotate_key", "issue_invite"
 	Champion string 	`json:"champion,omitempty"`
+	Seat string `json:"seat,omitempty"` // Target seat of referee actions
+	Side string `json:"side,omitempty"` // "blue" or "red", for choose_side and the winner of report_result
+	Order []int `json:"order,omitempty"` // New order of the picks as indices of the current ones, for trade_reorder
+	Player string `json:"player,omitempty"` // Player receiving Champion, for trade_assign; a roster ID or name if the team has a roster
+	ExpiresIn int `json:"expires_in,omitempty"` // Seconds, for issue_invite
+	Duration int `json:"duration,omitempty"` // Seconds of game time, for report_result
+	Notes string `json:"notes,omitempty"` // For report_result
+	RequestId string `json:"request_id,omitempty"`
 }
 
 type TeamMessage struct {
 	Name string `json:"name"`
-	Bans []int `json:"bans"`
-	Picks []int `json:"picks"`
+	Bans []string `json:"bans"`
+	Picks []string `json:"picks"`
 }
 
 type TeamStatus struct {
 	Name string `json:"name"`
 	Bans []string `json:"bans"`
 	Picks []string `json:"picks"`
+	Roster []Player `json:"roster,omitempty"`
+	Players []string `json:"players,omitempty"` // Player assigned to each pick, "" if unassigned
+	PlayerIds []string `json:"player_ids,omitempty"` // Roster ID of the player of each pick
+	TradeConfirmed bool `json:"trade_confirmed,omitempty"`
+	BanTimes []int `json:"ban_times,omitempty"` // Milliseconds each ban took, 0 until played; unset before the first lock
+	PickTimes []int `json:"pick_times,omitempty"` // Milliseconds each pick took, in the order of Picks
 }
 
 type StatusMessage struct {
 	Type string          			`json:"type"`
 	CurrentPhase  Phase  	`json:"current_phase"`
+	SideChooser string `json:"side_chooser,omitempty"` // Team that picks side during SideSelection
+	CoinFlip *CoinFlip `json:"coin_flip,omitempty"`
+	Match *MatchRef `json:"match,omitempty"` // Tournament match of the room, if any
+	Patch string `json:"patch,omitempty"`
+	Result *GameResult `json:"result,omitempty"`
+	ResultReports map[string]*GameResult `json:"result_reports,omitempty"` // Captain reports by seat while the result is pending
+	ResultDisputed bool `json:"result_disputed,omitempty"` // The captains reported different winners, the referee decides
+	Times DraftTimes `json:"times"`
+	TurnStartedAt int64 `json:"turn_started_at,omitempty"` // Unix milliseconds the current ban or pick turn started
 	TimePerPick int 					`json:"time_per_pick"`
 	TimePerBan int 					`json:"time_per_ban"`
 	TimeRemaining int         `json:"time_remaining"`
@@ -59,4 +100,160 @@ type UserJoinedMessage struct {
 	Type string `json:"type"`
 	Message string `json:"message"`
 	Team string `json:"team,omitempty"` // "blue", "red", or "" for spectator
-}
\ No newline at end of file
+}
+
+type ErrorMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	Code ErrorCode `json:"code"`
+	Message string `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+	RequestType string `json:"request_type,omitempty"` // Type of the message that caused the error
+	Action string `json:"action,omitempty"` // Action of the message that caused the error, if any
+}
+
+// AckMessage confirms that the request identified by RequestId was applied
+type AckMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	RequestType string `json:"request_type"`
+	Action string `json:"action,omitempty"`
+	Duplicate bool `json:"duplicate,omitempty"` // The request was already applied and has not been applied again
+}
+
+// HelloMessage opens the protocol handshake with the versions the client speaks
+type HelloMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	Versions []int `json:"versions"`
+	Client string `json:"client,omitempty"` // Free-form client name, for logging
+	Session string `json:"session,omitempty"` // Session of a previous connection, to keep recognising its retried requests
+}
+
+// WelcomeMessage answers a HelloMessage with the negotiated protocol version
+type WelcomeMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	Version int `json:"version"`
+	SupportedVersions []int `json:"supported_versions"`
+	SchemaPath string `json:"schema_path"`
+	Session string `json:"session"` // Identifies the requests of this connection; send it in the hello of a new connection to resume it
+}
+
+// RoomClosedMessage tells the clients of a room that it was evicted from the server
+type RoomClosedMessage struct {
+	Type string `json:"type"`
+	RoomId string `json:"room_id"`
+	Reason string `json:"reason"` // "expired", "subscription_expired" or "room_full"
+	Phase Phase `json:"phase"`
+}
+
+// WaitingForRoomMessage tells a spectator that the room does not exist yet and
+// that it will be joined automatically if created before ExpiresAt. Only
+// reserved room IDs, e.g. the next game of a tournament match, can be waited
+// for; other unknown rooms answer room_not_found.
+type WaitingForRoomMessage struct {
+	Type string `json:"type"`
+	RoomId string `json:"room_id"`
+	ExpiresAt int64 `json:"expires_at"` // Unix seconds
+}
+
+// KeyRotatedMessage gives the referee the new key of a seat after rotate_key
+type KeyRotatedMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	Seat string `json:"seat"`
+	Key string `json:"key"`
+}
+
+// InviteMessage carries a signed invite token issued with issue_invite
+type InviteMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	RoomId string `json:"room_id"`
+	Seat string `json:"seat"`
+	Token string `json:"token"`
+	ExpiresAt int64 `json:"expires_at"` // Unix seconds
+}
+
+// SeatRevokedMessage tells a client that its key was rotated and that it is
+// now a spectator until it joins again with the new key
+type SeatRevokedMessage struct {
+	Type string `json:"type"`
+	RoomId string `json:"room_id"`
+	Seat string `json:"seat"`
+}
+
+// PresenceEntry is a member of a room in the presence list
+type PresenceEntry struct {
+	Nickname string `json:"nickname,omitempty"`
+	Team string `json:"team,omitempty"` // "blue" or "red"
+	Role string `json:"role"` // "captain", "player", "coach" or "referee"
+}
+
+// PresenceMessage lists who is in a room; it is broadcast when someone joins,
+// leaves or changes seat
+type PresenceMessage struct {
+	Type string `json:"type"`
+	RoomId string `json:"room_id"`
+	Members []PresenceEntry `json:"members"`
+	Spectators int `json:"spectators"`
+}
+
+// TeamHoverMessage shares with a team the champion hovered by one of its
+// players or its coach; it is never sent to the rival team or spectators
+type TeamHoverMessage struct {
+	Type string `json:"type"`
+	Team string `json:"team"`
+	Nickname string `json:"nickname,omitempty"`
+	Role string `json:"role"`
+	Champion string `json:"champion"`
+	Phase Phase `json:"phase"`
+}
+
+// TeamChatMessage sends a chat line to the sender's team only
+type TeamChatMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	Text string `json:"text"`
+}
+
+// SuggestMessage proposes a champion to the sender's team only
+type SuggestMessage struct {
+	Type string `json:"type"`
+	RequestId string `json:"request_id,omitempty"`
+	Champion string `json:"champion"`
+	Text string `json:"text,omitempty"` // Optional note, e.g. "counters their mid"
+}
+
+// TeamChatPostedMessage delivers a TeamChatMessage to the members of the team
+type TeamChatPostedMessage struct {
+	Type string `json:"type"`
+	Team string `json:"team"`
+	Nickname string `json:"nickname,omitempty"`
+	Role string `json:"role"`
+	Text string `json:"text"`
+	At int64 `json:"at"` // Unix milliseconds
+}
+
+// SuggestionPostedMessage delivers a SuggestMessage to the members of the team
+type SuggestionPostedMessage struct {
+	Type string `json:"type"`
+	Team string `json:"team"`
+	Nickname string `json:"nickname,omitempty"`
+	Role string `json:"role"`
+	Champion string `json:"champion"`
+	Text string `json:"text,omitempty"`
+	Phase Phase `json:"phase"`
+	At int64 `json:"at"` // Unix milliseconds
+}
+
+// SideSelectedMessage announces the side chosen during side selection. When
+// Swapped is true every client changes team color: the team that joined as
+// blue now plays red and the other way round.
+type SideSelectedMessage struct {
+	Type string `json:"type"`
+	Chooser string `json:"chooser"` // As entered when creating the room
+	Side string `json:"side"`
+	Swapped bool `json:"swapped"`
+}
@@ -3,9 +3,11 @@ package models
 type Phase string
 
 const (
+	SideSelection Phase = "SideSelection" // Optional, the designated team picks blue or red
     NoReady       Phase = "NoReady"
 	BlueReady     Phase = "BlueReady"
 	RedReady      Phase = "RedReady"
+	Countdown     Phase = "Countdown" // Both teams ready, the draft starts when the timer ends
     BanBlue1       Phase = "BanBlue1"
     BanRed1      Phase = "BanRed1"
     BanBlue2       Phase = "BanBlue2"
@@ -22,5 +24,73 @@ const (
     BanBlue5        Phase = "BanBlue5"
     PickBlue3       Phase = "PickBlue3"
     PickRed3       Phase = "PickRed3"
+	Trading       Phase = "Trading" // Optional, teams reorder and assign their picks
 	Finished       Phase = "Finished"
 )
+
+// PickPhases lists the pick phases in draft order, with or without bans
+var PickPhases = []Phase{PickBlue1, PickRed1, PickRed2, PickBlue2, PickBlue3, PickRed3}
+
+// PickTurn returns the order of a pick phase in the draft across both teams,
+// from 1 to 6, or 0 if phase is not a pick phase
+func PickTurn(phase Phase) int {
+	for i, p := range PickPhases {
+		if p == phase {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// DraftSequence lists the turns of a draft in order, with the ban phases if
+// either team has bans
+func DraftSequence(bans bool) []Phase {
+	if !bans {
+		return PickPhases
+	}
+	return []Phase{
+		BanBlue1, BanRed1, BanBlue2, BanRed2, BanBlue3, BanRed3,
+		PickBlue1, PickRed1, PickRed2, PickBlue2,
+		BanRed4, BanBlue4, BanRed5, BanBlue5,
+		PickBlue3, PickRed3,
+	}
+}
+
+// NextPhase returns the phase that follows phase: the side choice leads to
+// the lobby, the countdown to the first turn of DraftSequence, each turn to
+// the next one and the last turn to the trade phase, if the room has one, or
+// to Finished, as does the trade phase. The lobby phases, whose successor
+// depends on who is ready, and Finished return false.
+func NextPhase(phase Phase, bans, trading bool) (Phase, bool) {
+	sequence := DraftSequence(bans)
+	switch phase {
+	case SideSelection:
+		return NoReady, true
+	case Countdown:
+		return sequence[0], true
+	case Trading:
+		return Finished, true
+	}
+	for i, turn := range sequence {
+		if turn != phase {
+			continue
+		}
+		if i+1 < len(sequence) {
+			return sequence[i+1], true
+		}
+		if trading {
+			return Trading, true
+		}
+		return Finished, true
+	}
+	return phase, false
+}
+
+// AllPhases lists every phase, in draft order
+var AllPhases = []Phase{
+	SideSelection, NoReady, BlueReady, RedReady, Countdown,
+	BanBlue1, BanRed1, BanBlue2, BanRed2, BanBlue3, BanRed3,
+	PickBlue1, PickRed1, PickRed2, PickBlue2,
+	BanRed4, BanBlue4, BanRed5, BanBlue5,
+	PickBlue3, PickRed3, Trading, Finished,
+}
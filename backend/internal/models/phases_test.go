@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestNextPhase(t *testing.T) {
+	tests := []struct {
+		name    string
+		phase   Phase
+		bans    bool
+		trading bool
+		want    Phase
+		ok      bool
+	}{
+		{"side choice to lobby", SideSelection, true, false, NoReady, true},
+		{"countdown to first ban", Countdown, true, false, BanBlue1, true},
+		{"countdown to first pick without bans", Countdown, false, false, PickBlue1, true},
+		{"first bans to picks", BanRed3, true, false, PickBlue1, true},
+		{"picks to second bans", PickBlue2, true, false, BanRed4, true},
+		{"no second bans without bans", PickBlue2, false, false, PickBlue3, true},
+		{"last pick to finished", PickRed3, true, false, Finished, true},
+		{"last pick to trading", PickRed3, false, true, Trading, true},
+		{"trading to finished", Trading, false, true, Finished, true},
+		{"lobby has no fixed successor", BlueReady, true, false, BlueReady, false},
+		{"finished is final", Finished, true, true, Finished, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NextPhase(tt.phase, tt.bans, tt.trading)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("NextPhase(%s, %v, %v) = %s, %v; want %s, %v", tt.phase, tt.bans, tt.trading, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	// Every turn of a draft is reached exactly once from the countdown
+	for _, bans := range []bool{false, true} {
+		phase, steps := Countdown, 0
+		for phase != Finished {
+			next, ok := NextPhase(phase, bans, false)
+			if !ok {
+				t.Fatalf("NextPhase(%s, %v) stopped before Finished", phase, bans)
+			}
+			phase = next
+			steps++
+		}
+		if want := len(DraftSequence(bans)) + 1; steps != want {
+			t.Errorf("bans=%v: %d steps from the countdown to Finished, want %d", bans, steps, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+// ProtocolVersion is the newest WebSocket protocol version spoken by the server
+const ProtocolVersion = 1
+
+// SupportedProtocolVersions lists every protocol version the server accepts,
+// oldest first. Clients that skip the hello handshake are treated as version 1.
+var SupportedProtocolVersions = []int{1}
+
+// NegotiateProtocolVersion returns the newest version supported by both the
+// server and the client, or false if they have none in common
+func NegotiateProtocolVersion(clientVersions []int) (int, bool) {
+	best := 0
+	for _, v := range clientVersions {
+		for _, supported := range SupportedProtocolVersions {
+			if v == supported && v > best {
+				best = v
+			}
+		}
+	}
+	return best, best != 0
+}
+
+// InboundMessages maps every message type a client may send to its model
+var InboundMessages = map[string]interface{}{
+	"hello":            HelloMessage{},
+	"create":           CreateMessage{},
+	"join":             JoinMessage{},
+	"action":           ActionMessage{},
+	"team_chat":        TeamChatMessage{},
+	"suggest":          SuggestMessage{},
+	"watch_tournament": WatchTournamentMessage{},
+}
+
+// OutboundMessages maps every message type the server may send to its model
+var OutboundMessages = map[string]interface{}{
+	"welcome":          WelcomeMessage{},
+	"create_response":  CreateResponseMessage{},
+	"status":           StatusMessage{},
+	"user_joined":      UserJoinedMessage{},
+	"room_closed":      RoomClosedMessage{},
+	"waiting_for_room": WaitingForRoomMessage{},
+	"key_rotated":      KeyRotatedMessage{},
+	"invite":           InviteMessage{},
+	"seat_revoked":     SeatRevokedMessage{},
+	"presence":         PresenceMessage{},
+	"team_hover":       TeamHoverMessage{},
+	"team_chat":        TeamChatPostedMessage{},
+	"suggestion":       SuggestionPostedMessage{},
+	"side_selected":    SideSelectedMessage{},
+	"standings":        StandingsMessage{},
+	"ack":              AckMessage{},
+	"error":            ErrorMessage{},
+}
@@ -2,40 +2,150 @@ package models
 
 import (
 	"sync"
+	"sync/atomic"
 	"github.com/gorilla/websocket"
 )
 
 type Champion struct {
 	Name string `json:"name"`
-	LockedAt int `json:"locked_at,omitempty"`
+	LockedAt int64 `json:"locked_at,omitempty"` // Unix milliseconds the turn of the slot ended
+	TimeUsed int `json:"time_used,omitempty"` // Milliseconds of the turn used before the lock
+	TimedOut bool `json:"timed_out,omitempty"` // Locked by the timer: the hovered champion, or none
+	Turn int `json:"turn,omitempty"` // Order of a pick in the draft across both teams, from 1 to 6; kept when picks are traded
+	Player string `json:"player,omitempty"` // Name of the player assigned to a pick during the trade phase
+	PlayerId string `json:"player_id,omitempty"` // Roster ID of that player, if the team has a roster
+}
+
+// Player is a member of a team roster
+type Player struct {
+	Id string `json:"id,omitempty"` // Stable ID, e.g. from tournament data
+	Name string `json:"name"`
 }
 
 type Client struct {
 	Conn *websocket.Conn `json:"-"`
 	Team string `json:"team"` // "blue", "red", or "" for spectator
+	Referee bool `json:"referee,omitempty"` // Joined with the referee key
+	Role string `json:"role,omitempty"` // Role inside the team, "" for spectators and the referee
+	Nickname string `json:"nickname,omitempty"`
 }
 
+// Roles of the members of a team. Only the captain may ready up and lock
+// champions; players and coaches hover suggestions seen by their team only.
+const (
+	RoleCaptain = "captain"
+	RolePlayer = "player"
+	RoleCoach = "coach"
+)
+
+// Seats that a key or an invite can grant
+const (
+	SeatBlue = "blue"
+	SeatRed = "red"
+	SeatReferee = "referee"
+)
+
 type Team struct {
 	Name string `json:"name"`
+	Roster []Player `json:"roster,omitempty"`
 	Bans []Champion `json:"bans"`
 	Picks []Champion `json:"picks"`
 }
 
+// MaxHistoryEntries caps the history kept per room
+const MaxHistoryEntries = 2000
+
+// Kinds of HistoryEntry
+const (
+	HistoryTeamChat = "team_chat"
+	HistorySuggestion = "suggestion"
+	HistoryCoinFlip = "coin_flip"
+	HistorySideSelected = "side_selected"
+	HistoryResultReported = "result_reported"
+)
+
+// CoinFlip decides which team picks side when no seed is given. The server
+// publishes Commitment, the SHA-256 of the random seed, when the room is
+// created and reveals Seed once both captains are connected. Winner is blue
+// if the first byte of SHA-256(seed || room ID) is even, red otherwise, so
+// anyone can check that the seed was fixed before the flip.
+type CoinFlip struct {
+	Commitment string `json:"commitment"` // Hex
+	Seed string `json:"seed,omitempty"` // Hex, empty until revealed
+	Winner string `json:"winner,omitempty"` // "blue" or "red", as entered when creating the room
+}
+
+// GameResult is the outcome of the game played with a finished draft. It is
+// also used for the reports of each captain until both agree.
+type GameResult struct {
+	Winner string `json:"winner"` // "blue" or "red", the sides of the draft
+	SubmittedBy string `json:"submitted_by"` // "referee", "captains", "admin" or "import"; the seat of a captain's report
+	Duration int `json:"duration,omitempty"` // Seconds of game time
+	Notes string `json:"notes,omitempty"`
+	At int64 `json:"at"` // Unix milliseconds
+}
+
+// HistoryEntry is an event of the room kept for post-match review. Team chat
+// and suggestions are private to their team and are never broadcast from here.
+type HistoryEntry struct {
+	At int64 `json:"at"` // Unix milliseconds
+	Kind string `json:"kind"`
+	Team string `json:"team,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+	Role string `json:"role,omitempty"`
+	Side string `json:"side,omitempty"`
+	Text string `json:"text,omitempty"`
+	Champion string `json:"champion,omitempty"`
+	Phase Phase `json:"phase,omitempty"`
+}
+
+// DraftTimes are the milestones of a room in Unix milliseconds, 0 until they
+// happen. A team that unreadies loses its ready time.
+type DraftTimes struct {
+	CreatedAt int64 `json:"created_at"`
+	BlueReadyAt int64 `json:"blue_ready_at,omitempty"`
+	RedReadyAt int64 `json:"red_ready_at,omitempty"`
+	StartedAt int64 `json:"started_at,omitempty"` // First turn of the draft
+	FinishedAt int64 `json:"finished_at,omitempty"` // End of the draft, after the trade phase if any
+}
+
 type Room struct {
 	Id string `json:"id"`
-	RedTeamKey string `json:"red_team_key"`
-	BlueTeamKey string `json:"blue_team_key"`
+	RedTeamKeyHash string `json:"-"` // SHA-256 of the red key, the key itself is never stored
+	BlueTeamKeyHash string `json:"-"`
+	RefereeKeyHash string `json:"-"`
+	SeatGenerations map[string]int `json:"-"` // Bumped on key rotation to revoke older invites
 	BlueTeamName string `json:"blue_team_name"`
 	RedTeamName string `json:"red_team_name"`
 	BlueTeamHasBans bool `json:"blue_team_has_bans"`
 	RedTeamHasBans bool `json:"red_team_has_bans"`
 	TimePerPick int `json:"time_per_pick"`
 	TimePerBan int `json:"time_per_ban"`
+	TradePhase bool `json:"trade_phase,omitempty"` // Trading phase between the last pick and Finished
+	TradeTime int `json:"trade_time,omitempty"` // Seconds
+	TradeConfirmed map[string]bool `json:"-"` // Teams done trading, protected by ClientsMutex
 	CurrentPhase Phase `json:"current_phase"`
+	SideChooser string `json:"side_chooser,omitempty"` // Team that picks side, as entered when creating the room
+	SidesSwapped bool `json:"sides_swapped,omitempty"` // The teams swapped sides during side selection
+	CoinFlip *CoinFlip `json:"coin_flip,omitempty"`
+	CoinFlipSeed string `json:"-"` // Hex, kept secret until the flip is revealed
 	BlueTeam Team `json:"blue_team"`
 	RedTeam Team `json:"red_team"`
 	FearlessBans []Champion `json:"fearless_bans"`
+	ChampionPool []string `json:"champion_pool,omitempty"` // Champions allowed in the draft, all if empty
+	Patch string `json:"patch,omitempty"` // Game patch the draft is played on, e.g. "14.3"
+	Match *MatchRef `json:"match,omitempty"` // Tournament match drafted in the room
+	Result *GameResult `json:"result,omitempty"` // Set after the draft finishes, protected by TimerMutex
+	ResultReports map[string]*GameResult `json:"-"` // Captain reports by seat until they agree, protected by TimerMutex
+	ResultDisputed bool `json:"result_disputed,omitempty"` // The captains reported different winners
+	Imported bool `json:"imported,omitempty"` // Entered after the game instead of drafted in the room
+	Times DraftTimes `json:"times"`
+	TurnStartedAt int64 `json:"-"` // Unix milliseconds the current ban or pick turn started, protected by TimerMutex
 	Clients map[*websocket.Conn]*Client `json:"-"` // Connected clients
+	ClientsMutex sync.Mutex `json:"-"` // Protects Clients and serializes broadcasts
+	LastActivity atomic.Int64 `json:"-"` // Unix nanoseconds of the last join, leave, action or phase change
+	Expired bool `json:"expired,omitempty"` // Evicted by the janitor before finishing
+	History []HistoryEntry `json:"history,omitempty"` // Event log for post-match review, protected by ClientsMutex
 	
 	// Timer fields
 	TimeRemaining int `json:"time_remaining"` // Tiempo restante en segundos
@@ -0,0 +1,394 @@
+package models
+
+// Limits of the tournament registry
+const (
+	MaxTournamentNameLength = 64
+	MaxTeamTagLength        = 8
+	MaxLogoLength           = 512
+	MaxChampionPool         = 300
+	MaxBestOf               = 7
+	MaxBracketTeams         = 64
+	// Match IDs leave room for the "-g<game>-<attempt>" suffix of their rooms
+	MaxMatchIdLength = MaxRoomIdLength - 8
+	// Bracket IDs leave room for the "-u<round>-<match>" suffix of their matches
+	MaxBracketIdLength = MaxMatchIdLength - 10
+)
+
+// Bracket formats
+const (
+	BracketSingleElimination = "single_elimination"
+	BracketDoubleElimination = "double_elimination"
+	BracketRoundRobin        = "round_robin"
+)
+
+// Stages of the matches of a bracket
+const (
+	StageUpper      = "upper" // Also every round of a single elimination bracket
+	StageLower      = "lower"
+	StageGrandFinal = "grand_final"
+	StageGroup      = "group" // Round robin
+)
+
+// Statuses of a Match
+const (
+	MatchScheduled  = "scheduled"
+	MatchInProgress = "in_progress"
+	MatchFinished   = "finished"
+)
+
+// Statuses of a MatchGame
+const (
+	GameDrafting  = "drafting"
+	GameFinished  = "finished"
+	GameAbandoned = "abandoned" // The room expired before the draft finished
+)
+
+// Tournament groups the registered teams and scheduled matches of a
+// competition, with the draft settings shared by all of its games
+type Tournament struct {
+	Id           string           `json:"id"`
+	Name         string           `json:"name"`
+	Draft        DraftSettings    `json:"draft"`
+	ChampionPool []string         `json:"champion_pool,omitempty"` // Champions allowed in its drafts, all if empty
+	Teams        []TournamentTeam `json:"teams"`
+	Brackets     []Bracket        `json:"brackets,omitempty"`
+	Matches      []Match          `json:"matches"`
+	CreatedAt    int64            `json:"created_at"` // Unix seconds
+}
+
+// DraftSettings are the room settings of the games of a tournament
+type DraftSettings struct {
+	TimePerPick int    `json:"time_per_pick"`
+	TimePerBan  int    `json:"time_per_ban"`
+	Bans        bool   `json:"bans"`
+	TradePhase  bool   `json:"trade_phase,omitempty"`
+	TradeTime   int    `json:"trade_time,omitempty"`
+	Patch       string `json:"patch,omitempty"` // Game patch of its drafts, the server default if empty
+}
+
+// TournamentTeam is a team registered in a tournament
+type TournamentTeam struct {
+	Id     string   `json:"id"`
+	Name   string   `json:"name"`
+	Tag    string   `json:"tag,omitempty"`  // Short name for overlays, e.g. "T1"
+	Logo   string   `json:"logo,omitempty"` // Image URL
+	Roster []Player `json:"roster,omitempty"`
+}
+
+// Bracket generates the matches of a stage of a tournament from a seeded list
+// of teams. Winners, and in double elimination losers of the upper bracket,
+// move on to the match and side in WinnerTo and LoserTo of their match.
+type Bracket struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Format    string   `json:"format"`
+	TeamIds   []string `json:"team_ids"` // By seed, first is the top seed
+	BestOf    int      `json:"best_of"`
+	Fearless  bool     `json:"fearless,omitempty"`
+	CreatedAt int64    `json:"created_at"` // Unix seconds
+}
+
+// BracketSlot is the side of a bracket match that a team moves on to
+type BracketSlot struct {
+	MatchId string `json:"match_id"`
+	Side    string `json:"side"` // "blue" or "red"
+}
+
+// Match is a scheduled series between two registered teams. Each game is
+// drafted in its own room; fearless matches carry the picks of the finished
+// games over as fearless bans. Bracket matches may wait for their teams,
+// which are empty until the matches before them finish.
+type Match struct {
+	Id            string       `json:"id"`
+	BlueTeamId    string       `json:"blue_team_id"` // Blue side of the first game
+	RedTeamId     string       `json:"red_team_id"`
+	Winner        string       `json:"winner,omitempty"` // Team ID, once it has won most of the games
+	BracketId     string       `json:"bracket_id,omitempty"`
+	Stage         string       `json:"stage,omitempty"`
+	Round         int          `json:"round,omitempty"`
+	WinnerTo      *BracketSlot `json:"winner_to,omitempty"`
+	LoserTo       *BracketSlot `json:"loser_to,omitempty"`
+	BestOf        int          `json:"best_of"`
+	Fearless      bool         `json:"fearless,omitempty"`
+	SideSelection bool         `json:"side_selection,omitempty"` // Games start with a side selection phase
+	ScheduledAt   int64        `json:"scheduled_at,omitempty"`   // Unix seconds
+	Status        string       `json:"status"`
+	Games         []MatchGame  `json:"games"`
+}
+
+// MatchGame is a game of a match and the room where it is drafted
+type MatchGame struct {
+	Number     int      `json:"number"`
+	RoomId     string   `json:"room_id"`
+	BlueTeamId string   `json:"blue_team_id"`
+	RedTeamId  string   `json:"red_team_id"`
+	Status     string   `json:"status"`
+	Winner     string   `json:"winner,omitempty"` // Team ID, submitted by the referee
+	Picks      []string `json:"picks,omitempty"`  // Champions picked by both teams once finished
+	CreatedAt  int64    `json:"created_at"`       // Unix seconds
+	FinishedAt int64    `json:"finished_at,omitempty"`
+}
+
+// MatchRef links a draft room to the tournament match it belongs to
+type MatchRef struct {
+	TournamentId string `json:"tournament_id"`
+	MatchId      string `json:"match_id"`
+	Game         int    `json:"game"`
+}
+
+// Standing is the record of a team in a bracket or tournament
+type Standing struct {
+	Rank       int    `json:"rank"` // Tied teams share a rank
+	TeamId     string `json:"team_id"`
+	Name       string `json:"name"`
+	Tag        string `json:"tag,omitempty"`
+	Played     int    `json:"played"` // Decided matches
+	Wins       int    `json:"wins"`
+	Losses     int    `json:"losses"`
+	GameWins   int    `json:"game_wins"`
+	GameLosses int    `json:"game_losses"`
+	Eliminated bool   `json:"eliminated,omitempty"`
+}
+
+// BracketStandings ranks the teams of a bracket, or of every match of the
+// tournament when BracketId is empty
+type BracketStandings struct {
+	BracketId string     `json:"bracket_id,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Format    string     `json:"format,omitempty"`
+	Standings []Standing `json:"standings"`
+}
+
+// StandingsMessage carries the standings of a tournament, over HTTP and to
+// the clients watching it with WatchTournamentMessage
+type StandingsMessage struct {
+	Type         string             `json:"type"`
+	TournamentId string             `json:"tournament_id"`
+	Overall      BracketStandings   `json:"overall"`
+	Brackets     []BracketStandings `json:"brackets"`
+	Matches      []Match            `json:"matches"`
+}
+
+// WatchTournamentMessage subscribes a connection to the standings of a
+// tournament, e.g. for stream graphics. The connection leaves its room.
+type WatchTournamentMessage struct {
+	Type         string `json:"type"`
+	RequestId    string `json:"request_id,omitempty"`
+	TournamentId string `json:"tournament_id"`
+}
+
+// CreateTournamentRequest asks the admin HTTP API for a new tournament
+type CreateTournamentRequest struct {
+	Id           string        `json:"id,omitempty"` // Generated if empty
+	Name         string        `json:"name"`
+	Draft        DraftSettings `json:"draft"`
+	ChampionPool []string      `json:"champion_pool,omitempty"`
+}
+
+// RegisterTeamRequest asks the admin HTTP API to register a team in a tournament
+type RegisterTeamRequest struct {
+	Id     string   `json:"id,omitempty"` // Generated if empty
+	Name   string   `json:"name"`
+	Tag    string   `json:"tag,omitempty"`
+	Logo   string   `json:"logo,omitempty"`
+	Roster []Player `json:"roster,omitempty"`
+}
+
+// ScheduleMatchRequest asks the admin HTTP API to schedule a match between
+// two registered teams
+type ScheduleMatchRequest struct {
+	Id            string `json:"id,omitempty"` // Generated if empty, unique across tournaments
+	BlueTeamId    string `json:"blue_team_id"`
+	RedTeamId     string `json:"red_team_id"`
+	BestOf        int    `json:"best_of,omitempty"` // Defaults to 1
+	Fearless      bool   `json:"fearless,omitempty"`
+	SideSelection bool   `json:"side_selection,omitempty"`
+	ScheduledAt   int64  `json:"scheduled_at,omitempty"`
+}
+
+// CreateBracketRequest asks the admin HTTP API to generate a bracket of
+// registered teams
+type CreateBracketRequest struct {
+	Id       string   `json:"id,omitempty"` // Generated if empty
+	Name     string   `json:"name"`
+	Format   string   `json:"format"`
+	TeamIds  []string `json:"team_ids"`          // By seed, first is the top seed
+	BestOf   int      `json:"best_of,omitempty"` // Defaults to 1
+	Fearless bool     `json:"fearless,omitempty"`
+}
+
+// BracketCreatedResponse answers a CreateBracketRequest with the generated
+// matches
+type BracketCreatedResponse struct {
+	Bracket Bracket `json:"bracket"`
+	Matches []Match `json:"matches"`
+}
+
+// StartGameRequest asks the admin HTTP API to create the room of the next
+// game of a match
+type StartGameRequest struct {
+	BlueTeamId string `json:"blue_team_id,omitempty"` // Team on blue side, the blue team of the match if empty
+}
+
+// SubmitResultRequest lets the admin HTTP API record the winner of a finished
+// game in place of the referee, e.g. when the captains disagree and nobody
+// holds the referee key
+type SubmitResultRequest struct {
+	Winner   string `json:"winner"`             // "blue" or "red", the sides of the draft
+	Duration int    `json:"duration,omitempty"` // Seconds of game time
+	Notes    string `json:"notes,omitempty"`
+}
+
+// GameCreatedResponse answers a StartGameRequest with the game and the keys
+// of its room
+type GameCreatedResponse struct {
+	Match MatchRef              `json:"match"`
+	Game  MatchGame             `json:"game"`
+	Room  CreateResponseMessage `json:"room"`
+}
+
+// Validate checks the name, draft settings and champion pool of a tournament
+func (m CreateTournamentRequest) Validate() error {
+	if err := checkRoomId("id", m.Id); err != nil {
+		return err
+	}
+	if err := checkRequired("name", m.Name, MaxTournamentNameLength); err != nil {
+		return err
+	}
+	if err := m.Draft.validate("draft"); err != nil {
+		return err
+	}
+	return checkChampionList("champion_pool", m.ChampionPool, MaxChampionPool)
+}
+
+// Validate checks the winner, duration and notes of a result
+func (m SubmitResultRequest) Validate() error {
+	if err := checkSeat("winner", m.Winner, SeatBlue, SeatRed); err != nil {
+		return err
+	}
+	if err := checkRange("duration", m.Duration, 0, MaxGameDuration); err != nil {
+		return err
+	}
+	return checkLength("notes", m.Notes, MaxResultNotesLength)
+}
+
+// validate checks the timing of the draft settings
+func (d DraftSettings) validate(field string) error {
+	if err := checkRange(field+".time_per_pick", d.TimePerPick, MinTimePerAction, MaxTimePerAction); err != nil {
+		return err
+	}
+	if err := checkRange(field+".time_per_ban", d.TimePerBan, MinTimePerAction, MaxTimePerAction); err != nil {
+		return err
+	}
+	if err := checkLength(field+".patch", d.Patch, MaxPatchLength); err != nil {
+		return err
+	}
+	if d.TradeTime != 0 {
+		if !d.TradePhase {
+			return validationError(field+".trade_time", "requires trade_phase")
+		}
+		return checkRange(field+".trade_time", d.TradeTime, MinTimePerAction, MaxTimePerAction)
+	}
+	return nil
+}
+
+// Validate checks the name, tag, logo and roster of a team
+func (m RegisterTeamRequest) Validate() error {
+	if err := checkRoomId("id", m.Id); err != nil {
+		return err
+	}
+	if err := checkRequired("name", m.Name, MaxTeamNameLength); err != nil {
+		return err
+	}
+	if err := checkLength("tag", m.Tag, MaxTeamTagLength); err != nil {
+		return err
+	}
+	if err := checkLength("logo", m.Logo, MaxLogoLength); err != nil {
+		return err
+	}
+	return checkRoster("roster", m.Roster)
+}
+
+// Validate checks the teams and format of a match
+func (m ScheduleMatchRequest) Validate() error {
+	if err := checkRoomId("id", m.Id); err != nil {
+		return err
+	}
+	if err := checkLength("id", m.Id, MaxMatchIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("blue_team_id", m.BlueTeamId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("red_team_id", m.RedTeamId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	if m.BlueTeamId == m.RedTeamId {
+		return validationError("red_team_id", "must be different from blue_team_id")
+	}
+	if err := checkBestOf(m.BestOf); err != nil {
+		return err
+	}
+	if m.ScheduledAt < 0 {
+		return validationError("scheduled_at", "must not be negative")
+	}
+	return nil
+}
+
+// Validate checks the format and teams of a bracket. Double elimination
+// brackets need a power of two of teams, at least four.
+func (m CreateBracketRequest) Validate() error {
+	if err := checkRoomId("id", m.Id); err != nil {
+		return err
+	}
+	if err := checkLength("id", m.Id, MaxBracketIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("name", m.Name, MaxTournamentNameLength); err != nil {
+		return err
+	}
+	if err := checkSeat("format", m.Format, BracketSingleElimination, BracketDoubleElimination, BracketRoundRobin); err != nil {
+		return err
+	}
+	if len(m.TeamIds) < 2 || len(m.TeamIds) > MaxBracketTeams {
+		return validationError("team_ids", "must have between 2 and %d teams", MaxBracketTeams)
+	}
+	if m.Format == BracketDoubleElimination && (len(m.TeamIds) < 4 || len(m.TeamIds)&(len(m.TeamIds)-1) != 0) {
+		return validationError("team_ids", "must have 4, 8, 16, 32 or 64 teams in a double elimination bracket")
+	}
+	seen := make(map[string]bool)
+	for _, teamId := range m.TeamIds {
+		if err := checkRequired("team_ids", teamId, MaxRoomIdLength); err != nil {
+			return err
+		}
+		if seen[teamId] {
+			return validationError("team_ids", "team %s appears twice", teamId)
+		}
+		seen[teamId] = true
+	}
+	return checkBestOf(m.BestOf)
+}
+
+// checkBestOf validates the optional length of a series
+func checkBestOf(bestOf int) error {
+	if bestOf != 0 && (bestOf%2 == 0 || bestOf > MaxBestOf || bestOf < 0) {
+		return validationError("best_of", "must be an odd number up to %d", MaxBestOf)
+	}
+	return nil
+}
+
+// Validate checks the tournament of a standings subscription
+func (m WatchTournamentMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("tournament_id", m.TournamentId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	return checkRoomId("tournament_id", m.TournamentId)
+}
+
+// Validate checks the side override of a game
+func (m StartGameRequest) Validate() error {
+	return checkLength("blue_team_id", m.BlueTeamId, MaxRoomIdLength)
+}
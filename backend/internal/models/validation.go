@@ -0,0 +1,306 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits enforced on inbound messages
+const (
+	MaxRequestIdLength    = 64
+	MaxRoomIdLength       = 64
+	MaxKeyLength          = 128
+	MaxInviteLength       = 512
+	MaxTeamNameLength     = 32
+	MaxChampionNameLength = 32
+	MaxClientNameLength   = 64
+	MaxSessionLength      = 128
+	MaxNicknameLength     = 32
+	MaxChatLength         = 500
+	MaxFearlessBans       = 60
+	MaxProtocolVersions   = 16
+	MinTimePerAction      = 5   // Seconds
+	MaxTimePerAction      = 600 // Seconds
+	MaxPickOrder          = 5
+	MaxRosterSize         = 10
+	MaxPlayerIdLength     = 64
+	MaxGameDuration       = 4 * 60 * 60 // Seconds
+	MaxResultNotesLength  = 500
+	MaxPatchLength        = 16
+)
+
+// Validator is implemented by every inbound message
+type Validator interface {
+	Validate() error
+}
+
+// validationError builds the error returned when a field is invalid
+func validationError(field, format string, args ...interface{}) *ProtocolError {
+	return NewProtocolError(ErrValidation, field+": "+format, args...).
+		WithDetail(DetailField, field)
+}
+
+// checkLength validates the length in characters of an optional string field
+func checkLength(field, value string, max int) error {
+	if !utf8.ValidString(value) {
+		return validationError(field, "must be valid UTF-8")
+	}
+	if utf8.RuneCountInString(value) > max {
+		return validationError(field, "must be at most %d characters", max)
+	}
+	return nil
+}
+
+// checkRequired validates a string field that must not be blank
+func checkRequired(field, value string, max int) error {
+	if strings.TrimSpace(value) == "" {
+		return validationError(field, "is required")
+	}
+	return checkLength(field, value, max)
+}
+
+// checkRoomId validates a room ID, which ends up in URLs and document paths
+func checkRoomId(field, value string) error {
+	if err := checkLength(field, value, MaxRoomIdLength); err != nil {
+		return err
+	}
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return validationError(field, "may only contain letters, digits, '-' and '_'")
+		}
+	}
+	return nil
+}
+
+// checkRange validates an integer field against an inclusive range
+func checkRange(field string, value, min, max int) error {
+	if value < min || value > max {
+		return validationError(field, "must be between %d and %d", min, max)
+	}
+	return nil
+}
+
+// Validate checks the hello handshake
+func (m HelloMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	if len(m.Versions) == 0 {
+		return validationError("versions", "is required")
+	}
+	if len(m.Versions) > MaxProtocolVersions {
+		return validationError("versions", "must have at most %d entries", MaxProtocolVersions)
+	}
+	if err := checkLength("session", m.Session, MaxSessionLength); err != nil {
+		return err
+	}
+	return checkLength("client", m.Client, MaxClientNameLength)
+}
+
+// Validate checks the room configuration
+func (m CreateMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("blue_team_name", m.BlueTeamName, MaxTeamNameLength); err != nil {
+		return err
+	}
+	if err := checkRequired("red_team_name", m.RedTeamName, MaxTeamNameLength); err != nil {
+		return err
+	}
+	if err := checkRange("time_per_pick", m.TimePerPick, MinTimePerAction, MaxTimePerAction); err != nil {
+		return err
+	}
+	if err := checkRange("time_per_ban", m.TimePerBan, MinTimePerAction, MaxTimePerAction); err != nil {
+		return err
+	}
+	if err := checkRoomId("room_id", m.RoomId); err != nil {
+		return err
+	}
+	if err := checkLength("patch", m.Patch, MaxPatchLength); err != nil {
+		return err
+	}
+	if err := checkRoster("blue_roster", m.BlueRoster); err != nil {
+		return err
+	}
+	if err := checkRoster("red_roster", m.RedRoster); err != nil {
+		return err
+	}
+	if m.TradeTime != 0 {
+		if !m.TradePhase {
+			return validationError("trade_time", "requires trade_phase")
+		}
+		if err := checkRange("trade_time", m.TradeTime, MinTimePerAction, MaxTimePerAction); err != nil {
+			return err
+		}
+	}
+	if m.SideChooser != "" {
+		if !m.SideSelection {
+			return validationError("side_chooser", "requires side_selection")
+		}
+		if err := checkSeat("side_chooser", m.SideChooser, SeatBlue, SeatRed); err != nil {
+			return err
+		}
+	}
+	if err := checkChampionList("fearless_bans", m.FearlessBans, MaxFearlessBans); err != nil {
+		return err
+	}
+	return checkChampionList("champion_pool", m.ChampionPool, MaxChampionPool)
+}
+
+// checkChampionList validates a list of champion names
+func checkChampionList(field string, champions []string, max int) error {
+	if len(champions) > max {
+		return validationError(field, "must have at most %d entries", max)
+	}
+	for _, champion := range champions {
+		if err := checkRequired(field, champion, MaxChampionNameLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks the room, credentials and role of a join request
+func (m JoinMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("room_id", m.RoomId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	if err := checkRoomId("room_id", m.RoomId); err != nil {
+		return err
+	}
+	if err := checkLength("key", m.Key, MaxKeyLength); err != nil {
+		return err
+	}
+	if m.Key != "" && m.Invite != "" {
+		return validationError("invite", "cannot be combined with key")
+	}
+	if err := checkLength("invite", m.Invite, MaxInviteLength); err != nil {
+		return err
+	}
+	if m.Role != "" {
+		if m.Key == "" && m.Invite == "" {
+			return validationError("role", "requires a key or an invite")
+		}
+		if err := checkSeat("role", m.Role, RoleCaptain, RolePlayer, RoleCoach); err != nil {
+			return err
+		}
+	}
+	return checkLength("nickname", m.Nickname, MaxNicknameLength)
+}
+
+// checkRoster validates a team roster: names are required and names and IDs
+// must be unique, so picks can be assigned by either
+func checkRoster(field string, roster []Player) error {
+	if len(roster) > MaxRosterSize {
+		return validationError(field, "must have at most %d players", MaxRosterSize)
+	}
+	// trade_assign names a player by ID or name, so both share a namespace:
+	// one player's ID cannot be another player's name
+	owners := make(map[string]int)
+	for i, player := range roster {
+		if err := checkRequired(field+".name", player.Name, MaxNicknameLength); err != nil {
+			return err
+		}
+		if err := checkLength(field+".id", player.Id, MaxPlayerIdLength); err != nil {
+			return err
+		}
+		for _, key := range []string{player.Name, player.Id} {
+			if key == "" {
+				continue
+			}
+			if owner, taken := owners[key]; taken && owner != i {
+				return validationError(field, "players must have unique names and IDs, and no ID may be the name of another player: %q", key)
+			}
+			owners[key] = i
+		}
+	}
+	return nil
+}
+
+// checkSeat validates a seat or role against the allowed values
+func checkSeat(field, value string, allowed ...string) error {
+	for _, seat := range allowed {
+		if value == seat {
+			return nil
+		}
+	}
+	return validationError(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// Validate checks the action name and the fields it requires
+func (m ActionMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	switch m.Action {
+	case "ready", "unready", "force_start":
+	case "champ_select", "champ_pick":
+		if err := checkRequired("champion", m.Champion, MaxChampionNameLength); err != nil {
+			return err
+		}
+	case "trade_reorder":
+		if len(m.Order) == 0 || len(m.Order) > MaxPickOrder {
+			return validationError("order", "must have between 1 and %d entries", MaxPickOrder)
+		}
+	case "trade_assign":
+		if err := checkRequired("champion", m.Champion, MaxChampionNameLength); err != nil {
+			return err
+		}
+		if err := checkRequired("player", m.Player, MaxNicknameLength); err != nil {
+			return err
+		}
+	case "trade_confirm":
+	case "choose_side":
+		if err := checkSeat("side", m.Side, SeatBlue, SeatRed); err != nil {
+			return err
+		}
+	case "report_result":
+		if err := checkSeat("side", m.Side, SeatBlue, SeatRed); err != nil {
+			return err
+		}
+		if err := checkRange("duration", m.Duration, 0, MaxGameDuration); err != nil {
+			return err
+		}
+		if err := checkLength("notes", m.Notes, MaxResultNotesLength); err != nil {
+			return err
+		}
+	case "rotate_key":
+		if err := checkSeat("seat", m.Seat, SeatBlue, SeatRed, SeatReferee); err != nil {
+			return err
+		}
+	case "issue_invite":
+		if err := checkSeat("seat", m.Seat, SeatBlue, SeatRed, SeatReferee); err != nil {
+			return err
+		}
+		if m.ExpiresIn < 0 {
+			return validationError("expires_in", "must not be negative")
+		}
+	default:
+		return NewProtocolError(ErrUnknownAction, "unknown action type: %s", m.Action).
+			WithDetail(DetailAction, m.Action)
+	}
+	return checkLength("champion", m.Champion, MaxChampionNameLength)
+}
+
+// Validate checks the text of a team chat line
+func (m TeamChatMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	return checkRequired("text", m.Text, MaxChatLength)
+}
+
+// Validate checks the champion and note of a suggestion
+func (m SuggestMessage) Validate() error {
+	if err := checkLength("request_id", m.RequestId, MaxRequestIdLength); err != nil {
+		return err
+	}
+	if err := checkRequired("champion", m.Champion, MaxChampionNameLength); err != nil {
+		return err
+	}
+	return checkLength("text", m.Text, MaxChatLength)
+}
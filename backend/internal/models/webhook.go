@@ -0,0 +1,125 @@
+package models
+
+import (
+	"net/url"
+	"slices"
+)
+
+// Limits of the webhook API
+const (
+	MaxWebhookUrlLength    = 512
+	MaxWebhookSecretLength = 128
+	MinWebhookSecretLength = 16
+	DefaultDeliveryLimit   = 50
+	MaxDeliveryLimit       = 500
+)
+
+// Events sent to webhooks
+const (
+	EventDraftStarted  = "draft.started"  // First turn of the draft
+	EventDraftLocked   = "draft.locked"   // End of every ban and pick turn
+	EventDraftFinished = "draft.finished" // After the trade phase, if any
+	EventGameResult    = "game.result"    // Winner of the game known
+	EventPing          = "ping"           // Sent on request to test a webhook
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{EventDraftStarted, EventDraftLocked, EventDraftFinished, EventGameResult}
+
+// Webhook is an HTTP endpoint notified of the draft lifecycle. The secret
+// signs every delivery and is only returned when the webhook is created.
+type Webhook struct {
+	Id           string   `json:"id"`
+	Url          string   `json:"url"`
+	TournamentId string   `json:"tournament_id,omitempty"` // Only rooms of this tournament; all rooms if empty
+	Events       []string `json:"events,omitempty"`        // All of WebhookEvents if empty
+	Secret       string   `json:"secret,omitempty"`
+	CreatedAt    int64    `json:"created_at"`
+}
+
+// Wants reports whether the webhook is subscribed to event for a room of
+// match, nil for rooms outside tournaments
+func (w Webhook) Wants(event string, match *MatchRef) bool {
+	if w.TournamentId != "" && (match == nil || match.TournamentId != w.TournamentId) {
+		return false
+	}
+	return event == EventPing || len(w.Events) == 0 || slices.Contains(w.Events, event)
+}
+
+// CreateWebhookRequest asks the admin HTTP API to register a webhook
+type CreateWebhookRequest struct {
+	Url          string   `json:"url"`
+	TournamentId string   `json:"tournament_id,omitempty"`
+	Events       []string `json:"events,omitempty"`
+	Secret       string   `json:"secret,omitempty"` // Generated if empty
+}
+
+// Validate checks the endpoint, scope and events of a webhook
+func (m CreateWebhookRequest) Validate() error {
+	if err := checkRequired("url", m.Url, MaxWebhookUrlLength); err != nil {
+		return err
+	}
+	if u, err := url.Parse(m.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return validationError("url", "must be an absolute http or https URL")
+	}
+	if err := checkLength("tournament_id", m.TournamentId, MaxRoomIdLength); err != nil {
+		return err
+	}
+	for _, event := range m.Events {
+		if !slices.Contains(WebhookEvents, event) {
+			return validationError("events", "unknown event %q", event)
+		}
+	}
+	if m.Secret != "" && (len(m.Secret) < MinWebhookSecretLength || len(m.Secret) > MaxWebhookSecretLength) {
+		return validationError("secret", "must be between %d and %d characters", MinWebhookSecretLength, MaxWebhookSecretLength)
+	}
+	return nil
+}
+
+// WebhookEvent is the body of a delivery. Data is a DraftStarted for
+// draft.started, a DraftAction for draft.locked and a DraftExport for
+// draft.finished and game.result.
+type WebhookEvent struct {
+	Id     string      `json:"id"` // Delivery ID, the same across retries
+	Event  string      `json:"event"`
+	At     int64       `json:"at"` // Unix milliseconds the event happened
+	RoomId string      `json:"room_id,omitempty"`
+	Match  *MatchRef   `json:"match,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// DraftStarted is the data of a draft.started event
+type DraftStarted struct {
+	BlueTeam string     `json:"blue_team"`
+	RedTeam  string     `json:"red_team"`
+	Patch    string     `json:"patch,omitempty"`
+	Bans     bool       `json:"bans"` // The draft has ban phases
+	Times    DraftTimes `json:"times"`
+}
+
+// Statuses of a WebhookDelivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // Out of attempts, or refused by the endpoint
+)
+
+// WebhookAttempt is a request of a delivery
+type WebhookAttempt struct {
+	At         int64  `json:"at"` // Unix milliseconds
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// WebhookDelivery is an event sent, or being sent, to a webhook
+type WebhookDelivery struct {
+	Id          string           `json:"id"`
+	WebhookId   string           `json:"webhook_id"`
+	Event       string           `json:"event"`
+	RoomId      string           `json:"room_id,omitempty"`
+	Status      string           `json:"status"`
+	CreatedAt   int64            `json:"created_at"`                // Unix milliseconds
+	NextAttempt int64            `json:"next_attempt_at,omitempty"` // Unix milliseconds of the next retry while pending
+	Attempts    []WebhookAttempt `json:"attempts"`
+}
@@ -0,0 +1,115 @@
+// Package ratelimit implements token-bucket rate limiting, standalone or
+// keyed by an arbitrary string such as a client IP
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket allowing limit events per period, with bursts of
+// up to limit events
+type Bucket struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	refill   float64 // Tokens per second
+	last     time.Time
+}
+
+// NewBucket creates a full bucket. A limit of zero or less never limits.
+func NewBucket(limit int, per time.Duration) *Bucket {
+	b := &Bucket{
+		capacity: float64(limit),
+		tokens:   float64(limit),
+		last:     time.Now(),
+	}
+	if limit > 0 && per > 0 {
+		b.refill = float64(limit) / per.Seconds()
+	}
+	return b
+}
+
+// Allow consumes a token if one is available. Otherwise it returns false and
+// how long until the next token.
+func (b *Bucket) Allow() (bool, time.Duration) {
+	if b.capacity <= 0 {
+		return true, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill(time.Now())
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.refill == 0 {
+		return false, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.refill * float64(time.Second))
+	return false, wait
+}
+
+// full reports whether the bucket has refilled completely
+func (b *Bucket) full() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.fill(time.Now())
+	return b.tokens >= b.capacity
+}
+
+func (b *Bucket) fill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.refill
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// Limiter keeps one bucket per key. Buckets that refilled completely are
+// forgotten so idle keys do not accumulate.
+type Limiter struct {
+	mu        sync.Mutex
+	limit     int
+	per       time.Duration
+	buckets   map[string]*Bucket
+	lastSweep time.Time
+}
+
+// NewLimiter creates a limiter allowing limit events per period for each key
+func NewLimiter(limit int, per time.Duration) *Limiter {
+	return &Limiter{
+		limit:     limit,
+		per:       per,
+		buckets:   make(map[string]*Bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow consumes a token from the bucket of key, see Bucket.Allow
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	if time.Since(l.lastSweep) > l.per {
+		for k, bucket := range l.buckets {
+			if bucket.full() {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = time.Now()
+	}
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = NewBucket(l.limit, l.per)
+		l.buckets[key] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.Allow()
+}
@@ -0,0 +1,153 @@
+// Package schema generates JSON Schema documents from the Go message models
+// so the wire protocol has a single source of truth
+package schema
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Draft is the JSON Schema dialect of the generated documents
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Generator builds a JSON Schema document describing a set of messages
+type Generator struct {
+	// Enums restricts named string types to a fixed set of values
+	Enums map[reflect.Type][]string
+
+	defs map[string]interface{}
+}
+
+// NewGenerator creates a schema generator
+func NewGenerator() *Generator {
+	return &Generator{
+		Enums: make(map[reflect.Type][]string),
+	}
+}
+
+// Document returns a schema whose root accepts any of the given messages.
+// The keys of messages are the values of their "type" field.
+func (g *Generator) Document(id, title string, messages map[string]interface{}) map[string]interface{} {
+	g.defs = make(map[string]interface{})
+
+	types := make([]string, 0, len(messages))
+	for msgType := range messages {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+
+	oneOf := make([]interface{}, 0, len(types))
+	for _, msgType := range types {
+		t := reflect.TypeOf(messages[msgType])
+		g.schemaFor(t)
+
+		// Fijar el valor de "type" para que cada variante sea distinguible
+		def := g.defs[t.Name()].(map[string]interface{})
+		if props, ok := def["properties"].(map[string]interface{}); ok {
+			if _, hasType := props["type"]; hasType {
+				props["type"] = map[string]interface{}{"const": msgType}
+			}
+		}
+		oneOf = append(oneOf, ref(t.Name()))
+	}
+
+	return map[string]interface{}{
+		"$schema": Draft,
+		"$id":     id,
+		"title":   title,
+		"oneOf":   oneOf,
+		"$defs":   g.defs,
+	}
+}
+
+// schemaFor returns the schema of t, registering struct types in $defs
+func (g *Generator) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if values, ok := g.Enums[t]; ok {
+		enum := make([]interface{}, len(values))
+		for i, v := range values {
+			enum[i] = v
+		}
+		return map[string]interface{}{"type": "string", "enum": enum}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if _, done := g.defs[t.Name()]; !done {
+			// Registrar antes de recorrer los campos para soportar tipos recursivos
+			g.defs[t.Name()] = map[string]interface{}{}
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return ref(t.Name())
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema describes the JSON encoding of a struct type
+func (g *Generator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		properties[name] = g.schemaFor(field.Type)
+		if !omitempty {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// jsonName reads the encoding/json name and options of a struct field
+func jsonName(field reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"picks3w2a/internal/models"
+)
+
+// AnalyticsService computes champion statistics over the finished drafts of
+// a draft store
+type AnalyticsService struct {
+	store DraftStore
+}
+
+// NewAnalyticsService creates the analytics service
+func NewAnalyticsService(store DraftStore) *AnalyticsService {
+	return &AnalyticsService{store: store}
+}
+
+// ChampionReport computes the statistics of every champion picked or banned
+// in the drafts matching filter
+func (as *AnalyticsService) ChampionReport(filter models.DraftFilter) (models.ChampionReport, error) {
+	drafts, err := as.store.FinishedDrafts(filter)
+	if err != nil {
+		return models.ChampionReport{}, err
+	}
+	return championReport(drafts, filter), nil
+}
+
+// bluePickPhases are the pick phases of the blue team, by pick index
+var bluePickPhases = []models.Phase{models.PickBlue1, models.PickBlue2, models.PickBlue3}
+
+// redPickPhases are the pick phases of the red team, by pick index
+var redPickPhases = []models.Phase{models.PickRed1, models.PickRed2, models.PickRed3}
+
+// championTally accumulates the statistics of a champion
+type championTally struct {
+	stats     models.ChampionStats
+	turnSum   int
+	turnCount int
+}
+
+// championReport tallies drafts, which must already match filter. With a
+// team in the filter only the side played by that team is counted.
+func championReport(drafts []RoomData, filter models.DraftFilter) models.ChampionReport {
+	report := models.ChampionReport{Filter: filter, Drafts: len(drafts), Champions: []models.ChampionStats{}}
+	tallies := make(map[string]*championTally)
+	tally := func(champion string) *championTally {
+		t, exists := tallies[champion]
+		if !exists {
+			t = &championTally{stats: models.ChampionStats{Champion: champion}}
+			tallies[champion] = t
+		}
+		return t
+	}
+
+	for _, draft := range drafts {
+		if draft.Result != nil {
+			report.Decided++
+		}
+		sides := []string{models.SeatBlue, models.SeatRed}
+		if filter.Team != "" {
+			sides = []string{draftSide(draft, filter.Team)}
+		}
+
+		present := make(map[string]bool)
+		for _, side := range sides {
+			team, pickPhases := draft.BlueTeam, bluePickPhases
+			if side == models.SeatRed {
+				team, pickPhases = draft.RedTeam, redPickPhases
+			}
+
+			for _, ban := range team.Bans {
+				if ban.Name == "-1" || ban.Name == "" {
+					continue
+				}
+				t := tally(ban.Name)
+				t.stats.Bans++
+				sideStats(&t.stats, side).Bans++
+				present[ban.Name] = true
+			}
+
+			for i, pick := range team.Picks {
+				if pick.Name == "-1" || pick.Name == "" {
+					continue
+				}
+				t := tally(pick.Name)
+				t.stats.Picks++
+				onSide := sideStats(&t.stats, side)
+				onSide.Picks++
+				present[pick.Name] = true
+
+				// Los drafts anteriores al turno guardado lo deducen de la posición
+				turn := pick.Turn
+				if turn == 0 && i < len(pickPhases) {
+					turn = models.PickTurn(pickPhases[i])
+				}
+				if turn > 0 {
+					t.turnSum += turn
+					t.turnCount++
+				}
+				if turn == 1 {
+					t.stats.FirstPicks++
+				}
+
+				if draft.Result != nil {
+					t.stats.Decided++
+					onSide.Decided++
+					if draft.Result.Winner == side {
+						t.stats.Wins++
+						onSide.Wins++
+					}
+				}
+			}
+		}
+		for champion := range present {
+			tallies[champion].stats.Presence++
+		}
+	}
+
+	for _, t := range tallies {
+		stats := t.stats
+		stats.PickRate = rate(stats.Picks, report.Drafts)
+		stats.BanRate = rate(stats.Bans, report.Drafts)
+		stats.PresenceRate = rate(stats.Presence, report.Drafts)
+		stats.WinRate = rate(stats.Wins, stats.Decided)
+		stats.FirstPickRate = rate(stats.FirstPicks, report.Drafts)
+		stats.Blue.WinRate = rate(stats.Blue.Wins, stats.Blue.Decided)
+		stats.Red.WinRate = rate(stats.Red.Wins, stats.Red.Decided)
+		if t.turnCount > 0 {
+			stats.AvgPickTurn = math.Round(float64(t.turnSum)/float64(t.turnCount)*100) / 100
+		}
+		report.Champions = append(report.Champions, stats)
+	}
+	sort.Slice(report.Champions, func(i, j int) bool {
+		a, b := report.Champions[i], report.Champions[j]
+		if a.Presence != b.Presence {
+			return a.Presence > b.Presence
+		}
+		if a.Picks != b.Picks {
+			return a.Picks > b.Picks
+		}
+		return a.Champion < b.Champion
+	})
+	return report
+}
+
+// sideStats returns the statistics of a champion on side
+func sideStats(stats *models.ChampionStats, side string) *models.SideStats {
+	if side == models.SeatRed {
+		return &stats.Red
+	}
+	return &stats.Blue
+}
+
+// rate divides n by total, rounded to four decimals, or 0 if total is 0
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)/float64(total)*10000) / 10000
+}
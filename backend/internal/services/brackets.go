@@ -0,0 +1,490 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math/bits"
+	"sort"
+	"time"
+
+	"picks3w2a/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// CreateBracket generates the matches of a bracket of registered teams.
+// Elimination brackets are filled up to a power of two with byes for the top
+// seeds; double elimination ends in a single grand final, without reset.
+func (ts *TournamentService) CreateBracket(tournamentId string, req models.CreateBracketRequest) (models.BracketCreatedResponse, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.BracketCreatedResponse{}, err
+	}
+	for _, teamId := range req.TeamIds {
+		if findTeam(tournament, teamId) == nil {
+			return models.BracketCreatedResponse{}, teamNotFoundError(teamId)
+		}
+	}
+	id := req.Id
+	if id == "" {
+		id = ts.roomService.generateRandomID()
+	}
+	for _, other := range tournament.Brackets {
+		if other.Id == id {
+			return models.BracketCreatedResponse{}, duplicateIdError("bracket", id)
+		}
+	}
+
+	bracket := models.Bracket{
+		Id:        id,
+		Name:      req.Name,
+		Format:    req.Format,
+		TeamIds:   req.TeamIds,
+		BestOf:    req.BestOf,
+		Fearless:  req.Fearless,
+		CreatedAt: time.Now().Unix(),
+	}
+	if bracket.BestOf == 0 {
+		bracket.BestOf = 1
+	}
+
+	var matches []models.Match
+	switch bracket.Format {
+	case models.BracketRoundRobin:
+		matches = roundRobinMatches(bracket)
+	default:
+		matches = eliminationMatches(bracket)
+	}
+	for _, match := range matches {
+		for _, other := range ts.tournaments {
+			if _, err := findMatch(other, match.Id); err == nil {
+				return models.BracketCreatedResponse{}, duplicateIdError("match", match.Id)
+			}
+		}
+	}
+
+	tournament.Brackets = append(tournament.Brackets, bracket)
+	tournament.Matches = append(tournament.Matches, matches...)
+	ts.save()
+	ts.broadcastStandings(tournament)
+
+	response := models.BracketCreatedResponse{Bracket: bracket, Matches: make([]models.Match, len(matches))}
+	for i, match := range matches {
+		response.Matches[i] = cloneMatch(match)
+	}
+	return response, nil
+}
+
+// eliminationMatches generates the upper bracket and, for double elimination,
+// the lower bracket and the grand final. Matches of round 1 without an
+// opponent are not created; their team starts in round 2.
+func eliminationMatches(bracket models.Bracket) []models.Match {
+	size := 1
+	for size < len(bracket.TeamIds) {
+		size *= 2
+	}
+	rounds := bits.Len(uint(size)) - 1
+
+	// upper[r] son las partidas de la ronda r del cuadro superior
+	upper := make([][]models.Match, rounds+1)
+	for r := 1; r <= rounds; r++ {
+		upper[r] = bracketRound(bracket, models.StageUpper, "u", r, size>>r)
+	}
+	for r := 1; r < rounds; r++ {
+		for i := range upper[r] {
+			upper[r][i].WinnerTo = bracketSlot(upper[r+1][i/2], i)
+		}
+	}
+
+	// Emparejamiento por cabezas de serie: 1 contra el último, etc.
+	order := seedOrder(size)
+	byes := make(map[int]bool)
+	for i := range upper[1] {
+		upper[1][i].BlueTeamId = seededTeam(bracket, order[2*i])
+		upper[1][i].RedTeamId = seededTeam(bracket, order[2*i+1])
+		if upper[1][i].RedTeamId == "" {
+			byes[i] = true
+			setSide(&upper[2][i/2], upper[1][i].WinnerTo.Side, upper[1][i].BlueTeamId)
+		}
+	}
+
+	var lower [][]models.Match
+	if bracket.Format == models.BracketDoubleElimination {
+		// Ronda 1 del inferior: los perdedores de la ronda 1 del superior
+		round := 1
+		previous := bracketRound(bracket, models.StageLower, "l", round, size/4)
+		for i := range upper[1] {
+			upper[1][i].LoserTo = bracketSlot(previous[i/2], i)
+		}
+		lower = append(lower, previous)
+
+		// Cada ronda del superior deja caer a sus perdedores contra los
+		// ganadores del inferior, y el inferior se reduce a la mitad
+		for r := 2; r <= rounds; r++ {
+			round++
+			drop := bracketRound(bracket, models.StageLower, "l", round, len(previous))
+			for j := range drop {
+				previous[j].WinnerTo = &models.BracketSlot{MatchId: drop[j].Id, Side: models.SeatBlue}
+				upper[r][j].LoserTo = &models.BracketSlot{MatchId: drop[j].Id, Side: models.SeatRed}
+			}
+			lower = append(lower, drop)
+			previous = drop
+			if r < rounds {
+				round++
+				reduce := bracketRound(bracket, models.StageLower, "l", round, len(drop)/2)
+				for j := range drop {
+					drop[j].WinnerTo = bracketSlot(reduce[j/2], j)
+				}
+				lower = append(lower, reduce)
+				previous = reduce
+			}
+		}
+
+		final := bracketMatch(bracket, models.StageGrandFinal, bracket.Id+"-gf", 1)
+		upper[rounds][0].WinnerTo = &models.BracketSlot{MatchId: final.Id, Side: models.SeatBlue}
+		previous[0].WinnerTo = &models.BracketSlot{MatchId: final.Id, Side: models.SeatRed}
+		lower = append(lower, []models.Match{final})
+	}
+
+	var matches []models.Match
+	for r := 1; r <= rounds; r++ {
+		for i, match := range upper[r] {
+			if r == 1 && byes[i] {
+				continue
+			}
+			matches = append(matches, match)
+		}
+	}
+	for _, round := range lower {
+		matches = append(matches, round...)
+	}
+	return matches
+}
+
+// roundRobinMatches pairs every team with every other once, with the circle
+// method so each round has every team playing at most once
+func roundRobinMatches(bracket models.Bracket) []models.Match {
+	teams := append([]string(nil), bracket.TeamIds...)
+	if len(teams)%2 == 1 {
+		teams = append(teams, "") // Descansa quien juegue contra ""
+	}
+	n := len(teams)
+
+	var matches []models.Match
+	for round := 1; round < n; round++ {
+		index := 0
+		for i := 0; i < n/2; i++ {
+			blue, red := teams[i], teams[n-1-i]
+			if blue == "" || red == "" {
+				continue
+			}
+			// El equipo fijo alterna de lado cada ronda
+			if i == 0 && round%2 == 0 {
+				blue, red = red, blue
+			}
+			index++
+			match := bracketMatch(bracket, models.StageGroup, fmt.Sprintf("%s-r%d-%d", bracket.Id, round, index), round)
+			match.BlueTeamId, match.RedTeamId = blue, red
+			matches = append(matches, match)
+		}
+
+		rotated := make([]string, n)
+		rotated[0], rotated[1] = teams[0], teams[n-1]
+		copy(rotated[2:], teams[1:n-1])
+		teams = rotated
+	}
+	return matches
+}
+
+// bracketRound creates the empty matches of a round of a bracket
+func bracketRound(bracket models.Bracket, stage, prefix string, round, count int) []models.Match {
+	matches := make([]models.Match, count)
+	for i := range matches {
+		matches[i] = bracketMatch(bracket, stage, fmt.Sprintf("%s-%s%d-%d", bracket.Id, prefix, round, i+1), round)
+	}
+	return matches
+}
+
+// bracketMatch creates a scheduled match of a bracket
+func bracketMatch(bracket models.Bracket, stage, id string, round int) models.Match {
+	return models.Match{
+		Id:        id,
+		BracketId: bracket.Id,
+		Stage:     stage,
+		Round:     round,
+		BestOf:    bracket.BestOf,
+		Fearless:  bracket.Fearless,
+		Status:    models.MatchScheduled,
+		Games:     []models.MatchGame{},
+	}
+}
+
+// bracketSlot is the side of next taken by the i-th match feeding it: even
+// matches go to blue, odd ones to red
+func bracketSlot(next models.Match, i int) *models.BracketSlot {
+	side := models.SeatBlue
+	if i%2 == 1 {
+		side = models.SeatRed
+	}
+	return &models.BracketSlot{MatchId: next.Id, Side: side}
+}
+
+// setSide puts a team on a side of a match
+func setSide(match *models.Match, side, teamId string) {
+	if side == models.SeatBlue {
+		match.BlueTeamId = teamId
+	} else {
+		match.RedTeamId = teamId
+	}
+}
+
+// seedOrder lists the seeds of a bracket of size teams in the order they are
+// paired in round 1, so the top seeds only meet in the last rounds
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order) * 2
+		next := make([]int, 0, n)
+		for _, seed := range order {
+			next = append(next, seed, n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// seededTeam returns the team with a seed, "" for a bye
+func seededTeam(bracket models.Bracket, seed int) string {
+	if seed > len(bracket.TeamIds) {
+		return ""
+	}
+	return bracket.TeamIds[seed-1]
+}
+
+// Standings returns the standings of a tournament
+func (ts *TournamentService) Standings(tournamentId string) (models.StandingsMessage, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.StandingsMessage{}, err
+	}
+	return standingsMessage(tournament), nil
+}
+
+// standingsMessage computes the standings of a tournament, overall and per
+// bracket. Must be called with mu held.
+func standingsMessage(tournament *models.Tournament) models.StandingsMessage {
+	message := models.StandingsMessage{
+		Type:         "standings",
+		TournamentId: tournament.Id,
+		Brackets:     make([]models.BracketStandings, 0, len(tournament.Brackets)),
+		Matches:      make([]models.Match, len(tournament.Matches)),
+	}
+	for i, match := range tournament.Matches {
+		message.Matches[i] = cloneMatch(match)
+	}
+
+	teamIds := make([]string, len(tournament.Teams))
+	for i, team := range tournament.Teams {
+		teamIds[i] = team.Id
+	}
+	message.Overall = models.BracketStandings{
+		Standings: rankTeams(tournament, teamIds, tournament.Matches, false),
+	}
+
+	for _, bracket := range tournament.Brackets {
+		var matches []models.Match
+		for _, match := range tournament.Matches {
+			if match.BracketId == bracket.Id {
+				matches = append(matches, match)
+			}
+		}
+		message.Brackets = append(message.Brackets, models.BracketStandings{
+			BracketId: bracket.Id,
+			Name:      bracket.Name,
+			Format:    bracket.Format,
+			Standings: rankTeams(tournament, bracket.TeamIds, matches, bracket.Format != models.BracketRoundRobin),
+		})
+	}
+	return message
+}
+
+// rankTeams computes the record of each team in matches and ranks them by
+// match wins, then fewer losses, then game difference. In elimination
+// brackets teams still alive rank above eliminated ones.
+func rankTeams(tournament *models.Tournament, teamIds []string, matches []models.Match, elimination bool) []models.Standing {
+	standings := make([]models.Standing, len(teamIds))
+	byTeam := make(map[string]*models.Standing, len(teamIds))
+	for i, teamId := range teamIds {
+		standings[i].TeamId = teamId
+		if team := findTeam(tournament, teamId); team != nil {
+			standings[i].Name = team.Name
+			standings[i].Tag = team.Tag
+		}
+		byTeam[teamId] = &standings[i]
+	}
+
+	for _, match := range matches {
+		for _, game := range match.Games {
+			if game.Winner == "" {
+				continue
+			}
+			loser := game.BlueTeamId
+			if loser == game.Winner {
+				loser = game.RedTeamId
+			}
+			if standing, ok := byTeam[game.Winner]; ok {
+				standing.GameWins++
+			}
+			if standing, ok := byTeam[loser]; ok {
+				standing.GameLosses++
+			}
+		}
+		if match.Winner == "" {
+			continue
+		}
+		loser := match.BlueTeamId
+		if loser == match.Winner {
+			loser = match.RedTeamId
+		}
+		if standing, ok := byTeam[match.Winner]; ok {
+			standing.Played++
+			standing.Wins++
+		}
+		if standing, ok := byTeam[loser]; ok {
+			standing.Played++
+			standing.Losses++
+			if elimination && match.LoserTo == nil {
+				standing.Eliminated = true
+			}
+		}
+	}
+
+	// El orden de entrada (cabezas de serie) desempata
+	less := func(a, b models.Standing) bool {
+		if a.Eliminated != b.Eliminated {
+			return !a.Eliminated
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Losses != b.Losses {
+			return a.Losses < b.Losses
+		}
+		return a.GameWins-a.GameLosses > b.GameWins-b.GameLosses
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		return less(standings[i], standings[j])
+	})
+	for i := range standings {
+		if i > 0 && !less(standings[i-1], standings[i]) {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+	return standings
+}
+
+// standingsWatcher sends the standings of a tournament to a connection from
+// its own goroutine, so that no write happens while mu is held. Only the
+// latest standings are queued: they replace the ones the client did not
+// get yet.
+type standingsWatcher struct {
+	conn    *websocket.Conn
+	pending chan models.StandingsMessage
+	done    chan struct{}
+}
+
+// newStandingsWatcher starts the writer of a connection
+func newStandingsWatcher(conn *websocket.Conn) *standingsWatcher {
+	w := &standingsWatcher{
+		conn:    conn,
+		pending: make(chan models.StandingsMessage, 1),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// queue replaces the standings waiting to be sent. Must be called with
+// watchMu held, which keeps it the only sender.
+func (w *standingsWatcher) queue(message models.StandingsMessage) {
+	select {
+	case <-w.pending:
+	default:
+	}
+	w.pending <- message
+}
+
+// run writes the queued standings until the watcher is stopped
+func (w *standingsWatcher) run() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case message := <-w.pending:
+			if err := WriteJSON(w.conn, message); err != nil {
+				log.Printf("Error sending standings of tournament %s: %v", message.TournamentId, err)
+			}
+		}
+	}
+}
+
+// Watch subscribes a connection to the standings of a tournament and sends it
+// the current ones
+func (ts *TournamentService) Watch(conn *websocket.Conn, tournamentId string) error {
+	ts.Unwatch(conn)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return err
+	}
+
+	ts.watchMu.Lock()
+	defer ts.watchMu.Unlock()
+	if ts.watchers[tournamentId] == nil {
+		ts.watchers[tournamentId] = make(map[*websocket.Conn]*standingsWatcher)
+	}
+	watcher := newStandingsWatcher(conn)
+	ts.watchers[tournamentId][conn] = watcher
+	watcher.queue(standingsMessage(tournament))
+	return nil
+}
+
+// Unwatch cancels the standings subscription of a connection, if any
+func (ts *TournamentService) Unwatch(conn *websocket.Conn) {
+	ts.watchMu.Lock()
+	defer ts.watchMu.Unlock()
+	for tournamentId, conns := range ts.watchers {
+		if watcher, watching := conns[conn]; watching {
+			close(watcher.done)
+			delete(conns, conn)
+		}
+		if len(conns) == 0 {
+			delete(ts.watchers, tournamentId)
+		}
+	}
+}
+
+// broadcastStandings queues the standings of a tournament, snapshotted while
+// mu is held, for the connections watching it. Must be called with mu held.
+func (ts *TournamentService) broadcastStandings(tournament *models.Tournament) {
+	ts.watchMu.Lock()
+	defer ts.watchMu.Unlock()
+	conns := ts.watchers[tournament.Id]
+	if len(conns) == 0 {
+		return
+	}
+	message := standingsMessage(tournament)
+	for _, watcher := range conns {
+		watcher.queue(message)
+	}
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"picks3w2a/internal/models"
+)
+
+// teamIds returns the IDs t1..tn, by seed
+func teamIds(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("t%d", i+1)
+	}
+	return ids
+}
+
+// checkBracketFeeds checks that every side of every match is either filled
+// from the start or fed by exactly one other match, that no slot points
+// outside the bracket and that a single match, the final, sends its winner
+// nowhere
+func checkBracketFeeds(t *testing.T, matches []models.Match) {
+	t.Helper()
+	byId := make(map[string]models.Match, len(matches))
+	for _, match := range matches {
+		if _, duplicate := byId[match.Id]; duplicate {
+			t.Fatalf("match %s generated twice", match.Id)
+		}
+		byId[match.Id] = match
+	}
+
+	feeds := make(map[models.BracketSlot]int)
+	finals := 0
+	for _, match := range matches {
+		if match.WinnerTo == nil {
+			finals++
+		}
+		for _, slot := range []*models.BracketSlot{match.WinnerTo, match.LoserTo} {
+			if slot == nil {
+				continue
+			}
+			if _, exists := byId[slot.MatchId]; !exists {
+				t.Fatalf("match %s feeds missing match %s", match.Id, slot.MatchId)
+			}
+			feeds[*slot]++
+		}
+	}
+	if finals != 1 {
+		t.Fatalf("expected one final, got %d matches without winner_to", finals)
+	}
+
+	for _, match := range matches {
+		for side, teamId := range map[string]string{models.SeatBlue: match.BlueTeamId, models.SeatRed: match.RedTeamId} {
+			sources := feeds[models.BracketSlot{MatchId: match.Id, Side: side}]
+			if teamId != "" {
+				sources++
+			}
+			if sources != 1 {
+				t.Errorf("%s side of match %s has %d sources", side, match.Id, sources)
+			}
+		}
+	}
+}
+
+// firstRound returns the pairs of the upper matches of round 1 and the teams
+// placed directly in round 2 by a bye
+func firstRound(matches []models.Match) (pairs [][2]string, byes []string) {
+	for _, match := range matches {
+		switch {
+		case match.Stage == models.StageUpper && match.Round == 1:
+			pairs = append(pairs, [2]string{match.BlueTeamId, match.RedTeamId})
+		case match.Stage == models.StageUpper && match.Round == 2:
+			for _, teamId := range []string{match.BlueTeamId, match.RedTeamId} {
+				if teamId != "" {
+					byes = append(byes, teamId)
+				}
+			}
+		}
+	}
+	return pairs, byes
+}
+
+func TestSeedOrder(t *testing.T) {
+	tests := []struct {
+		size int
+		want []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{4, []int{1, 4, 2, 3}},
+		{8, []int{1, 8, 4, 5, 2, 7, 3, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.size), func(t *testing.T) {
+			if got := seedOrder(tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("seedOrder(%d) = %v, want %v", tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEliminationMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		teams   int
+		matches int
+		rounds  map[string]int // Matches per stage
+		pairs   [][2]string    // Upper round 1
+		byes    []string       // Teams starting in upper round 2
+	}{
+		{
+			name: "single elimination of 3", format: models.BracketSingleElimination, teams: 3, matches: 2,
+			rounds: map[string]int{models.StageUpper: 2},
+			pairs:  [][2]string{{"t2", "t3"}},
+			byes:   []string{"t1"},
+		},
+		{
+			name: "single elimination of 5", format: models.BracketSingleElimination, teams: 5, matches: 4,
+			rounds: map[string]int{models.StageUpper: 4},
+			pairs:  [][2]string{{"t4", "t5"}},
+			byes:   []string{"t1", "t2", "t3"},
+		},
+		{
+			name: "single elimination of 8", format: models.BracketSingleElimination, teams: 8, matches: 7,
+			rounds: map[string]int{models.StageUpper: 7},
+			pairs:  [][2]string{{"t1", "t8"}, {"t4", "t5"}, {"t2", "t7"}, {"t3", "t6"}},
+		},
+		{
+			name: "double elimination of 4", format: models.BracketDoubleElimination, teams: 4, matches: 6,
+			rounds: map[string]int{models.StageUpper: 3, models.StageLower: 2, models.StageGrandFinal: 1},
+			pairs:  [][2]string{{"t1", "t4"}, {"t2", "t3"}},
+		},
+		{
+			name: "double elimination of 8", format: models.BracketDoubleElimination, teams: 8, matches: 14,
+			rounds: map[string]int{models.StageUpper: 7, models.StageLower: 6, models.StageGrandFinal: 1},
+			pairs:  [][2]string{{"t1", "t8"}, {"t4", "t5"}, {"t2", "t7"}, {"t3", "t6"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bracket := models.Bracket{Id: "b", Format: tt.format, TeamIds: teamIds(tt.teams), BestOf: 3}
+			matches := eliminationMatches(bracket)
+			if len(matches) != tt.matches {
+				t.Fatalf("expected %d matches, got %d", tt.matches, len(matches))
+			}
+
+			stages := make(map[string]int)
+			for _, match := range matches {
+				stages[match.Stage]++
+				if match.BracketId != "b" || match.BestOf != 3 || match.Status != models.MatchScheduled {
+					t.Errorf("match %s not scheduled in bracket b as a best of 3: %+v", match.Id, match)
+				}
+				if tt.format == models.BracketSingleElimination && match.LoserTo != nil {
+					t.Errorf("match %s of a single elimination sends its loser to %s", match.Id, match.LoserTo.MatchId)
+				}
+			}
+			if !reflect.DeepEqual(stages, tt.rounds) {
+				t.Fatalf("expected matches per stage %v, got %v", tt.rounds, stages)
+			}
+
+			pairs, byes := firstRound(matches)
+			if !reflect.DeepEqual(pairs, tt.pairs) {
+				t.Errorf("expected round 1 %v, got %v", tt.pairs, pairs)
+			}
+			if !reflect.DeepEqual(byes, tt.byes) {
+				t.Errorf("expected byes for %v, got %v", tt.byes, byes)
+			}
+			checkBracketFeeds(t, matches)
+		})
+	}
+}
+
+func TestDoubleEliminationGrandFinal(t *testing.T) {
+	bracket := models.Bracket{Id: "b", Format: models.BracketDoubleElimination, TeamIds: teamIds(4), BestOf: 1}
+	matches := eliminationMatches(bracket)
+
+	final := matches[len(matches)-1]
+	if final.Id != "b-gf" || final.Stage != models.StageGrandFinal || final.WinnerTo != nil {
+		t.Fatalf("expected the grand final last, got %+v", final)
+	}
+	for _, match := range matches {
+		if match.WinnerTo == nil || match.WinnerTo.MatchId != final.Id {
+			continue
+		}
+		// The upper final takes blue, the lower final red
+		want := models.SeatBlue
+		if match.Stage == models.StageLower {
+			want = models.SeatRed
+		}
+		if match.WinnerTo.Side != want {
+			t.Errorf("%s match %s reaches the grand final on %s", match.Stage, match.Id, match.WinnerTo.Side)
+		}
+	}
+}
+
+func TestRoundRobinMatches(t *testing.T) {
+	tests := []struct {
+		teams  int
+		rounds int
+	}{
+		{3, 3},
+		{4, 3},
+		{5, 5},
+		{7, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d teams", tt.teams), func(t *testing.T) {
+			bracket := models.Bracket{Id: "rr", Format: models.BracketRoundRobin, TeamIds: teamIds(tt.teams), BestOf: 1}
+			matches := roundRobinMatches(bracket)
+			if want := tt.teams * (tt.teams - 1) / 2; len(matches) != want {
+				t.Fatalf("expected %d matches, got %d", want, len(matches))
+			}
+
+			pairs := make(map[[2]string]bool)
+			played := make(map[string]int)
+			perRound := make(map[int]map[string]bool)
+			for _, match := range matches {
+				if match.Stage != models.StageGroup || match.BlueTeamId == "" || match.RedTeamId == "" || match.BlueTeamId == match.RedTeamId {
+					t.Fatalf("invalid match %+v", match)
+				}
+				pair := [2]string{match.BlueTeamId, match.RedTeamId}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if pairs[pair] {
+					t.Fatalf("%s and %s meet twice", pair[0], pair[1])
+				}
+				pairs[pair] = true
+
+				if perRound[match.Round] == nil {
+					perRound[match.Round] = make(map[string]bool)
+				}
+				for _, teamId := range pair {
+					if perRound[match.Round][teamId] {
+						t.Fatalf("%s plays twice in round %d", teamId, match.Round)
+					}
+					perRound[match.Round][teamId] = true
+					played[teamId]++
+				}
+			}
+			if len(perRound) != tt.rounds {
+				t.Fatalf("expected %d rounds, got %d", tt.rounds, len(perRound))
+			}
+			for _, teamId := range bracket.TeamIds {
+				if played[teamId] != tt.teams-1 {
+					t.Errorf("%s plays %d matches, want %d", teamId, played[teamId], tt.teams-1)
+				}
+			}
+		})
+	}
+}
+
+// decided returns a match won by winner, with a game won by each of games
+func decided(blue, red, winner string, loserTo *models.BracketSlot, games ...string) models.Match {
+	match := models.Match{BlueTeamId: blue, RedTeamId: red, Winner: winner, LoserTo: loserTo}
+	for i, gameWinner := range games {
+		match.Games = append(match.Games, models.MatchGame{Number: i + 1, BlueTeamId: blue, RedTeamId: red, Winner: gameWinner})
+	}
+	return match
+}
+
+func TestRankTeams(t *testing.T) {
+	lower := &models.BracketSlot{MatchId: "l1", Side: models.SeatBlue}
+	tests := []struct {
+		name        string
+		teams       []string
+		matches     []models.Match
+		elimination bool
+		want        []string // Team IDs in order
+		ranks       []int
+	}{
+		{
+			name:  "match wins",
+			teams: []string{"a", "b", "c"},
+			matches: []models.Match{
+				decided("a", "b", "a", nil, "a", "a"),
+				decided("b", "c", "b", nil, "b", "c", "b"),
+				decided("c", "a", "a", nil, "c", "a", "a"),
+			},
+			want:  []string{"a", "b", "c"},
+			ranks: []int{1, 2, 3},
+		},
+		{
+			name:  "game difference breaks ties",
+			teams: []string{"a", "b", "c", "d"},
+			matches: []models.Match{
+				decided("a", "b", "a", nil, "a", "b", "a"),
+				decided("c", "d", "c", nil, "c", "c"),
+			},
+			want:  []string{"c", "a", "b", "d"},
+			ranks: []int{1, 2, 3, 4},
+		},
+		{
+			name:  "ties share a rank in seed order",
+			teams: []string{"a", "b", "c", "d"},
+			matches: []models.Match{
+				decided("a", "b", "b", nil, "b"),
+				decided("c", "d", "d", nil, "d"),
+			},
+			want:  []string{"b", "d", "a", "c"},
+			ranks: []int{1, 1, 3, 3},
+		},
+		{
+			name:  "eliminated teams rank last",
+			teams: []string{"a", "b", "c", "d"},
+			matches: []models.Match{
+				decided("a", "b", "a", lower, "a"),
+				decided("c", "d", "c", nil, "c"),
+			},
+			elimination: true,
+			want:        []string{"a", "c", "b", "d"},
+			ranks:       []int{1, 1, 3, 4},
+		},
+		{
+			name:  "undecided matches only count their games",
+			teams: []string{"a", "b"},
+			matches: []models.Match{
+				decided("a", "b", "", nil, "b"),
+			},
+			want:  []string{"b", "a"},
+			ranks: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tournament := &models.Tournament{}
+			for _, teamId := range tt.teams {
+				tournament.Teams = append(tournament.Teams, models.TournamentTeam{Id: teamId, Name: "Team " + teamId})
+			}
+			standings := rankTeams(tournament, tt.teams, tt.matches, tt.elimination)
+
+			var order []string
+			var ranks []int
+			for _, standing := range standings {
+				order = append(order, standing.TeamId)
+				ranks = append(ranks, standing.Rank)
+				if standing.Name != "Team "+standing.TeamId {
+					t.Errorf("standing of %s named %q", standing.TeamId, standing.Name)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.want) || !reflect.DeepEqual(ranks, tt.ranks) {
+				t.Fatalf("expected %v ranked %v, got %v ranked %v", tt.want, tt.ranks, order, ranks)
+			}
+		})
+	}
+}
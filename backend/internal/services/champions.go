@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ChampionCatalog lists the champions of the game, read from a Data Dragon
+// champion.json file. Drafts store champions by their numeric key, as the
+// client sends them; the catalog also resolves IDs ("MonkeyKing") and names
+// ("Wukong") to keys, case-insensitively.
+type ChampionCatalog struct {
+	aliases map[string]string // Lower-cased key, ID or name to key
+}
+
+// championFile is the part of a Data Dragon champion.json that is read
+type championFile struct {
+	Data map[string]struct {
+		Id   string `json:"id"`
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+// LoadChampionCatalog reads a Data Dragon champion.json file. Without a file
+// the catalog is nil, which accepts every champion as it is written.
+func LoadChampionCatalog(path string) (*ChampionCatalog, error) {
+	if path == "" {
+		log.Println("Warning: CHAMPIONS_FILE is empty, imported drafts are not checked against a champion catalog")
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading champions file: %v", err)
+	}
+	var file championFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing champions file %s: %v", path, err)
+	}
+	if len(file.Data) == 0 {
+		return nil, fmt.Errorf("champions file %s has no champions", path)
+	}
+
+	catalog := &ChampionCatalog{aliases: make(map[string]string)}
+	for _, champion := range file.Data {
+		if champion.Key == "" {
+			return nil, fmt.Errorf("champion %s has no key in %s", champion.Id, path)
+		}
+		for _, alias := range []string{champion.Key, champion.Id, champion.Name} {
+			if alias != "" {
+				catalog.aliases[strings.ToLower(alias)] = champion.Key
+			}
+		}
+	}
+	log.Printf("Loaded %d champions from %s", len(file.Data), path)
+	return catalog, nil
+}
+
+// Resolve returns the key of a champion given its key, ID or name, and false
+// if the catalog does not have it
+func (c *ChampionCatalog) Resolve(champion string) (string, bool) {
+	if c == nil {
+		return champion, true
+	}
+	key, exists := c.aliases[strings.ToLower(strings.TrimSpace(champion))]
+	return key, exists
+}
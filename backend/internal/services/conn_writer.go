@@ -0,0 +1,38 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeTimeout bounds every write, so a client that stops reading cannot
+// block the goroutine writing to it
+const writeTimeout = 10 * time.Second
+
+// connWriters holds the write lock of every open connection. gorilla/websocket
+// allows a single concurrent writer, while the handler, the room timers and the
+// HTTP API all write to the same connections.
+var connWriters sync.Map // *websocket.Conn -> *sync.Mutex
+
+// TrackConn registers a new connection, before anything writes to it
+func TrackConn(conn *websocket.Conn) {
+	connWriters.Store(conn, &sync.Mutex{})
+}
+
+// ForgetConn drops the write lock of a closed connection
+func ForgetConn(conn *websocket.Conn) {
+	connWriters.Delete(conn)
+}
+
+// WriteJSON sends a message to a connection, one writer at a time and with
+// a deadline. Every JSON write to a client goes through it.
+func WriteJSON(conn *websocket.Conn, message interface{}) error {
+	if mu, tracked := connWriters.Load(conn); tracked {
+		mu.(*sync.Mutex).Lock()
+		defer mu.(*sync.Mutex).Unlock()
+	}
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteJSON(message)
+}
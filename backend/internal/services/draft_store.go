@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"picks3w2a/internal/models"
+)
+
+// DraftStore keeps the drafts and reads back the finished ones, for
+// analytics, exports and the history.
+// FirebaseService implements it over the rooms collection; LocalDraftStore is
+// used when Firestore is disabled.
+type DraftStore interface {
+	SaveRoom(room *models.Room) error // Also used for drafts abandoned halfway
+	LoadRoom(roomId string) (*models.Room, error)
+	FinishedDrafts(filter models.DraftFilter) ([]RoomData, error)
+	FindDraft(roomId string) (*RoomData, error) // nil if the room has no finished draft
+	// QueryDrafts returns a page of the drafts matching query and the cursor
+	// of the next page. Sort and Limit must be set.
+	QueryDrafts(query models.DraftQuery) ([]RoomData, string, error)
+}
+
+// maxDraftLineBytes caps a line of the local drafts file
+const maxDraftLineBytes = 4 << 20
+
+// LocalDraftStore keeps the finished drafts in memory and appends them to a
+// JSON lines file. A room saved again, e.g. when its result is reported, is
+// appended again and the last line wins when the file is loaded.
+type LocalDraftStore struct {
+	mu     sync.Mutex
+	drafts map[string]RoomData
+	path   string // JSON lines file, drafts are only kept in memory if empty
+}
+
+// NewLocalDraftStore loads the drafts file and records the drafts finished
+// by roomService and their results
+func NewLocalDraftStore(path string, roomService *RoomService) (*LocalDraftStore, error) {
+	ls := &LocalDraftStore{
+		drafts: make(map[string]RoomData),
+		path:   path,
+	}
+	if path == "" {
+		log.Println("Warning: DRAFTS_FILE is empty and Firestore is disabled, finished drafts are lost when the server restarts")
+	} else if err := ls.load(); err != nil {
+		return nil, err
+	}
+	roomService.OnDraftFinished(ls.record)
+	roomService.OnGameResult(ls.record)
+	return ls, nil
+}
+
+// load reads the drafts file, which may not exist yet
+func (ls *LocalDraftStore) load() error {
+	file, err := os.Open(ls.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading drafts file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxDraftLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var roomData RoomData
+		if err := json.Unmarshal(scanner.Bytes(), &roomData); err != nil {
+			return fmt.Errorf("error parsing drafts file %s, line %d: %v", ls.path, line, err)
+		}
+		ls.drafts[roomData.Id] = roomData
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading drafts file %s: %v", ls.path, err)
+	}
+	log.Printf("Loaded %d drafts from %s", len(ls.drafts), ls.path)
+	return nil
+}
+
+// record stores a finished room. Failures to write the file are logged: the
+// draft stays in memory until the server restarts.
+func (ls *LocalDraftStore) record(room *models.Room) {
+	if err := ls.SaveRoom(room); err != nil {
+		log.Printf("Error saving draft %s: %v", room.Id, err)
+	}
+}
+
+// SaveRoom keeps the current state of a room and appends it to the file
+func (ls *LocalDraftStore) SaveRoom(room *models.Room) error {
+	roomData := newRoomData(room)
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.drafts[roomData.Id] = roomData
+	if ls.path == "" {
+		return nil
+	}
+	return ls.appendLine(roomData)
+}
+
+// appendLine writes a draft at the end of the file. Must be called with mu held.
+func (ls *LocalDraftStore) appendLine(roomData RoomData) error {
+	data, err := json.Marshal(roomData)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ls.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(ls.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// LoadRoom rebuilds a saved room, to bring it back to memory
+func (ls *LocalDraftStore) LoadRoom(roomId string) (*models.Room, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	roomData, exists := ls.drafts[roomId]
+	if !exists {
+		return nil, fmt.Errorf("room %s not found in the drafts file", roomId)
+	}
+	// A copy, so the loaded room does not share slices and maps with the store
+	data, err := json.Marshal(roomData)
+	if err != nil {
+		return nil, err
+	}
+	var copied RoomData
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	return copied.room(), nil
+}
+
+// FinishedDrafts lists the finished drafts matching filter, oldest first
+func (ls *LocalDraftStore) FinishedDrafts(filter models.DraftFilter) ([]RoomData, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	var drafts []RoomData
+	for _, roomData := range ls.drafts {
+		if matchesDraftFilter(roomData, filter) {
+			drafts = append(drafts, roomData)
+		}
+	}
+	sortDrafts(drafts)
+	return drafts, nil
+}
+
+// FindDraft returns the finished draft of a room, nil if there is none
+func (ls *LocalDraftStore) FindDraft(roomId string) (*RoomData, error) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	roomData, exists := ls.drafts[roomId]
+	if !exists || roomData.CurrentPhase != models.Finished {
+		return nil, nil
+	}
+	return &roomData, nil
+}
+
+// QueryDrafts returns a page of the drafts matching query and the cursor of
+// the next page
+func (ls *LocalDraftStore) QueryDrafts(query models.DraftQuery) ([]RoomData, string, error) {
+	cursor, err := decodeDraftCursor(query.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ls.mu.Lock()
+	var drafts []RoomData
+	for _, roomData := range ls.drafts {
+		if matchesDraftQuery(roomData, query) {
+			drafts = append(drafts, roomData)
+		}
+	}
+	ls.mu.Unlock()
+
+	page, next := pageDrafts(drafts, cursor, query)
+	return page, next, nil
+}
+
+// matchesDraftFilter reports whether a saved room is a finished draft
+// selected by filter
+func matchesDraftFilter(roomData RoomData, filter models.DraftFilter) bool {
+	if roomData.CurrentPhase != models.Finished {
+		return false
+	}
+	if filter.TournamentId != "" && (roomData.Match == nil || roomData.Match.TournamentId != filter.TournamentId) {
+		return false
+	}
+	if filter.Team != "" && draftSide(roomData, filter.Team) == "" {
+		return false
+	}
+	if filter.Patch != "" && roomData.Patch != filter.Patch {
+		return false
+	}
+	if filter.From != 0 && roomData.CompletedAt < filter.From {
+		return false
+	}
+	if filter.To != 0 && roomData.CompletedAt >= filter.To {
+		return false
+	}
+	return true
+}
+
+// draftSide returns the side played by a team in a draft, "" if it did not
+// play it. Team names are compared case-insensitively.
+func draftSide(roomData RoomData, team string) string {
+	switch {
+	case strings.EqualFold(roomData.BlueTeam.Name, team):
+		return models.SeatBlue
+	case strings.EqualFold(roomData.RedTeam.Name, team):
+		return models.SeatRed
+	}
+	return ""
+}
+
+// sortDrafts orders drafts by completion time, then ID
+func sortDrafts(drafts []RoomData) {
+	sort.Slice(drafts, func(i, j int) bool {
+		if drafts[i].CompletedAt != drafts[j].CompletedAt {
+			return drafts[i].CompletedAt < drafts[j].CompletedAt
+		}
+		return drafts[i].Id < drafts[j].Id
+	})
+}
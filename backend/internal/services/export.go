@@ -0,0 +1,366 @@
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"picks3w2a/internal/card"
+	"picks3w2a/internal/models"
+)
+
+// ExportService exports finished drafts and tournament series as JSON, CSV
+// rows and summary cards
+type ExportService struct {
+	store       DraftStore
+	tournaments *TournamentService
+}
+
+// NewExportService creates the export service
+func NewExportService(store DraftStore, tournaments *TournamentService) *ExportService {
+	return &ExportService{store: store, tournaments: tournaments}
+}
+
+// DraftExport exports the finished draft of a room
+func (es *ExportService) DraftExport(roomId string) (models.DraftExport, error) {
+	roomData, err := es.store.FindDraft(roomId)
+	if err != nil {
+		return models.DraftExport{}, err
+	}
+	if roomData == nil {
+		return models.DraftExport{}, models.NewProtocolError(models.ErrRoomNotFound, "room %s has no finished draft", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+	return draftExport(*roomData), nil
+}
+
+// SeriesExport exports the finished games of a tournament match. Games whose
+// draft is missing from the store are left out.
+func (es *ExportService) SeriesExport(tournamentId, matchId string) (models.SeriesExport, error) {
+	tournament, err := es.tournaments.Tournament(tournamentId)
+	if err != nil {
+		return models.SeriesExport{}, err
+	}
+	match, err := findMatch(&tournament, matchId)
+	if err != nil {
+		return models.SeriesExport{}, err
+	}
+
+	teamName := func(teamId string) string {
+		if team := findTeam(&tournament, teamId); team != nil {
+			return team.Name
+		}
+		return teamId
+	}
+	export := models.SeriesExport{
+		SchemaVersion: models.ExportSchemaVersion,
+		TournamentId:  tournament.Id,
+		Tournament:    tournament.Name,
+		MatchId:       match.Id,
+		BestOf:        match.BestOf,
+		Fearless:      match.Fearless,
+		BlueTeam:      teamName(match.BlueTeamId),
+		RedTeam:       teamName(match.RedTeamId),
+		Games:         []models.DraftExport{},
+	}
+	if match.Winner != "" {
+		export.Winner = teamName(match.Winner)
+	}
+	for _, game := range match.Games {
+		if game.Status != models.GameFinished {
+			continue
+		}
+		switch game.Winner {
+		case "":
+		case match.BlueTeamId:
+			export.BlueWins++
+		default:
+			export.RedWins++
+		}
+		roomData, err := es.store.FindDraft(game.RoomId)
+		if err != nil {
+			return models.SeriesExport{}, err
+		}
+		if roomData != nil {
+			export.Games = append(export.Games, draftExport(*roomData))
+		}
+	}
+	return export, nil
+}
+
+// draftExport converts a saved draft to its canonical export
+func draftExport(roomData RoomData) models.DraftExport {
+	export := models.DraftExport{
+		SchemaVersion: models.ExportSchemaVersion,
+		RoomId:        roomData.Id,
+		Patch:         roomData.Patch,
+		Match:         roomData.Match,
+		CompletedAt:   roomData.CompletedAt,
+		Result:        roomData.Result,
+		Imported:      roomData.Imported,
+		Times:         roomData.draftTimes(),
+		BlueTeam:      exportTeam(roomData.BlueTeam),
+		RedTeam:       exportTeam(roomData.RedTeam),
+		FearlessBans:  lockedNames(roomData.FearlessBans),
+		Actions:       []models.DraftAction{},
+	}
+
+	for i, phase := range models.DraftSequence(roomData.BlueTeamHasBans || roomData.RedTeamHasBans) {
+		side, kind, index := phaseSlot(phase)
+		team := roomData.BlueTeam
+		if side == models.SeatRed {
+			team = roomData.RedTeam
+		}
+		action := models.DraftAction{Seq: i + 1, Phase: phase, Side: side, Team: team.Name, Kind: kind}
+		var champion models.Champion
+		if kind == models.ActionBan {
+			if index < len(team.Bans) {
+				champion = team.Bans[index]
+			}
+		} else {
+			champion = pickOfTurn(team, models.PickTurn(phase), index)
+		}
+		if champion.Name != "-1" && champion.Name != "" {
+			action.Champion = champion.Name
+			action.Player = champion.Player
+		}
+		action.LockedAt, action.TimeUsed, action.TimedOut = champion.LockedAt, champion.TimeUsed, champion.TimedOut
+		export.Actions = append(export.Actions, action)
+	}
+	return export
+}
+
+// exportTeam converts a team of a saved draft, skipping empty slots
+func exportTeam(team models.Team) models.ExportTeam {
+	export := models.ExportTeam{
+		Name:   team.Name,
+		Roster: team.Roster,
+		Bans:   lockedNames(team.Bans),
+		Picks:  []models.ExportPick{},
+	}
+	for _, pick := range team.Picks {
+		if pick.Name == "-1" || pick.Name == "" {
+			continue
+		}
+		export.Picks = append(export.Picks, models.ExportPick{
+			Champion: pick.Name,
+			Turn:     pick.Turn,
+			Player:   pick.Player,
+			PlayerId: pick.PlayerId,
+		})
+	}
+	return export
+}
+
+// phaseSlot returns the side, the kind of action and the index in the bans
+// or picks of the team of a draft phase
+func phaseSlot(phase models.Phase) (side, kind string, index int) {
+	for _, slots := range []struct {
+		phases []models.Phase
+		side   string
+		kind   string
+	}{
+		{blueBanPhases, models.SeatBlue, models.ActionBan},
+		{redBanPhases, models.SeatRed, models.ActionBan},
+		{bluePickPhases, models.SeatBlue, models.ActionPick},
+		{redPickPhases, models.SeatRed, models.ActionPick},
+	} {
+		for i, p := range slots.phases {
+			if p == phase {
+				return slots.side, slots.kind, i
+			}
+		}
+	}
+	return "", "", -1
+}
+
+// pickOfTurn finds the pick locked at a turn of the draft, which trades may
+// have moved; drafts saved before turns were recorded use its position
+func pickOfTurn(team models.Team, turn, index int) models.Champion {
+	for _, pick := range team.Picks {
+		if pick.Turn == turn {
+			return pick
+		}
+	}
+	if index < len(team.Picks) && team.Picks[index].Turn == 0 {
+		return team.Picks[index]
+	}
+	return models.Champion{}
+}
+
+// DraftCSVHeader is the header of the CSV export, one row per action.
+// locked_at and time_used are in milliseconds, empty if not recorded.
+var DraftCSVHeader = []string{"room_id", "game", "seq", "phase", "side", "team", "kind", "champion", "player", "locked_at", "time_used", "timed_out"}
+
+// draftCSVOptional are the columns of DraftCSVHeader an imported file may
+// leave out, added after the first version of the format
+var draftCSVOptional = map[string]bool{"locked_at": true, "time_used": true, "timed_out": true}
+
+// DraftCSVRows converts the actions of drafts to CSV rows
+func DraftCSVRows(drafts ...models.DraftExport) [][]string {
+	var rows [][]string
+	for _, draft := range drafts {
+		game := ""
+		if draft.Match != nil {
+			game = strconv.Itoa(draft.Match.Game)
+		}
+		for _, action := range draft.Actions {
+			lockedAt, timeUsed, timedOut := "", "", ""
+			if action.LockedAt != 0 {
+				lockedAt = strconv.FormatInt(action.LockedAt, 10)
+			}
+			if action.TimeUsed != 0 {
+				timeUsed = strconv.Itoa(action.TimeUsed)
+			}
+			if action.TimedOut {
+				timedOut = "true"
+			}
+			rows = append(rows, []string{
+				draft.RoomId, game, strconv.Itoa(action.Seq), string(action.Phase), action.Side,
+				action.Team, action.Kind, action.Champion, action.Player, lockedAt, timeUsed, timedOut,
+			})
+		}
+	}
+	return rows
+}
+
+// Colors of the summary cards
+var (
+	cardBackground = card.Hex("#10141c")
+	cardBluePanel  = card.Hex("#16324f")
+	cardBlue       = card.Hex("#3b82f6")
+	cardRedPanel   = card.Hex("#4a1d24")
+	cardRed        = card.Hex("#ef4444")
+	cardText       = card.Hex("#f8fafc")
+	cardMuted      = card.Hex("#94a3b8")
+	cardWinner     = card.Hex("#facc15")
+)
+
+// DraftCard lays out the summary card of a draft: both teams with their
+// picks, players and bans, the winner and the match it belongs to
+func DraftCard(draft models.DraftExport) card.Card {
+	c := card.Card{Width: 960, Height: 430, Background: cardBackground}
+
+	title := "DRAFT " + draft.RoomId
+	if draft.Match != nil {
+		title = fmt.Sprintf("%s - %s - GAME %d", draft.Match.TournamentId, draft.Match.MatchId, draft.Match.Game)
+	}
+	c.Texts = append(c.Texts, card.Text{X: 32, Y: 28, Scale: 3, Color: cardText, Value: card.Fit(title, 40)})
+	if draft.Patch != "" {
+		c.Texts = append(c.Texts, card.Text{X: 928, Y: 32, Scale: 2, Align: card.AlignRight, Color: cardMuted, Value: card.Fit("PATCH "+draft.Patch, 20)})
+	}
+
+	winner := ""
+	if draft.Result != nil {
+		winner = draft.Result.Winner
+	}
+	draftPanel(&c, 32, draft.BlueTeam, winner == models.SeatBlue, cardBluePanel, cardBlue)
+	draftPanel(&c, 492, draft.RedTeam, winner == models.SeatRed, cardRedPanel, cardRed)
+
+	var footer []string
+	if len(draft.FearlessBans) > 0 {
+		footer = append(footer, fmt.Sprintf("FEARLESS BANS: %d", len(draft.FearlessBans)))
+	}
+	if draft.Result != nil && draft.Result.Duration > 0 {
+		footer = append(footer, fmt.Sprintf("GAME TIME %d:%02d", draft.Result.Duration/60, draft.Result.Duration%60))
+	}
+	if draft.CompletedAt > 0 {
+		footer = append(footer, time.Unix(draft.CompletedAt, 0).UTC().Format("2006-01-02"))
+	}
+	c.Texts = append(c.Texts, card.Text{X: 32, Y: 396, Scale: 2, Color: cardMuted, Value: card.Fit(strings.Join(footer, "  |  "), 74)})
+	return c
+}
+
+// draftPanel lays out the panel of a team on a draft card
+func draftPanel(c *card.Card, x int, team models.ExportTeam, won bool, fill, edge color.RGBA) {
+	const y, w, h = 76, 436, 292
+	c.Rects = append(c.Rects,
+		card.Rect{X: x, Y: y, W: w, H: h, Fill: fill},
+		card.Rect{X: x, Y: y, W: w, H: 6, Fill: edge},
+	)
+	c.Texts = append(c.Texts, card.Text{X: x + 16, Y: y + 22, Scale: 3, Color: cardText, Value: card.Fit(team.Name, 18)})
+	if won {
+		c.Texts = append(c.Texts, card.Text{X: x + w - 16, Y: y + 22, Scale: 3, Align: card.AlignRight, Color: cardWinner, Value: "WIN"})
+	}
+
+	c.Texts = append(c.Texts, card.Text{X: x + 16, Y: y + 64, Scale: 2, Color: cardMuted, Value: "PICKS"})
+	for i, pick := range team.Picks {
+		row := y + 90 + i*26
+		c.Texts = append(c.Texts, card.Text{X: x + 16, Y: row, Scale: 2, Color: cardText, Value: card.Fit(pick.Champion, 17)})
+		if pick.Player != "" {
+			c.Texts = append(c.Texts, card.Text{X: x + w - 16, Y: row, Scale: 2, Align: card.AlignRight, Color: cardMuted, Value: card.Fit(pick.Player, 14)})
+		}
+	}
+
+	bans := "-"
+	if len(team.Bans) > 0 {
+		bans = strings.Join(team.Bans, ", ")
+	}
+	c.Texts = append(c.Texts,
+		card.Text{X: x + 16, Y: y + 232, Scale: 2, Color: cardMuted, Value: "BANS"},
+		card.Text{X: x + 16, Y: y + 258, Scale: 2, Color: cardText, Value: card.Fit(bans, 33)},
+	)
+}
+
+// SeriesCard lays out the summary card of a series: the score and a row per
+// finished game with its sides and winner
+func SeriesCard(series models.SeriesExport) card.Card {
+	c := card.Card{Width: 960, Height: 200 + 40*len(series.Games), Background: cardBackground}
+
+	subtitle := fmt.Sprintf("%s - BO%d", series.MatchId, series.BestOf)
+	if series.Fearless {
+		subtitle += " - FEARLESS"
+	}
+	c.Texts = append(c.Texts,
+		card.Text{X: 32, Y: 28, Scale: 3, Color: cardText, Value: card.Fit(series.Tournament, 49)},
+		card.Text{X: 928, Y: 32, Scale: 2, Align: card.AlignRight, Color: cardMuted, Value: card.Fit(subtitle, 30)},
+	)
+
+	c.Rects = append(c.Rects,
+		card.Rect{X: 32, Y: 76, W: 436, H: 72, Fill: cardBluePanel},
+		card.Rect{X: 492, Y: 76, W: 436, H: 72, Fill: cardRedPanel},
+	)
+	blueColor, redColor := cardText, cardText
+	switch series.Winner {
+	case "":
+	case series.BlueTeam:
+		blueColor = cardWinner
+	default:
+		redColor = cardWinner
+	}
+	c.Texts = append(c.Texts,
+		card.Text{X: 48, Y: 102, Scale: 3, Color: blueColor, Value: card.Fit(series.BlueTeam, 17)},
+		card.Text{X: 912, Y: 102, Scale: 3, Align: card.AlignRight, Color: redColor, Value: card.Fit(series.RedTeam, 17)},
+		card.Text{X: 452, Y: 98, Scale: 4, Align: card.AlignRight, Color: cardText, Value: strconv.Itoa(series.BlueWins)},
+		card.Text{X: 508, Y: 98, Scale: 4, Color: cardText, Value: strconv.Itoa(series.RedWins)},
+	)
+
+	for i, game := range series.Games {
+		row := 176 + i*40
+		number := i + 1
+		if game.Match != nil {
+			number = game.Match.Game
+		}
+		winner := ""
+		if game.Result != nil {
+			switch game.Result.Winner {
+			case models.SeatBlue:
+				winner = game.BlueTeam.Name
+			case models.SeatRed:
+				winner = game.RedTeam.Name
+			}
+		}
+		c.Texts = append(c.Texts,
+			card.Text{X: 32, Y: row, Scale: 2, Color: cardMuted, Value: fmt.Sprintf("GAME %d", number)},
+			card.Text{X: 160, Y: row, Scale: 2, Color: cardBlue, Value: card.Fit(game.BlueTeam.Name, 16)},
+			card.Text{X: 372, Y: row, Scale: 2, Color: cardMuted, Value: "VS"},
+			card.Text{X: 412, Y: row, Scale: 2, Color: cardRed, Value: card.Fit(game.RedTeam.Name, 16)},
+		)
+		if winner != "" {
+			c.Texts = append(c.Texts, card.Text{X: 928, Y: row, Scale: 2, Align: card.AlignRight, Color: cardWinner, Value: card.Fit("WIN: "+winner, 16)})
+		}
+	}
+	return c
+}
@@ -11,7 +11,10 @@ import (
 	firebase "firebase.google.com/go/v4"
 	"cloud.google.com/go/firestore"
 	"github.com/gorilla/websocket"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FirebaseService struct {
@@ -28,12 +31,43 @@ type RoomData struct {
 	RedTeamHasBans  bool               `json:"red_team_has_bans"`
 	TimePerPick     int                `json:"time_per_pick"`
 	TimePerBan      int                `json:"time_per_ban"`
+	TradePhase      bool               `json:"trade_phase,omitempty"`
+	Patch           string             `json:"patch,omitempty"`
 	CurrentPhase    models.Phase       `json:"current_phase"`
+	SideChooser     string             `json:"side_chooser,omitempty"`
+	SidesSwapped    bool               `json:"sides_swapped,omitempty"`
+	CoinFlip        *models.CoinFlip   `json:"coin_flip,omitempty"`
+	Match           *models.MatchRef   `json:"match,omitempty"`
+	Result          *models.GameResult `json:"result,omitempty"`
 	BlueTeam        models.Team        `json:"blue_team"`
 	RedTeam         models.Team        `json:"red_team"`
 	FearlessBans    []models.Champion  `json:"fearless_bans"`
+	History         []models.HistoryEntry `json:"history,omitempty"`
+	Imported        bool               `json:"imported,omitempty"`
+	Times           models.DraftTimes  `json:"times"`
 	CreatedAt       int64              `json:"created_at"`
 	CompletedAt     int64              `json:"completed_at,omitempty"`
+	SeatKeys        *SeatKeys          `json:"seat_keys,omitempty"`
+}
+
+// SeatKeys are the key hashes of a tournament game, saved until its result is
+// reported so that the captains and the referee can still report it once the
+// room has been evicted or the server restarted
+type SeatKeys struct {
+	Blue        string         `json:"blue"`
+	Red         string         `json:"red"`
+	Referee     string         `json:"referee"`
+	Generations map[string]int `json:"generations,omitempty"`
+}
+
+// draftTimes returns the times of a saved draft, nil if it was saved before
+// they were recorded
+func (roomData RoomData) draftTimes() *models.DraftTimes {
+	if roomData.Times.CreatedAt == 0 {
+		return nil
+	}
+	times := roomData.Times
+	return &times
 }
 
 // NewFirebaseService creates a new Firebase service instance
@@ -82,8 +116,42 @@ func (fs *FirebaseService) SaveRoom(room *models.Room) error {
 	
 	log.Printf("Attempting to save room %s to Firebase", room.Id)
 
-	// Convert room to RoomData for Firebase storage
-	roomData := RoomData{
+	roomData := newRoomData(room)
+
+	// Save to Firestore under collection "rooms" with document ID = roomId
+	log.Printf("Saving to Firestore collection: rooms, document: %s", room.Id)
+	
+	_, err := fs.client.Collection("rooms").Doc(room.Id).Set(fs.ctx, roomData)
+	if err != nil {
+		log.Printf("Error saving room to Firestore: %v", err)
+		return fmt.Errorf("error saving room to Firestore: %v", err)
+	}
+
+	log.Printf("Room %s saved to Firestore successfully", room.Id)
+	return nil
+}
+
+// newRoomData converts a room to the document saved for it
+func newRoomData(room *models.Room) RoomData {
+	completedAt := getCurrentTimestamp()
+	if room.Times.FinishedAt != 0 {
+		completedAt = room.Times.FinishedAt / 1000
+	}
+	// Las rooms anteriores a DraftTimes no tienen hora de creación
+	createdAt := completedAt
+	if room.Times.CreatedAt != 0 {
+		createdAt = room.Times.CreatedAt / 1000
+	}
+	var seatKeys *SeatKeys
+	if room.Match != nil && room.Result == nil {
+		seatKeys = &SeatKeys{
+			Blue:        room.BlueTeamKeyHash,
+			Red:         room.RedTeamKeyHash,
+			Referee:     room.RefereeKeyHash,
+			Generations: room.SeatGenerations,
+		}
+	}
+	return RoomData{
 		Id:              room.Id,
 		BlueTeamName:    room.BlueTeamName,
 		RedTeamName:     room.RedTeamName,
@@ -91,25 +159,68 @@ func (fs *FirebaseService) SaveRoom(room *models.Room) error {
 		RedTeamHasBans:  room.RedTeamHasBans,
 		TimePerPick:     room.TimePerPick,
 		TimePerBan:      room.TimePerBan,
+		TradePhase:      room.TradePhase,
+		Patch:           room.Patch,
 		CurrentPhase:    room.CurrentPhase,
+		SideChooser:     room.SideChooser,
+		SidesSwapped:    room.SidesSwapped,
+		CoinFlip:        room.CoinFlip,
+		Match:           room.Match,
+		Result:          room.Result,
 		BlueTeam:        room.BlueTeam,
 		RedTeam:         room.RedTeam,
 		FearlessBans:    room.FearlessBans,
-		CreatedAt:       getCurrentTimestamp(), // Timestamp de cuando se creó la room
-		CompletedAt:     getCurrentTimestamp(), // Timestamp de cuando se completó
+		History:         room.History,
+		Imported:        room.Imported,
+		Times:           room.Times,
+		CreatedAt:       createdAt, // Timestamp de cuando se creó la room
+		CompletedAt:     completedAt, // Timestamp de cuando se completó
+		SeatKeys:        seatKeys,
 	}
+}
 
-	// Save to Firestore under collection "rooms" with document ID = roomId
-	log.Printf("Saving to Firestore collection: rooms, document: %s", room.Id)
-	
-	_, err := fs.client.Collection("rooms").Doc(room.Id).Set(fs.ctx, roomData)
-	if err != nil {
-		log.Printf("Error saving room to Firestore: %v", err)
-		return fmt.Errorf("error saving room to Firestore: %v", err)
+// room converts saved data back to a room, without clients nor timer
+func (roomData RoomData) room() *models.Room {
+	room := &models.Room{
+		Id:              roomData.Id,
+		BlueTeamName:    roomData.BlueTeamName,
+		RedTeamName:     roomData.RedTeamName,
+		BlueTeamHasBans: roomData.BlueTeamHasBans,
+		RedTeamHasBans:  roomData.RedTeamHasBans,
+		TimePerPick:     roomData.TimePerPick,
+		TimePerBan:      roomData.TimePerBan,
+		TradePhase:      roomData.TradePhase,
+		Patch:           roomData.Patch,
+		CurrentPhase:    roomData.CurrentPhase,
+		SideChooser:     roomData.SideChooser,
+		SidesSwapped:    roomData.SidesSwapped,
+		CoinFlip:        roomData.CoinFlip,
+		Match:           roomData.Match,
+		Result:          roomData.Result,
+		BlueTeam:        roomData.BlueTeam,
+		RedTeam:         roomData.RedTeam,
+		FearlessBans:    roomData.FearlessBans,
+		History:         roomData.History,
+		Imported:        roomData.Imported,
+		Times:           roomData.Times,
+		Clients:         make(map[*websocket.Conn]*models.Client), // Empty clients map
+		TimeRemaining:   0,
+		TimerActive:     false,
+		TimerCancel:     make(chan bool, 1),
 	}
 
-	log.Printf("Room %s saved to Firestore successfully", room.Id)
-	return nil
+	// Conserva la fecha de los drafts guardados antes de DraftTimes
+	if room.Times.FinishedAt == 0 {
+		room.Times.FinishedAt = roomData.CompletedAt * 1000
+	}
+	// Solo se guardan las claves de las partidas de torneo sin resultado
+	if roomData.SeatKeys != nil {
+		room.BlueTeamKeyHash = roomData.SeatKeys.Blue
+		room.RedTeamKeyHash = roomData.SeatKeys.Red
+		room.RefereeKeyHash = roomData.SeatKeys.Referee
+		room.SeatGenerations = roomData.SeatKeys.Generations
+	}
+	return room
 }
 
 // LoadRoom loads a room from Firestore
@@ -136,31 +247,138 @@ func (fs *FirebaseService) LoadRoom(roomId string) (*models.Room, error) {
 		return nil, fmt.Errorf("error parsing room data from Firestore: %v", err)
 	}
 
-	// Convert RoomData back to Room
-	room := &models.Room{
-		Id:              roomData.Id,
-		RedTeamKey:      "", // Keys are not stored for security
-		BlueTeamKey:     "", // Keys are not stored for security
-		BlueTeamName:    roomData.BlueTeamName,
-		RedTeamName:     roomData.RedTeamName,
-		BlueTeamHasBans: roomData.BlueTeamHasBans,
-		RedTeamHasBans:  roomData.RedTeamHasBans,
-		TimePerPick:     roomData.TimePerPick,
-		TimePerBan:      roomData.TimePerBan,
-		CurrentPhase:    roomData.CurrentPhase,
-		BlueTeam:        roomData.BlueTeam,
-		RedTeam:         roomData.RedTeam,
-		FearlessBans:    roomData.FearlessBans,
-		Clients:         make(map[*websocket.Conn]*models.Client), // Empty clients map
-		TimeRemaining:   0,
-		TimerActive:     false,
-		TimerCancel:     make(chan bool, 1),
-	}
-
+	room := roomData.room()
 	log.Printf("Room %s loaded from Firestore successfully", roomId)
 	return room, nil
 }
 
+// FinishedDrafts lists the finished drafts matching filter, oldest first.
+// Only equality filters are sent to Firestore, as they need no composite
+// index; the date range and the team are applied to the results.
+func (fs *FirebaseService) FinishedDrafts(filter models.DraftFilter) ([]RoomData, error) {
+	if fs == nil || fs.client == nil {
+		return nil, fmt.Errorf("Firestore not configured")
+	}
+
+	query := fs.client.Collection("rooms").Where("CurrentPhase", "==", string(models.Finished))
+	if filter.TournamentId != "" {
+		query = query.Where("Match.TournamentId", "==", filter.TournamentId)
+	}
+	if filter.Patch != "" {
+		query = query.Where("Patch", "==", filter.Patch)
+	}
+
+	var drafts []RoomData
+	iter := query.Documents(fs.ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error querying rooms in Firestore: %v", err)
+		}
+		var roomData RoomData
+		if err := doc.DataTo(&roomData); err != nil {
+			log.Printf("Skipping room %s, error parsing data from Firestore: %v", doc.Ref.ID, err)
+			continue
+		}
+		if matchesDraftFilter(roomData, filter) {
+			drafts = append(drafts, roomData)
+		}
+	}
+	sortDrafts(drafts)
+	return drafts, nil
+}
+
+// QueryDrafts returns a page of the drafts matching query and the cursor of
+// the next page. The order, the date range and the equality filters are sent
+// to Firestore, which needs a composite index on CurrentPhase, the filtered
+// fields and CompletedAt; the team and the champion are applied to the
+// results, reading on until the page is full.
+func (fs *FirebaseService) QueryDrafts(query models.DraftQuery) ([]RoomData, string, error) {
+	if fs == nil || fs.client == nil {
+		return nil, "", fmt.Errorf("Firestore not configured")
+	}
+	cursor, err := decodeDraftCursor(query.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := fs.client.Collection("rooms").Where("CurrentPhase", "==", string(models.Finished))
+	if query.TournamentId != "" {
+		q = q.Where("Match.TournamentId", "==", query.TournamentId)
+	}
+	if query.Patch != "" {
+		q = q.Where("Patch", "==", query.Patch)
+	}
+	if query.From != 0 {
+		q = q.Where("CompletedAt", ">=", query.From)
+	}
+	if query.To != 0 {
+		q = q.Where("CompletedAt", "<", query.To)
+	}
+	direction := firestore.Desc
+	if query.Sort == models.SortOldest {
+		direction = firestore.Asc
+	}
+	q = q.OrderBy("CompletedAt", direction).OrderBy(firestore.DocumentID, direction)
+	if cursor != nil {
+		q = q.StartAfter(cursor.CompletedAt, cursor.Id)
+	}
+
+	var drafts []RoomData
+	iter := q.Documents(fs.ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("error querying rooms in Firestore: %v", err)
+		}
+		var roomData RoomData
+		if err := doc.DataTo(&roomData); err != nil {
+			log.Printf("Skipping room %s, error parsing data from Firestore: %v", doc.Ref.ID, err)
+			continue
+		}
+		if !matchesDraftQuery(roomData, query) {
+			continue
+		}
+		if len(drafts) == query.Limit {
+			return drafts, encodeDraftCursor(drafts[len(drafts)-1]), nil
+		}
+		drafts = append(drafts, roomData)
+	}
+	return drafts, "", nil
+}
+
+// FindDraft returns the finished draft of a room, nil if there is none
+func (fs *FirebaseService) FindDraft(roomId string) (*RoomData, error) {
+	if fs == nil || fs.client == nil {
+		return nil, fmt.Errorf("Firestore not configured")
+	}
+
+	doc, err := fs.client.Collection("rooms").Doc(roomId).Get(fs.ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading room from Firestore: %v", err)
+	}
+
+	var roomData RoomData
+	if err := doc.DataTo(&roomData); err != nil {
+		return nil, fmt.Errorf("error parsing room data from Firestore: %v", err)
+	}
+	if roomData.CurrentPhase != models.Finished {
+		return nil, nil
+	}
+	return &roomData, nil
+}
+
 // RoomExists checks if a room exists in Firestore
 func (fs *FirebaseService) RoomExists(roomId string) (bool, error) {
 	if fs == nil || fs.client == nil {
@@ -168,6 +386,9 @@ func (fs *FirebaseService) RoomExists(roomId string) (bool, error) {
 	}
 
 	doc, err := fs.client.Collection("rooms").Doc(roomId).Get(fs.ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
 	if err != nil {
 		return false, fmt.Errorf("error checking room existence: %v", err)
 	}
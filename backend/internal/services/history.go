@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"picks3w2a/internal/models"
+)
+
+// HistoryService browses the finished drafts, a page at a time
+type HistoryService struct {
+	store   DraftStore
+	catalog *ChampionCatalog
+}
+
+// NewHistoryService creates the history service. The catalog, which may be
+// nil, lets queries name champions by ID or name instead of key.
+func NewHistoryService(store DraftStore, catalog *ChampionCatalog) *HistoryService {
+	return &HistoryService{store: store, catalog: catalog}
+}
+
+// Drafts returns a page of the finished drafts matching query
+func (hs *HistoryService) Drafts(query models.DraftQuery) (models.HistoryPage, error) {
+	if query.Champion != "" {
+		key, exists := hs.catalog.Resolve(query.Champion)
+		if !exists {
+			return models.HistoryPage{}, models.NewProtocolError(models.ErrUnknownChampion, "champion %s is not in the champion catalog", query.Champion).
+				WithDetail(models.DetailChampion, query.Champion)
+		}
+		query.Champion = key
+	}
+	if query.Sort == "" {
+		query.Sort = models.SortNewest
+	}
+	if query.Limit == 0 {
+		query.Limit = models.DefaultHistoryLimit
+	}
+
+	drafts, next, err := hs.store.QueryDrafts(query)
+	if err != nil {
+		return models.HistoryPage{}, err
+	}
+	page := models.HistoryPage{Drafts: make([]models.DraftExport, 0, len(drafts)), NextCursor: next}
+	for _, roomData := range drafts {
+		page.Drafts = append(page.Drafts, draftExport(roomData))
+	}
+	return page, nil
+}
+
+// draftCursor is the position of the last draft of a page, in the order of
+// completion time then room ID that every store sorts by
+type draftCursor struct {
+	CompletedAt int64
+	Id          string
+}
+
+// encodeDraftCursor returns the opaque cursor of the page after a draft
+func encodeDraftCursor(roomData RoomData) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(roomData.CompletedAt, 10) + ":" + roomData.Id))
+}
+
+// decodeDraftCursor parses a cursor, nil if it is empty
+func decodeDraftCursor(cursor string) (*draftCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	invalid := models.NewProtocolError(models.ErrValidation, "cursor: is not a cursor returned by this API").
+		WithDetail(models.DetailField, "cursor")
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	completedAt, id, found := strings.Cut(string(data), ":")
+	if !found {
+		return nil, invalid
+	}
+	at, err := strconv.ParseInt(completedAt, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	return &draftCursor{CompletedAt: at, Id: id}, nil
+}
+
+// matchesDraftQuery reports whether a saved room is a finished draft
+// selected by query, cursor aside
+func matchesDraftQuery(roomData RoomData, query models.DraftQuery) bool {
+	if !matchesDraftFilter(roomData, query.DraftFilter) {
+		return false
+	}
+	if query.Champion == "" {
+		return true
+	}
+	for _, team := range []models.Team{roomData.BlueTeam, roomData.RedTeam} {
+		if containsChampion(lockedNames(team.Picks), query.Champion) || containsChampion(lockedNames(team.Bans), query.Champion) {
+			return true
+		}
+	}
+	return false
+}
+
+// afterCursor reports whether a draft comes after the cursor in the order
+// of query
+func afterCursor(roomData RoomData, cursor *draftCursor, order string) bool {
+	if cursor == nil {
+		return true
+	}
+	if order == models.SortOldest {
+		return roomData.CompletedAt > cursor.CompletedAt || roomData.CompletedAt == cursor.CompletedAt && roomData.Id > cursor.Id
+	}
+	return roomData.CompletedAt < cursor.CompletedAt || roomData.CompletedAt == cursor.CompletedAt && roomData.Id < cursor.Id
+}
+
+// pageDrafts cuts the drafts after the cursor, in order, to a page of
+// limit and returns the cursor of the next page, empty on the last one
+func pageDrafts(drafts []RoomData, cursor *draftCursor, query models.DraftQuery) ([]RoomData, string) {
+	sortDrafts(drafts)
+	if query.Sort != models.SortOldest {
+		for i, j := 0, len(drafts)-1; i < j; i, j = i+1, j-1 {
+			drafts[i], drafts[j] = drafts[j], drafts[i]
+		}
+	}
+	page := make([]RoomData, 0, query.Limit)
+	for _, roomData := range drafts {
+		if !afterCursor(roomData, cursor, query.Sort) {
+			continue
+		}
+		if len(page) == query.Limit {
+			return page, encodeDraftCursor(page[len(page)-1])
+		}
+		page = append(page, roomData)
+	}
+	return page, ""
+}
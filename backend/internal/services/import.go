@@ -0,0 +1,585 @@
+package services
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// ResultByImport is the SubmittedBy of the result of an imported draft
+const ResultByImport = "import"
+
+// ImportService stores drafts played outside the tool, e.g. in the game
+// client during an outage, as finished rooms flagged as imported. Drafts of
+// tournament games are linked to the next game of their match like drafted
+// games, so they count for the standings and the fearless bans.
+type ImportService struct {
+	rooms       *RoomService
+	store       DraftStore
+	tournaments *TournamentService
+	catalog     *ChampionCatalog
+}
+
+// NewImportService creates the import service. A nil catalog accepts every
+// champion.
+func NewImportService(rooms *RoomService, store DraftStore, tournaments *TournamentService, catalog *ChampionCatalog) *ImportService {
+	return &ImportService{rooms: rooms, store: store, tournaments: tournaments, catalog: catalog}
+}
+
+// ImportDraft checks a draft against the draft order and the champion
+// catalog, stores it and returns it as exported. The draft must be valid
+// (see DraftExport.Validate). Tournament games are named like drafted games
+// and take their fearless bans from the match, whatever the draft says.
+func (is *ImportService) ImportDraft(draft models.DraftExport) (models.DraftExport, error) {
+	room, err := is.newImportedRoom(draft)
+	if err != nil {
+		return models.DraftExport{}, err
+	}
+
+	if draft.Match != nil {
+		if err := is.tournaments.reserveImportedGame(room, *draft.Match); err != nil {
+			return models.DraftExport{}, err
+		}
+	} else {
+		room.Id = draft.RoomId
+		if room.Id == "" {
+			room.Id = "import-" + is.rooms.generateRandomID()
+		}
+		if err := checkImportedChampions(room); err != nil {
+			return models.DraftExport{}, err
+		}
+	}
+
+	if room.Patch == "" {
+		room.Patch = is.rooms.gamePatch
+	}
+
+	if err := is.save(room); err != nil {
+		if room.Match != nil {
+			is.tournaments.releaseImportedGame(room)
+		}
+		return models.DraftExport{}, err
+	}
+	return draftExport(newRoomData(room)), nil
+}
+
+// save stores an imported room unless a draft with its ID exists
+func (is *ImportService) save(room *models.Room) error {
+	stored, err := is.store.FindDraft(room.Id)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		return is.rooms.roomExistsError(room.Id)
+	}
+	return is.rooms.importRoom(room)
+}
+
+// ParseDraftCSV reads a draft in the CSV export format, one row per action
+// with the DraftCSVHeader columns in any order. The rows must belong to a
+// single room; the game column sets the game of Match when it is given.
+func ParseDraftCSV(r io.Reader) (models.DraftExport, error) {
+	draft := models.DraftExport{SchemaVersion: models.ExportSchemaVersion}
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return draft, csvError("header", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range DraftCSVHeader {
+		if _, exists := columns[name]; !exists && !draftCSVOptional[name] {
+			return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: missing column %s", name)
+		}
+	}
+
+	game := ""
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return draft, csvError("line "+strconv.Itoa(line), err)
+		}
+		field := func(name string) string {
+			column, exists := columns[name]
+			if !exists {
+				return ""
+			}
+			return strings.TrimSpace(row[column])
+		}
+		if line == 2 {
+			draft.RoomId, game = field("room_id"), field("game")
+		} else if field("room_id") != draft.RoomId {
+			return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: line %d belongs to room %s, a file holds a single draft", line, field("room_id"))
+		}
+		seq, err := strconv.Atoi(field("seq"))
+		if err != nil {
+			return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: line %d: seq must be a number", line)
+		}
+		action := models.DraftAction{
+			Seq:      seq,
+			Phase:    models.Phase(field("phase")),
+			Side:     field("side"),
+			Team:     field("team"),
+			Kind:     field("kind"),
+			Champion: field("champion"),
+			Player:   field("player"),
+		}
+		if value := field("locked_at"); value != "" {
+			if action.LockedAt, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: line %d: locked_at must be a number", line)
+			}
+		}
+		if value := field("time_used"); value != "" {
+			if action.TimeUsed, err = strconv.Atoi(value); err != nil {
+				return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: line %d: time_used must be a number", line)
+			}
+		}
+		if value := field("timed_out"); value != "" {
+			if action.TimedOut, err = strconv.ParseBool(value); err != nil {
+				return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: line %d: timed_out must be true or false", line)
+			}
+		}
+		draft.Actions = append(draft.Actions, action)
+	}
+	if game != "" {
+		number, err := strconv.Atoi(game)
+		if err != nil {
+			return draft, models.NewProtocolError(models.ErrInvalidMessage, "csv: game must be a number")
+		}
+		draft.Match = &models.MatchRef{Game: number}
+	}
+	return draft, nil
+}
+
+// csvError reports a CSV file that cannot be read
+func csvError(where string, err error) error {
+	return models.NewProtocolError(models.ErrInvalidMessage, "csv: %s: %v", where, err)
+}
+
+// newImportedRoom builds the finished room of a draft, with its champions
+// resolved to catalog keys
+func (is *ImportService) newImportedRoom(draft models.DraftExport) (*models.Room, error) {
+	completedAt := time.Now().Unix()
+	if draft.CompletedAt > completedAt {
+		return nil, models.NewProtocolError(models.ErrValidation, "completed_at: must not be in the future").
+			WithDetail(models.DetailField, "completed_at")
+	}
+	if draft.CompletedAt != 0 {
+		completedAt = draft.CompletedAt
+	}
+
+	room := &models.Room{
+		CurrentPhase: models.Finished,
+		Patch:        draft.Patch,
+		Imported:     true,
+		Times:        importedTimes(draft.Times, completedAt),
+		BlueTeam:     is.rooms.importedTeam(draft.BlueTeam),
+		RedTeam:      is.rooms.importedTeam(draft.RedTeam),
+		FearlessBans: []models.Champion{},
+	}
+	for _, name := range draft.FearlessBans {
+		key, err := is.champion(name)
+		if err != nil {
+			return nil, err
+		}
+		room.FearlessBans = append(room.FearlessBans, models.Champion{Name: key})
+	}
+
+	var err error
+	if len(draft.Actions) > 0 {
+		err = is.fillFromActions(room, draft.Actions)
+	} else {
+		err = is.fillFromTeams(room, draft)
+	}
+	if err != nil {
+		return nil, err
+	}
+	room.BlueTeamName, room.RedTeamName = room.BlueTeam.Name, room.RedTeam.Name
+
+	if draft.Result != nil {
+		result := *draft.Result
+		result.SubmittedBy = ResultByImport
+		if result.At == 0 {
+			result.At = completedAt * 1000
+		}
+		room.Result = &result
+	}
+	return room, nil
+}
+
+// importedTimes returns the times of an imported draft finished at
+// completedAt, in Unix seconds. Times recorded in the draft are kept unless
+// they finish at another time.
+func importedTimes(times *models.DraftTimes, completedAt int64) models.DraftTimes {
+	var imported models.DraftTimes
+	if times != nil && times.FinishedAt/1000 == completedAt {
+		imported = *times
+	}
+	imported.FinishedAt = max(imported.FinishedAt, completedAt*1000)
+	if imported.CreatedAt == 0 {
+		imported.CreatedAt = imported.FinishedAt
+	}
+	return imported
+}
+
+// importedTeam starts a team of an imported draft, with empty slots
+func (s *RoomService) importedTeam(team models.ExportTeam) models.Team {
+	return models.Team{
+		Name:   team.Name,
+		Roster: team.Roster,
+		Bans:   s.initializeBansArray(),
+		Picks:  s.initializePicksArray(),
+	}
+}
+
+// fillFromActions fills the bans and picks of a room from the actions of a
+// draft, which must follow the draft order. Actions without champion are
+// turns that ran out.
+func (is *ImportService) fillFromActions(room *models.Room, actions []models.DraftAction) error {
+	actions = append([]models.DraftAction(nil), actions...)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Seq < actions[j].Seq
+	})
+
+	var sequence []models.Phase
+	switch len(actions) {
+	case len(models.DraftSequence(false)):
+		sequence = models.DraftSequence(false)
+	case len(models.DraftSequence(true)):
+		sequence = models.DraftSequence(true)
+		room.BlueTeamHasBans, room.RedTeamHasBans = true, true
+	default:
+		return models.NewProtocolError(models.ErrInvalidDraft, "actions must be the %d picks, or the %d bans and picks, of a draft",
+			len(models.DraftSequence(false)), len(models.DraftSequence(true)))
+	}
+
+	for i, action := range actions {
+		phase := sequence[i]
+		side, kind, index := phaseSlot(phase)
+		if action.Phase != "" && action.Phase != phase || action.Side != "" && action.Side != side || action.Kind != "" && action.Kind != kind {
+			return models.NewProtocolError(models.ErrInvalidDraft, "action %d must be the %s %s of %s", i+1, side, kind, phase).
+				WithDetail(models.DetailPhase, string(phase))
+		}
+		team := &room.BlueTeam
+		if side == models.SeatRed {
+			team = &room.RedTeam
+		}
+		if action.Team != "" {
+			if team.Name == "" {
+				team.Name = action.Team
+			} else if !strings.EqualFold(team.Name, action.Team) {
+				return models.NewProtocolError(models.ErrInvalidDraft, "action %d is by %s, but the %s team is %s", i+1, action.Team, side, team.Name).
+					WithDetail(models.DetailTeam, action.Team)
+			}
+		}
+		slot := &team.Picks[index]
+		if kind == models.ActionBan {
+			slot = &team.Bans[index]
+		}
+		if action.Champion != "" {
+			key, err := is.champion(action.Champion)
+			if err != nil {
+				return err
+			}
+			if kind == models.ActionBan {
+				*slot = models.Champion{Name: key}
+			} else {
+				*slot = importedPick(*team, key, models.PickTurn(phase), action.Player, "")
+			}
+		}
+		slot.LockedAt, slot.TimeUsed, slot.TimedOut = action.LockedAt, action.TimeUsed, action.TimedOut
+	}
+
+	for _, team := range []struct {
+		field string
+		name  string
+	}{{"blue_team.name", room.BlueTeam.Name}, {"red_team.name", room.RedTeam.Name}} {
+		if team.name == "" {
+			return models.NewProtocolError(models.ErrValidation, "%s: is required", team.field).
+				WithDetail(models.DetailField, team.field)
+		}
+	}
+	return nil
+}
+
+// fillFromTeams fills the bans and picks of a room from the teams of a
+// draft. Picks without turn are taken in the order of the team's turns.
+func (is *ImportService) fillFromTeams(room *models.Room, draft models.DraftExport) error {
+	hasBans := len(lockedBans(draft.BlueTeam.Bans)) > 0 || len(lockedBans(draft.RedTeam.Bans)) > 0
+	room.BlueTeamHasBans, room.RedTeamHasBans = hasBans, hasBans
+
+	turns := make(map[int]bool)
+	for _, side := range []struct {
+		name   string
+		team   models.ExportTeam
+		target *models.Team
+		phases []models.Phase
+	}{
+		{models.SeatBlue, draft.BlueTeam, &room.BlueTeam, bluePickPhases},
+		{models.SeatRed, draft.RedTeam, &room.RedTeam, redPickPhases},
+	} {
+		for i, ban := range side.team.Bans {
+			if ban == "" {
+				continue
+			}
+			key, err := is.champion(ban)
+			if err != nil {
+				return err
+			}
+			side.target.Bans[i] = models.Champion{Name: key}
+		}
+
+		if len(side.team.Picks) != models.TeamPicks {
+			return models.NewProtocolError(models.ErrInvalidDraft, "the %s team must have %d picks", side.name, models.TeamPicks).
+				WithDetail(models.DetailTeam, side.name)
+		}
+		for i, pick := range side.team.Picks {
+			turn := pick.Turn
+			if turn == 0 {
+				turn = models.PickTurn(side.phases[i])
+			}
+			if turns[turn] || !containsPhase(side.phases, models.PickPhases[turn-1]) {
+				return models.NewProtocolError(models.ErrInvalidDraft, "turn %d is not a free pick turn of the %s team", turn, side.name).
+					WithDetail(models.DetailTeam, side.name)
+			}
+			turns[turn] = true
+			key, err := is.champion(pick.Champion)
+			if err != nil {
+				return err
+			}
+			side.target.Picks[i] = importedPick(*side.target, key, turn, pick.Player, pick.PlayerId)
+		}
+	}
+	return nil
+}
+
+// lockedBans lists the bans of an imported team that are not empty
+func lockedBans(bans []string) []string {
+	var locked []string
+	for _, ban := range bans {
+		if ban != "" {
+			locked = append(locked, ban)
+		}
+	}
+	return locked
+}
+
+// containsPhase reports whether phases contains phase
+func containsPhase(phases []models.Phase, phase models.Phase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// importedPick builds a pick, filling the player from the team roster when
+// only its name or ID is given
+func importedPick(team models.Team, champion string, turn int, player, playerId string) models.Champion {
+	pick := models.Champion{Name: champion, Turn: turn, Player: player, PlayerId: playerId}
+	for _, member := range team.Roster {
+		if pick.Player != "" && pick.Player == member.Name || pick.PlayerId != "" && pick.PlayerId == member.Id {
+			pick.Player, pick.PlayerId = member.Name, member.Id
+			break
+		}
+	}
+	return pick
+}
+
+// champion resolves a champion of an imported draft to its catalog key
+func (is *ImportService) champion(name string) (string, error) {
+	key, exists := is.catalog.Resolve(name)
+	if !exists {
+		return "", models.NewProtocolError(models.ErrUnknownChampion, "champion %s is not in the champion catalog", name).
+			WithDetail(models.DetailChampion, name)
+	}
+	return key, nil
+}
+
+// checkImportedChampions applies the draft rules to the champions of an
+// imported room: each is locked once, out of the fearless bans and in the
+// champion pool
+func checkImportedChampions(room *models.Room) error {
+	seen := make(map[string]bool)
+	for _, team := range []models.Team{room.BlueTeam, room.RedTeam} {
+		for _, champion := range append(lockedNames(team.Bans), lockedNames(team.Picks)...) {
+			if seen[champion] {
+				return models.NewProtocolError(models.ErrInvalidDraft, "champion %s is in the draft more than once", champion).
+					WithDetail(models.DetailChampion, champion)
+			}
+			seen[champion] = true
+		}
+	}
+	for champion := range seen {
+		for _, ban := range room.FearlessBans {
+			if ban.Name == champion {
+				return models.NewProtocolError(models.ErrChampionFearless, "champion %s is disabled (fearless ban)", champion).
+					WithDetail(models.DetailChampion, champion)
+			}
+		}
+		if len(room.ChampionPool) > 0 && !containsChampion(room.ChampionPool, champion) {
+			return models.NewProtocolError(models.ErrChampionNotInPool, "champion %s is not in the champion pool", champion).
+				WithDetail(models.DetailChampion, champion)
+		}
+	}
+	return nil
+}
+
+// containsChampion reports whether champions contains champion
+func containsChampion(champions []string, champion string) bool {
+	for _, c := range champions {
+		if c == champion {
+			return true
+		}
+	}
+	return false
+}
+
+// importRoom guarda una room importada como draft terminado y avisa a los
+// hooks igual que al terminar un draft
+func (s *RoomService) importRoom(room *models.Room) error {
+	if _, live := s.lookupRoom(room.Id); live {
+		return s.roomExistsError(room.Id)
+	}
+	if s.firebaseService != nil {
+		exists, err := s.firebaseService.RoomExists(room.Id)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return s.roomExistsError(room.Id)
+		}
+		if err := s.firebaseService.SaveRoom(room); err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range s.finishedHooks {
+		hook(room)
+	}
+	if room.Result != nil {
+		for _, hook := range s.resultHooks {
+			hook(room)
+		}
+	}
+	return nil
+}
+
+// reserveImportedGame links an imported draft to the next game of its match.
+// It names the room, matches its teams, by name or ID, to those of the match
+// and fills in the tournament settings a drafted game gets.
+func (ts *TournamentService) reserveImportedGame(room *models.Room, ref models.MatchRef) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(ref.TournamentId)
+	if err != nil {
+		return err
+	}
+	match, err := findMatch(tournament, ref.MatchId)
+	if err != nil {
+		return err
+	}
+	if match.BlueTeamId == "" || match.RedTeamId == "" {
+		return models.NewProtocolError(models.ErrMatchNotReady, "match %s is waiting for the matches before it", match.Id).
+			WithDetail(models.DetailId, match.Id)
+	}
+	number, err := ts.nextGame(match)
+	if err != nil {
+		return err
+	}
+	if ref.Game != 0 && ref.Game != number {
+		return models.NewProtocolError(models.ErrValidation, "match.game: the next game of match %s is game %d", match.Id, number).
+			WithDetail(models.DetailField, "match.game")
+	}
+
+	blueTeam := matchTeam(tournament, match, room.BlueTeam.Name)
+	redTeam := matchTeam(tournament, match, room.RedTeam.Name)
+	if blueTeam == nil || redTeam == nil || blueTeam == redTeam {
+		return models.NewProtocolError(models.ErrTeamNotFound, "%s and %s are not the teams of match %s", room.BlueTeam.Name, room.RedTeam.Name, match.Id).
+			WithDetail(models.DetailId, match.Id)
+	}
+	for _, side := range []struct {
+		team       *models.Team
+		registered *models.TournamentTeam
+	}{{&room.BlueTeam, blueTeam}, {&room.RedTeam, redTeam}} {
+		side.team.Name = side.registered.Name
+		if len(side.team.Roster) == 0 {
+			side.team.Roster = side.registered.Roster
+		}
+	}
+	room.BlueTeamName, room.RedTeamName = blueTeam.Name, redTeam.Name
+
+	room.Id = gameRoomId(match, number)
+	room.Match = &models.MatchRef{TournamentId: tournament.Id, MatchId: match.Id, Game: number}
+	room.ChampionPool = tournament.ChampionPool
+	if room.Patch == "" {
+		room.Patch = tournament.Draft.Patch
+	}
+	room.FearlessBans = []models.Champion{}
+	if match.Fearless {
+		room.FearlessBans = ts.roomService.initializeFearlessBans(fearlessBans(match))
+	}
+	if err := checkImportedChampions(room); err != nil {
+		return err
+	}
+
+	match.Games = append(match.Games, models.MatchGame{
+		Number:     number,
+		RoomId:     room.Id,
+		BlueTeamId: blueTeam.Id,
+		RedTeamId:  redTeam.Id,
+		Status:     models.GameDrafting,
+		CreatedAt:  room.Times.CreatedAt / 1000,
+	})
+	match.Status = models.MatchInProgress
+	ts.save()
+	return nil
+}
+
+// releaseImportedGame drops the game reserved for an imported draft that
+// could not be saved
+func (ts *TournamentService) releaseImportedGame(room *models.Room) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, match, game := ts.roomGame(room)
+	if game == nil || game.Status != models.GameDrafting {
+		return
+	}
+	games := match.Games[:0]
+	for _, g := range match.Games {
+		if g.RoomId != room.Id {
+			games = append(games, g)
+		}
+	}
+	match.Games = games
+	if len(match.Games) == 0 {
+		match.Status = models.MatchScheduled
+	}
+	ts.save()
+	ts.broadcastStandings(tournament)
+}
+
+// matchTeam finds the team of a match with the given name or ID,
+// case-insensitively
+func matchTeam(tournament *models.Tournament, match *models.Match, name string) *models.TournamentTeam {
+	for _, teamId := range []string{match.BlueTeamId, match.RedTeamId} {
+		team := findTeam(tournament, teamId)
+		if team != nil && (strings.EqualFold(team.Name, name) || strings.EqualFold(team.Id, name)) {
+			return team
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// InviteClaims is the content of a signed invite token
+type InviteClaims struct {
+	RoomId     string `json:"room"`
+	Seat       string `json:"seat"`
+	ExpiresAt  int64  `json:"exp"`           // Unix seconds
+	Generation int    `json:"gen"`           // Seat key generation, invalidated by key rotation
+	Swapped    bool   `json:"swp,omitempty"` // The room had swapped sides when the invite was issued
+}
+
+// InviteSigner issues and verifies HMAC-signed invite tokens. A token grants a
+// seat in a room until it expires, without knowing the seat key, so invite
+// links can be sent before the room even exists.
+type InviteSigner struct {
+	secret []byte
+}
+
+// NewInviteSigner creates a signer. Without a secret a random one is used and
+// tokens stop being valid when the server restarts.
+func NewInviteSigner(secret string) *InviteSigner {
+	if secret == "" {
+		log.Println("Warning: INVITE_SECRET not configured, invite links will not survive a restart")
+		random := make([]byte, 32)
+		rand.Read(random)
+		return &InviteSigner{secret: random}
+	}
+	return &InviteSigner{secret: []byte(secret)}
+}
+
+// Issue creates a token for claims
+func (is *InviteSigner) Issue(claims InviteClaims) string {
+	payload, _ := json.Marshal(claims)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(is.sign(encoded))
+}
+
+// Verify checks the signature and expiry of a token and returns its claims
+func (is *InviteSigner) Verify(token string) (InviteClaims, error) {
+	var claims InviteClaims
+
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return claims, models.NewProtocolError(models.ErrInvalidInvite, "malformed invite")
+	}
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, is.sign(encoded)) {
+		return claims, models.NewProtocolError(models.ErrInvalidInvite, "invalid invite signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &claims) != nil {
+		return claims, models.NewProtocolError(models.ErrInvalidInvite, "malformed invite")
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return claims, models.NewProtocolError(models.ErrInviteExpired, "invite expired").
+			WithDetail(models.DetailRoomId, claims.RoomId)
+	}
+	return claims, nil
+}
+
+func (is *InviteSigner) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, is.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
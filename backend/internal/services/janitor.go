@@ -0,0 +1,181 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"picks3w2a/internal/metrics"
+	"picks3w2a/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// RoomTTLs configures how long a room may stay idle in each state before the
+// janitor evicts it from memory. A zero TTL disables eviction for that state.
+type RoomTTLs struct {
+	Lobby      time.Duration
+	Drafting   time.Duration
+	Finished   time.Duration
+	Empty      time.Duration
+	Unreported time.Duration // Finished tournament games waiting for their result
+}
+
+// Room states used for TTLs and metrics
+const (
+	RoomStateLobby    = "lobby"
+	RoomStateDrafting = "drafting"
+	RoomStateFinished = "finished"
+)
+
+// roomState groups the phases of a room into lifecycle states
+func (s *RoomService) roomState(phase models.Phase) string {
+	switch {
+	case phase == models.Finished:
+		return RoomStateFinished
+	case s.isBanPhase(phase) || s.isPickPhase(phase) || phase == models.Trading:
+		return RoomStateDrafting
+	default:
+		return RoomStateLobby
+	}
+}
+
+// StartJanitor evicts expired rooms every interval until the returned stop
+// function is called. It also registers the per-state room gauge.
+func (s *RoomService) StartJanitor(interval time.Duration) (stop func()) {
+	metrics.Default.NewGaugeFunc("draft_rooms", "Rooms held in memory, by lifecycle state", "state",
+		func() map[string]float64 {
+			values := make(map[string]float64)
+			for state, count := range s.RoomCountsByState() {
+				values[state] = float64(count)
+			}
+			return values
+		})
+	metrics.Default.NewGaugeFunc("draft_pending_subscriptions", "Spectators waiting for a room that does not exist yet", "",
+		func() map[string]float64 {
+			return map[string]float64{"": float64(s.PendingSubscriptionCount())}
+		})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				s.expireRooms(now)
+				s.expireSubscriptions(now)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// RoomCountsByState cuenta las rooms en RAM por estado
+func (s *RoomService) RoomCountsByState() map[string]int {
+	counts := map[string]int{
+		RoomStateLobby:    0,
+		RoomStateDrafting: 0,
+		RoomStateFinished: 0,
+	}
+	for _, room := range s.GetRooms() {
+		room.TimerMutex.RLock()
+		phase := room.CurrentPhase
+		room.TimerMutex.RUnlock()
+		counts[s.roomState(phase)]++
+	}
+	return counts
+}
+
+// expireRooms evicts every room whose idle time exceeds the TTL of its state
+func (s *RoomService) expireRooms(now time.Time) {
+	for _, room := range s.GetRooms() {
+		room.TimerMutex.RLock()
+		phase := room.CurrentPhase
+		unreported := room.Match != nil && room.Result == nil
+		room.TimerMutex.RUnlock()
+
+		room.ClientsMutex.Lock()
+		clients := len(room.Clients)
+		room.ClientsMutex.Unlock()
+
+		idle := now.Sub(time.Unix(0, room.LastActivity.Load()))
+		state := s.roomState(phase)
+		if s.isExpired(state, clients, idle, unreported) {
+			log.Printf("Room %s expired after %s idle in state %s", room.Id, idle.Round(time.Second), state)
+			s.evictRoom(room, state)
+		}
+	}
+}
+
+// isExpired decide si una room ha superado el TTL que le corresponde. Las
+// partidas de torneo terminadas sin resultado tienen su propio TTL, vacías o
+// no, para que los capitanes y el árbitro puedan reportarlo más tarde.
+func (s *RoomService) isExpired(state string, clients int, idle time.Duration, unreported bool) bool {
+	if state == RoomStateFinished && unreported {
+		return s.ttls.Unreported > 0 && idle > s.ttls.Unreported
+	}
+	if clients == 0 && s.ttls.Empty > 0 && idle > s.ttls.Empty {
+		return true
+	}
+
+	var ttl time.Duration
+	switch state {
+	case RoomStateLobby:
+		ttl = s.ttls.Lobby
+	case RoomStateDrafting:
+		ttl = s.ttls.Drafting
+	case RoomStateFinished:
+		ttl = s.ttls.Finished
+	}
+	return ttl > 0 && idle > ttl
+}
+
+// evictRoom persists a room if worth keeping, notifies and disconnects its
+// clients and removes it from memory
+func (s *RoomService) evictRoom(room *models.Room, state string) {
+	s.stopTimer(room)
+
+	// Los drafts terminados ya se guardaron al acabar; de los drafts
+	// abandonados se guarda lo que se haya llegado a bloquear
+	if state == RoomStateDrafting && s.hasLockedChampions(room) && s.draftStore != nil {
+		room.Expired = true
+		if err := s.draftStore.SaveRoom(room); err != nil {
+			log.Printf("Error saving expired room %s: %v", room.Id, err)
+		}
+	}
+
+	closed := models.RoomClosedMessage{
+		Type:   "room_closed",
+		RoomId: room.Id,
+		Reason: "expired",
+		Phase:  room.CurrentPhase,
+	}
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "room expired")
+
+	room.ClientsMutex.Lock()
+	for conn := range room.Clients {
+		if err := WriteJSON(conn, closed); err != nil {
+			log.Printf("Error notifying client of expired room %s: %v", room.Id, err)
+		}
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		delete(room.Clients, conn)
+	}
+	room.ClientsMutex.Unlock()
+
+	s.removeRoomFromRAM(room.Id)
+}
+
+// hasLockedChampions indica si algún equipo ha elegido algún campeón
+func (s *RoomService) hasLockedChampions(room *models.Room) bool {
+	for _, champions := range [][]models.Champion{room.BlueTeam.Bans, room.BlueTeam.Picks, room.RedTeam.Bans, room.RedTeam.Picks} {
+		for _, champion := range champions {
+			if champion.Name != "-1" {
+				return true
+			}
+		}
+	}
+	return false
+}
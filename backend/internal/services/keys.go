@@ -0,0 +1,34 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// keyBytes is the entropy of a seat key, encoded as 43 URL-safe characters
+const keyBytes = 32
+
+// generateKey genera una clave aleatoria para un asiento (equipo o árbitro)
+func generateKey() string {
+	bytes := make([]byte, keyBytes)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// hashKey devuelve el hash con el que se guarda una clave; las claves en
+// claro solo se envían una vez al creador de la room
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// keyMatches compara una clave con un hash guardado en tiempo constante
+func keyMatches(key, hash string) bool {
+	if key == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(hash)) == 1
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"picks3w2a/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// pendingSubscription is a spectator connection waiting for a room that has
+// not been created yet, e.g. the pre-scheduled ID of a tournament match. It
+// costs a map entry instead of a full models.Room.
+type pendingSubscription struct {
+	roomId    string
+	expiresAt time.Time
+}
+
+// SubscribeToRoom hace que un espectador espere a que se cree la room.
+// Solo se puede esperar a rooms reservadas, p.ej. la siguiente partida de un
+// partido de torneo, o que ya existan. Devuelve cuándo caduca la espera.
+func (s *RoomService) SubscribeToRoom(roomId string, conn *websocket.Conn) (time.Time, error) {
+	// Sin esto cualquiera podría ocupar plazas de espera con IDs inventados
+	if _, exists := s.lookupRoom(roomId); !exists && !s.isReserved(roomId) {
+		return time.Time{}, models.NewProtocolError(models.ErrRoomNotFound, "room %s not found", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	s.pendingMutex.Lock()
+	defer s.pendingMutex.Unlock()
+
+	// Una conexión solo espera a una room a la vez
+	if _, waiting := s.pending[conn]; !waiting && s.maxPending > 0 && len(s.pending) >= s.maxPending {
+		return time.Time{}, models.NewProtocolError(models.ErrSubscriptionLimitReached,
+			"room %s does not exist and no more spectators can wait for rooms right now", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	expiresAt := time.Now().Add(s.pendingTTL)
+	s.pending[conn] = pendingSubscription{roomId: roomId, expiresAt: expiresAt}
+	log.Printf("Espectador esperando a la room %s", roomId)
+	return expiresAt, nil
+}
+
+// unsubscribe cancela la espera de una conexión, si la hay
+func (s *RoomService) unsubscribe(conn *websocket.Conn) {
+	s.pendingMutex.Lock()
+	delete(s.pending, conn)
+	s.pendingMutex.Unlock()
+}
+
+// PendingSubscriptionCount devuelve cuántas conexiones esperan a una room
+func (s *RoomService) PendingSubscriptionCount() int {
+	s.pendingMutex.Lock()
+	defer s.pendingMutex.Unlock()
+	return len(s.pending)
+}
+
+// activateSubscriptions mete como espectadores en una room recién creada a
+// las conexiones que la esperaban y les envía su estado. Las que no caben
+// por el límite de conexiones por room se cierran.
+func (s *RoomService) activateSubscriptions(room *models.Room) {
+	s.pendingMutex.Lock()
+	var waiting []*websocket.Conn
+	for conn, sub := range s.pending {
+		if sub.roomId == room.Id {
+			waiting = append(waiting, conn)
+			delete(s.pending, conn)
+		}
+	}
+	s.pendingMutex.Unlock()
+
+	if len(waiting) == 0 {
+		return
+	}
+
+	full := make(map[*websocket.Conn]string)
+	room.ClientsMutex.Lock()
+	for _, conn := range waiting {
+		if s.maxClientsPerRoom > 0 && len(room.Clients) >= s.maxClientsPerRoom {
+			full[conn] = room.Id
+			continue
+		}
+		room.Clients[conn] = &models.Client{Conn: conn, Team: ""}
+	}
+	room.ClientsMutex.Unlock()
+
+	if len(full) > 0 {
+		log.Printf("%d espectadores en espera no caben en la room %s", len(full), room.Id)
+		closeSubscriptions(full, "room_full", "room is full")
+	}
+	if added := len(waiting) - len(full); added > 0 {
+		log.Printf("%d espectadores en espera añadidos a la room %s", added, room.Id)
+		s.broadcastRoomUpdate(room)
+	}
+}
+
+// expireSubscriptions avisa y desconecta a los espectadores cuya espera ha caducado
+func (s *RoomService) expireSubscriptions(now time.Time) {
+	s.pendingMutex.Lock()
+	expired := make(map[*websocket.Conn]string)
+	for conn, sub := range s.pending {
+		if now.After(sub.expiresAt) {
+			expired[conn] = sub.roomId
+			delete(s.pending, conn)
+		}
+	}
+	s.pendingMutex.Unlock()
+
+	closeSubscriptions(expired, "subscription_expired", "room was not created in time")
+}
+
+// closeSubscriptions tells connections that stopped waiting for a room why
+// and closes them
+func closeSubscriptions(conns map[*websocket.Conn]string, reason, text string) {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, text)
+	for conn, roomId := range conns {
+		closed := models.RoomClosedMessage{
+			Type:   "room_closed",
+			RoomId: roomId,
+			Reason: reason,
+			Phase:  models.NoReady,
+		}
+		if err := WriteJSON(conn, closed); err != nil {
+			log.Printf("Error notifying closed subscription to room %s: %v", roomId, err)
+		}
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+	}
+}
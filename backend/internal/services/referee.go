@@ -0,0 +1,155 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// defaultInviteTTL is used when issue_invite does not ask for an expiry
+const defaultInviteTTL = 24 * time.Hour
+
+// isRefereeAction reports whether an action is reserved to the referee
+func isRefereeAction(action string) bool {
+	switch action {
+	case "rotate_key", "issue_invite", "force_start":
+		return true
+	}
+	return false
+}
+
+// processRefereeAction handles the referee-only actions. Their result is sent
+// only to the referee, never broadcast.
+func (s *RoomService) processRefereeAction(room *models.Room, client *models.Client, action models.ActionMessage) error {
+	if !client.Referee {
+		return models.NewProtocolError(models.ErrNotReferee, "only the referee can %s", action.Action).
+			WithDetail(models.DetailAction, action.Action)
+	}
+
+	switch action.Action {
+	case "force_start":
+		return s.forceStart(room)
+	case "rotate_key":
+		key := s.rotateKey(room, client, action.Seat)
+		return s.sendTo(room, client, models.KeyRotatedMessage{
+			Type:      "key_rotated",
+			RequestId: action.RequestId,
+			Seat:      action.Seat,
+			Key:       key,
+		})
+	case "issue_invite":
+		invite, err := s.IssueInvite(room.Id, action.Seat, time.Duration(action.ExpiresIn)*time.Second)
+		if err != nil {
+			return err
+		}
+		invite.RequestId = action.RequestId
+		return s.sendTo(room, client, invite)
+	}
+	return models.NewProtocolError(models.ErrUnknownAction, "unknown action type: %s", action.Action).
+		WithDetail(models.DetailAction, action.Action)
+}
+
+// forceStart starts the draft from the lobby without waiting for the teams to
+// be ready or for the countdown
+func (s *RoomService) forceStart(room *models.Room) error {
+	switch room.CurrentPhase {
+	case models.NoReady, models.BlueReady, models.RedReady, models.Countdown:
+	default:
+		return s.invalidPhaseError("force_start", room.CurrentPhase)
+	}
+	s.stopTimer(room)
+	s.startDraft(room)
+	log.Printf("Referee force-started the draft of room %s", room.Id)
+	return nil
+}
+
+// rotateKey replaces the key of a seat, revokes the invites issued for it and
+// demotes the clients that joined with the old key to spectators
+func (s *RoomService) rotateKey(room *models.Room, referee *models.Client, seat string) string {
+	key := generateKey()
+
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
+
+	switch seat {
+	case models.SeatBlue:
+		room.BlueTeamKeyHash = hashKey(key)
+	case models.SeatRed:
+		room.RedTeamKeyHash = hashKey(key)
+	case models.SeatReferee:
+		room.RefereeKeyHash = hashKey(key)
+	}
+	if room.SeatGenerations == nil {
+		room.SeatGenerations = make(map[string]int)
+	}
+	room.SeatGenerations[seat]++
+
+	revoked := models.SeatRevokedMessage{Type: "seat_revoked", RoomId: room.Id, Seat: seat}
+	for conn, c := range room.Clients {
+		if c == referee {
+			continue // The referee rotating its own key keeps its seat
+		}
+		if seat == models.SeatReferee && c.Referee || c.Team == seat {
+			c.Team = ""
+			c.Role = ""
+			c.Referee = false
+			if err := WriteJSON(conn, revoked); err != nil {
+				log.Printf("Error enviando mensaje a cliente: %v", err)
+			}
+		}
+	}
+	s.broadcastPresenceLocked(room)
+
+	log.Printf("Key of seat %s rotated in room %s", seat, room.Id)
+	return key
+}
+
+// IssueInvite signs an invite for a seat of a live room, or of a room
+// reserved for a tournament game that has not started yet. A ttl of zero
+// uses the default expiry.
+func (s *RoomService) IssueInvite(roomId, seat string, ttl time.Duration) (models.InviteMessage, error) {
+	if ttl <= 0 {
+		ttl = defaultInviteTTL
+	}
+	if s.maxInviteTTL > 0 && ttl > s.maxInviteTTL {
+		return models.InviteMessage{}, models.NewProtocolError(models.ErrValidation, "expires_in: must be at most %d seconds", int(s.maxInviteTTL.Seconds())).
+			WithDetail(models.DetailField, "expires_in")
+	}
+
+	// Las invitaciones de rooms existentes se atan a la generación actual de la key
+	generation, swapped := 0, false
+	if room, exists := s.lookupRoom(roomId); exists {
+		room.ClientsMutex.Lock()
+		generation = room.SeatGenerations[seat]
+		swapped = room.SidesSwapped
+		room.ClientsMutex.Unlock()
+	} else if !s.isReserved(roomId) {
+		// Una invitación de una room sin dueño le serviría a quien la creara primero
+		return models.InviteMessage{}, models.NewProtocolError(models.ErrRoomNotFound, "room %s not found", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	expiresAt := time.Now().Add(ttl).Unix()
+	token := s.invites.Issue(InviteClaims{
+		RoomId:     roomId,
+		Seat:       seat,
+		ExpiresAt:  expiresAt,
+		Generation: generation,
+		Swapped:    swapped,
+	})
+	return models.InviteMessage{
+		Type:      "invite",
+		RoomId:    roomId,
+		Seat:      seat,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// sendTo writes a message to a single client of a room
+func (s *RoomService) sendTo(room *models.Room, client *models.Client, message interface{}) error {
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
+	return WriteJSON(client.Conn, message)
+}
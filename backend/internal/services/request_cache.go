@@ -0,0 +1,89 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// RequestCache remembers the outcome of recently processed requests so that a
+// request retried with the same request_id is answered again instead of being
+// applied twice. A request is claimed before it runs, so a duplicate that
+// arrives while the first one is still running waits for its outcome.
+type RequestCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*requestCacheEntry
+	expiry  []*requestCacheEntry // Finished entries, oldest first; they all share ttl
+}
+
+// RequestOutcome is what a request was answered with
+type RequestOutcome struct {
+	Response interface{} // Reply sent on success, if the request has one besides the ack
+	Err      error
+}
+
+type requestCacheEntry struct {
+	key       string
+	outcome   RequestOutcome
+	done      chan struct{} // Closed when the request finishes
+	expiresAt time.Time     // Zero while the request runs
+}
+
+// NewRequestCache creates a cache that keeps outcomes for the given duration
+func NewRequestCache(ttl time.Duration) *RequestCache {
+	return &RequestCache{
+		ttl:     ttl,
+		entries: make(map[string]*requestCacheEntry),
+	}
+}
+
+// Claim reserves key for a request about to run. If the request is new it
+// returns false and the caller must run it and then call Finish. If it is a
+// retry it returns true with the outcome of the first request, waiting for
+// it to finish if it is still running.
+func (c *RequestCache) Claim(key string) (RequestOutcome, bool) {
+	c.mu.Lock()
+	now := time.Now()
+	c.evictExpired(now)
+	entry, exists := c.entries[key]
+	if !exists || !entry.expiresAt.IsZero() && now.After(entry.expiresAt) {
+		c.entries[key] = &requestCacheEntry{key: key, done: make(chan struct{})}
+		c.mu.Unlock()
+		return RequestOutcome{}, false
+	}
+	c.mu.Unlock()
+
+	<-entry.done
+	return entry.outcome, true
+}
+
+// Finish records the outcome of a request claimed with Claim and releases
+// the retries waiting for it
+func (c *RequestCache) Finish(key string, outcome RequestOutcome) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists || !entry.expiresAt.IsZero() {
+		return
+	}
+	entry.outcome = outcome
+	entry.expiresAt = time.Now().Add(c.ttl)
+	close(entry.done)
+	c.expiry = append(c.expiry, entry)
+}
+
+// evictExpired drops the finished entries whose time is up. As they all
+// live for the same ttl, they expire in the order they finished. Must be
+// called with mu held.
+func (c *RequestCache) evictExpired(now time.Time) {
+	for len(c.expiry) > 0 && now.After(c.expiry[0].expiresAt) {
+		entry := c.expiry[0]
+		c.expiry[0] = nil
+		c.expiry = c.expiry[1:]
+		// The key may have been claimed again since
+		if c.entries[entry.key] == entry {
+			delete(c.entries, entry.key)
+		}
+	}
+}
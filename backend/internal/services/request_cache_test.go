@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestCacheWaitsForRunningRequest(t *testing.T) {
+	cache := NewRequestCache(time.Minute)
+	if _, replay := cache.Claim("room/session/r1"); replay {
+		t.Fatal("first claim reported as a replay")
+	}
+
+	failed := errors.New("not your turn")
+	var wg sync.WaitGroup
+	outcomes := make([]RequestOutcome, 3)
+	replays := make([]bool, 3)
+	for i := range outcomes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outcomes[i], replays[i] = cache.Claim("room/session/r1")
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cache.Finish("room/session/r1", RequestOutcome{Err: failed})
+	wg.Wait()
+
+	for i := range outcomes {
+		if !replays[i] || outcomes[i].Err != failed {
+			t.Errorf("duplicate %d = %+v, %v; want the first outcome replayed", i, outcomes[i], replays[i])
+		}
+	}
+}
+
+func TestRequestCacheExpires(t *testing.T) {
+	cache := NewRequestCache(10 * time.Millisecond)
+	cache.Claim("a")
+	cache.Finish("a", RequestOutcome{Response: "first"})
+	if outcome, replay := cache.Claim("a"); !replay || outcome.Response != "first" {
+		t.Fatalf("Claim before expiry = %+v, %v; want the stored outcome", outcome, replay)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, replay := cache.Claim("b"); replay {
+		t.Fatal("new key reported as a replay")
+	}
+	if len(cache.entries) != 1 || len(cache.expiry) != 0 {
+		t.Fatalf("expired entries kept: %d entries, %d queued", len(cache.entries), len(cache.expiry))
+	}
+	if _, replay := cache.Claim("a"); replay {
+		t.Fatal("expired key replayed")
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// ResultByCaptains is the SubmittedBy of a result both captains agreed on
+const ResultByCaptains = "captains"
+
+// ResultByAdmin is the SubmittedBy of a result entered through the admin API
+const ResultByAdmin = "admin"
+
+// reportResult records the winner of the game played with a finished draft.
+// The referee's report is final; a captain's report is kept until the other
+// captain reports too. If both name the same winner it becomes the result,
+// otherwise the result is disputed until a captain changes their report or
+// the referee submits one. A result cannot be changed once recorded.
+func (s *RoomService) reportResult(room *models.Room, client *models.Client, action models.ActionMessage) error {
+	seat := models.SeatReferee
+	if !client.Referee {
+		if client.Team == "" {
+			return models.NewProtocolError(models.ErrSpectatorAction, "spectators cannot perform actions").
+				WithDetail(models.DetailAction, action.Action)
+		}
+		if err := s.requireCaptain(client, action.Action); err != nil {
+			return err
+		}
+		seat = client.Team
+	}
+
+	report := &models.GameResult{
+		Winner:      action.Side,
+		SubmittedBy: seat,
+		Duration:    action.Duration,
+		Notes:       action.Notes,
+		At:          time.Now().UnixMilli(),
+	}
+
+	room.TimerMutex.Lock()
+	if room.CurrentPhase != models.Finished {
+		phase := room.CurrentPhase
+		room.TimerMutex.Unlock()
+		return s.invalidPhaseError(action.Action, phase)
+	}
+	if room.Result != nil {
+		room.TimerMutex.Unlock()
+		return models.NewProtocolError(models.ErrResultExists, "the winner of room %s was already submitted", room.Id).
+			WithDetail(models.DetailRoomId, room.Id)
+	}
+
+	var result *models.GameResult
+	if seat == models.SeatReferee {
+		result = report
+	} else {
+		if room.ResultReports == nil {
+			room.ResultReports = make(map[string]*models.GameResult)
+		}
+		room.ResultReports[seat] = report
+		result = agreedResult(room.ResultReports)
+		room.ResultDisputed = result == nil && len(room.ResultReports) == 2
+	}
+	if result != nil {
+		room.Result = result
+		room.ResultReports = nil
+		room.ResultDisputed = false
+	}
+	disputed := room.ResultDisputed
+	room.TimerMutex.Unlock()
+
+	room.ClientsMutex.Lock()
+	appendHistoryLocked(room, models.HistoryEntry{
+		At:       report.At,
+		Kind:     models.HistoryResultReported,
+		Team:     seat,
+		Nickname: client.Nickname,
+		Side:     report.Winner,
+		Text:     report.Notes,
+	})
+	room.ClientsMutex.Unlock()
+
+	switch {
+	case result != nil:
+		log.Printf("Result of room %s recorded: %s won, submitted by %s", room.Id, result.Winner, result.SubmittedBy)
+		s.recordResult(room)
+	case disputed:
+		log.Printf("Captains of room %s reported different winners, waiting for the referee", room.Id)
+	}
+	return nil
+}
+
+// SubmitResult records the winner of a finished draft from outside the room.
+// Like the referee's report it is final and settles a dispute between the
+// captains; a result already recorded cannot be changed.
+func (s *RoomService) SubmitResult(roomId string, req models.SubmitResultRequest) error {
+	room, err := s.GetRoom(roomId)
+	if err != nil {
+		return err
+	}
+	result := &models.GameResult{
+		Winner:      req.Winner,
+		SubmittedBy: ResultByAdmin,
+		Duration:    req.Duration,
+		Notes:       req.Notes,
+		At:          time.Now().UnixMilli(),
+	}
+
+	room.TimerMutex.Lock()
+	if room.CurrentPhase != models.Finished {
+		phase := room.CurrentPhase
+		room.TimerMutex.Unlock()
+		return s.invalidPhaseError("submit_result", phase)
+	}
+	if room.Result != nil {
+		room.TimerMutex.Unlock()
+		return models.NewProtocolError(models.ErrResultExists, "the winner of room %s was already submitted", room.Id).
+			WithDetail(models.DetailRoomId, room.Id)
+	}
+	room.Result = result
+	room.ResultReports = nil
+	room.ResultDisputed = false
+	room.TimerMutex.Unlock()
+
+	room.ClientsMutex.Lock()
+	appendHistoryLocked(room, models.HistoryEntry{
+		At:   result.At,
+		Kind: models.HistoryResultReported,
+		Team: ResultByAdmin,
+		Side: result.Winner,
+		Text: result.Notes,
+	})
+	room.ClientsMutex.Unlock()
+
+	log.Printf("Result of room %s recorded: %s won, submitted by %s", room.Id, result.Winner, result.SubmittedBy)
+	s.recordResult(room)
+	s.broadcastRoomUpdate(room)
+	return nil
+}
+
+// agreedResult merges the reports of both captains when they name the same
+// winner. The duration and notes of the first report are kept unless only the
+// second one has them.
+func agreedResult(reports map[string]*models.GameResult) *models.GameResult {
+	blue, red := reports[models.SeatBlue], reports[models.SeatRed]
+	if blue == nil || red == nil || blue.Winner != red.Winner {
+		return nil
+	}
+	first, second := blue, red
+	if red.At < blue.At {
+		first, second = red, blue
+	}
+	result := *first
+	result.SubmittedBy = ResultByCaptains
+	result.At = second.At
+	if result.Duration == 0 {
+		result.Duration = second.Duration
+	}
+	if result.Notes == "" {
+		result.Notes = second.Notes
+	}
+	return &result
+}
@@ -3,24 +3,78 @@ package services
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
+	"picks3w2a/internal/config"
 	"picks3w2a/internal/models"
 	"github.com/gorilla/websocket"
 )
 
 type RoomService struct {
-	rooms           map[string]*models.Room
-	firebaseService *FirebaseService
+	rooms             map[string]*models.Room
+	roomsMutex        sync.RWMutex // Protege el mapa rooms, accedido también por el janitor
+	firebaseService   *FirebaseService
+	draftStore        DraftStore // Donde se guardan los drafts abandonados, con o sin Firestore
+	maxRooms          int
+	maxClientsPerRoom int
+	ttls              RoomTTLs
+	invites           *InviteSigner
+	countdown         int // Segundos entre que los dos equipos están listos y el primer turno
+	maxInviteTTL      time.Duration
+	finishedHooks     []func(*models.Room) // Se registran al arrancar, antes de servir conexiones
+	resultHooks       []func(*models.Room)
+	startedHooks      []func(*models.Room)
+	lockHooks         []func(*models.Room, models.Phase, models.Champion)
+	reservations      []func(roomId string) bool // IDs de rooms que aún no existen pero ya tienen dueño
+	gamePatch         string // Parche por defecto de las rooms que no indican uno
+
+	// Espectadores esperando a rooms que aún no existen
+	pending      map[*websocket.Conn]pendingSubscription
+	pendingMutex sync.Mutex
+	maxPending   int
+	pendingTTL   time.Duration
 }
 
-func NewRoomService(firebaseService *FirebaseService) *RoomService {
+func NewRoomService(firebaseService *FirebaseService, cfg *config.Config) *RoomService {
 	return &RoomService{
-		rooms:           make(map[string]*models.Room),
-		firebaseService: firebaseService,
+		rooms:             make(map[string]*models.Room),
+		firebaseService:   firebaseService,
+		maxRooms:          cfg.MaxRooms,
+		maxClientsPerRoom: cfg.MaxClientsPerRoom,
+		ttls: RoomTTLs{
+			Lobby:      cfg.RoomTTLLobby,
+			Drafting:   cfg.RoomTTLDrafting,
+			Finished:   cfg.RoomTTLFinished,
+			Empty:      cfg.RoomTTLEmpty,
+			Unreported: cfg.RoomTTLUnreported,
+		},
+		invites:      NewInviteSigner(cfg.InviteSecret),
+		countdown:    int(cfg.StartCountdown.Seconds()),
+		maxInviteTTL: cfg.MaxInviteTTL,
+		gamePatch:    cfg.GamePatch,
+		pending:      make(map[*websocket.Conn]pendingSubscription),
+		maxPending:   cfg.MaxPendingSubscriptions,
+		pendingTTL:   cfg.PendingSubscriptionTTL,
+	}
+}
+
+// lookupRoom busca una room en RAM
+func (s *RoomService) lookupRoom(roomId string) (*models.Room, bool) {
+	s.roomsMutex.RLock()
+	defer s.roomsMutex.RUnlock()
+	room, exists := s.rooms[roomId]
+	return room, exists
+}
+
+// checkRoomLimit devuelve un error si no caben más rooms en memoria.
+// Debe llamarse con roomsMutex bloqueado.
+func (s *RoomService) checkRoomLimit() error {
+	if s.maxRooms > 0 && len(s.rooms) >= s.maxRooms {
+		return models.NewProtocolError(models.ErrRoomLimitReached, "the server cannot hold more rooms right now")
 	}
+	return nil
 }
 
 // generateRandomID genera un ID aleatorio de 8 caracteres
@@ -30,7 +84,8 @@ func (s *RoomService) generateRandomID() string {
 	return hex.EncodeToString(bytes)
 }
 
-// generateUniqueRoomID genera un ID único para la room
+// generateUniqueRoomID genera un ID único para la room.
+// Debe llamarse con roomsMutex bloqueado.
 func (s *RoomService) generateUniqueRoomID() string {
 	for {
 		id := s.generateRandomID()
@@ -40,37 +95,6 @@ func (s *RoomService) generateUniqueRoomID() string {
 	}
 }
 
-// createEmptyRoom crea una room vacía sin empezar (para espectadores)
-func (s *RoomService) createEmptyRoom(roomId string) *models.Room {
-	return &models.Room{
-		Id:              roomId,
-		RedTeamKey:      "",
-		BlueTeamKey:     "",
-		BlueTeamName:    "Blue Team",
-		RedTeamName:     "Red Team",
-		BlueTeamHasBans: false,
-		RedTeamHasBans:  false,
-		TimePerPick:     30,
-		TimePerBan:      30,
-		CurrentPhase:    models.NoReady,
-		BlueTeam: models.Team{
-			Name:  "Blue Team",
-			Bans:  s.initializeBansArray(),
-			Picks: s.initializePicksArray(),
-		},
-		RedTeam: models.Team{
-			Name:  "Red Team",
-			Bans:  s.initializeBansArray(),
-			Picks: s.initializePicksArray(),
-		},
-		FearlessBans:  []models.Champion{}, // Lista vacía para rooms de espectadores
-		Clients:       make(map[*websocket.Conn]*models.Client),
-		TimeRemaining: 0,
-		TimerActive:   false,
-		TimerCancel:   make(chan bool, 1),
-	}
-}
-
 // initializeBansArray inicializa el array de bans con 5 posiciones vacías
 func (s *RoomService) initializeBansArray() []models.Champion {
 	bans := make([]models.Champion, 5)
@@ -102,41 +126,137 @@ func (s *RoomService) initializeFearlessBans(fearlessBansNames []string) []model
 	return fearlessBans
 }
 
-// CreateRoom crea una nueva room basada en el CreateMessage
+// CreateRoom crea una nueva room basada en el CreateMessage. Los clientes no
+// eligen el ID: solo la API de torneos crea rooms con un ID conocido de antemano.
 func (s *RoomService) CreateRoom(createMsg models.CreateMessage) (*models.CreateResponseMessage, error) {
-	// Generar IDs únicos
-	roomId := s.generateUniqueRoomID()
-	redTeamKey := s.generateRandomID()
-	blueTeamKey := s.generateRandomID()
+	if createMsg.RoomId != "" {
+		return nil, models.NewProtocolError(models.ErrValidation, "room_id: only tournament games are created with a chosen ID").
+			WithDetail(models.DetailField, "room_id")
+	}
+	return s.createRoom(createMsg, nil)
+}
+
+// CreateMatchRoom crea la room de una partida de torneo, enlazada con su partido
+func (s *RoomService) CreateMatchRoom(createMsg models.CreateMessage, match models.MatchRef) (*models.CreateResponseMessage, error) {
+	return s.createRoom(createMsg, &match)
+}
+
+// OnDraftFinished registra una función que se llama con cada room que termina
+// el draft, después de guardarla. Debe llamarse antes de servir conexiones.
+func (s *RoomService) OnDraftFinished(hook func(room *models.Room)) {
+	s.finishedHooks = append(s.finishedHooks, hook)
+}
+
+// OnGameResult registra una función que se llama cuando se conoce el ganador
+// de la partida de una room terminada. Debe llamarse antes de servir conexiones.
+func (s *RoomService) OnGameResult(hook func(room *models.Room)) {
+	s.resultHooks = append(s.resultHooks, hook)
+}
+
+// OnDraftStarted registra una función que se llama cuando empieza el primer
+// turno del draft de una room. Debe llamarse antes de servir conexiones.
+func (s *RoomService) OnDraftStarted(hook func(room *models.Room)) {
+	s.startedHooks = append(s.startedHooks, hook)
+}
+
+// OnChampionLocked registra una función que se llama al terminar cada turno
+// de ban o pick con la fase y el hueco bloqueado, cuyo nombre es "-1" si el
+// tiempo se agotó sin campeón. Debe llamarse antes de servir conexiones.
+func (s *RoomService) OnChampionLocked(hook func(room *models.Room, phase models.Phase, champion models.Champion)) {
+	s.lockHooks = append(s.lockHooks, hook)
+}
+
+// SetDraftStore indica dónde guardar los drafts que el janitor expulsa a
+// medias. Debe llamarse antes de arrancar el janitor.
+func (s *RoomService) SetDraftStore(store DraftStore) {
+	s.draftStore = store
+}
+
+// ReserveRoomIds registra una función que dice si un ID es el de una room
+// que se creará más adelante, p.ej. la siguiente partida de un partido de
+// torneo. Debe llamarse antes de servir conexiones.
+func (s *RoomService) ReserveRoomIds(reserved func(roomId string) bool) {
+	s.reservations = append(s.reservations, reserved)
+}
+
+// isReserved dice si alguna función registrada reserva el ID
+func (s *RoomService) isReserved(roomId string) bool {
+	for _, reserved := range s.reservations {
+		if reserved(roomId) {
+			return true
+		}
+	}
+	return false
+}
+
+// createRoom crea la room, enlazada con un partido de torneo si match no es nil
+func (s *RoomService) createRoom(createMsg models.CreateMessage, match *models.MatchRef) (*models.CreateResponseMessage, error) {
+	// Un ID elegido no puede pisar el de un draft ya guardado
+	if createMsg.RoomId != "" && s.firebaseService != nil {
+		exists, err := s.firebaseService.RoomExists(createMsg.RoomId)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, s.roomExistsError(createMsg.RoomId)
+		}
+	}
+
+	s.roomsMutex.Lock()
+	if err := s.checkRoomLimit(); err != nil {
+		s.roomsMutex.Unlock()
+		return nil, err
+	}
+
+	// Generar IDs únicos, salvo que se pida uno concreto (p.ej. el de un partido programado)
+	roomId := createMsg.RoomId
+	if roomId == "" {
+		roomId = s.generateUniqueRoomID()
+	} else if _, exists := s.rooms[roomId]; exists {
+		s.roomsMutex.Unlock()
+		return nil, s.roomExistsError(roomId)
+	}
+	// Las keys solo se guardan hasheadas y nunca se registran en los logs
+	redTeamKey := generateKey()
+	blueTeamKey := generateKey()
+	refereeKey := generateKey()
 	log.Println("roomId", roomId)
-	log.Println("redTeamKey", redTeamKey)
-	log.Println("blueTeamKey", blueTeamKey)
 	// Determinar la fase inicial basada en si los equipos tienen bans
 	initialPhase := models.NoReady
 
 	// Crear la room
 	room := &models.Room{
 		Id:              roomId,
-		RedTeamKey:      redTeamKey,
-		BlueTeamKey:     blueTeamKey,
+		RedTeamKeyHash:  hashKey(redTeamKey),
+		BlueTeamKeyHash: hashKey(blueTeamKey),
+		RefereeKeyHash:  hashKey(refereeKey),
+		SeatGenerations: make(map[string]int),
 		BlueTeamName:    createMsg.BlueTeamName,
 		RedTeamName:     createMsg.RedTeamName,
 		BlueTeamHasBans: createMsg.BlueTeamHasBans,
 		RedTeamHasBans:  createMsg.RedTeamHasBans,
 		TimePerPick:     createMsg.TimePerPick,
 		TimePerBan:      createMsg.TimePerBan,
+		TradePhase:      createMsg.TradePhase,
+		TradeTime:       createMsg.TradeTime,
 		CurrentPhase:    initialPhase,
+		Times:           models.DraftTimes{CreatedAt: time.Now().UnixMilli()},
 		BlueTeam: models.Team{
-			Name:  createMsg.BlueTeamName,
-			Bans:  s.initializeBansArray(),
-			Picks: s.initializePicksArray(),
+			Name:   createMsg.BlueTeamName,
+			Roster: createMsg.BlueRoster,
+			Bans:   s.initializeBansArray(),
+			Picks:  s.initializePicksArray(),
 		},
 		RedTeam: models.Team{
-			Name:  createMsg.RedTeamName,
-			Bans:  s.initializeBansArray(),
-			Picks: s.initializePicksArray(),
+			Name:   createMsg.RedTeamName,
+			Roster: createMsg.RedRoster,
+			Bans:   s.initializeBansArray(),
+			Picks:  s.initializePicksArray(),
 		},
 		FearlessBans: s.initializeFearlessBans(createMsg.FearlessBans),
+		ChampionPool: createMsg.ChampionPool,
+		Patch: createMsg.Patch,
+		Match: match,
 		Clients: make(map[*websocket.Conn]*models.Client),
 		
 		// Inicializar campos de timer
@@ -144,9 +264,20 @@ func (s *RoomService) CreateRoom(createMsg models.CreateMessage) (*models.Create
 		TimerActive: false,
 		TimerCancel: make(chan bool, 1),
 	}
-	log.Println("room", room)
+	room.LastActivity.Store(time.Now().UnixNano())
+	s.setupSideSelection(room, createMsg)
+	if room.TradePhase && room.TradeTime == 0 {
+		room.TradeTime = defaultTradeTime
+	}
+	if room.Patch == "" {
+		room.Patch = s.gamePatch
+	}
 	// Guardar la room
 	s.rooms[roomId] = room
+	s.roomsMutex.Unlock()
+
+	// Los espectadores que esperaban esta room pasan a estar dentro
+	s.activateSubscriptions(room)
 
 	// Crear la respuesta
 	response := &models.CreateResponseMessage{
@@ -154,36 +285,58 @@ func (s *RoomService) CreateRoom(createMsg models.CreateMessage) (*models.Create
 		RoomId:      roomId,
 		RedTeamKey:  redTeamKey,
 		BlueTeamKey: blueTeamKey,
+		RefereeKey:  refereeKey,
+	}
+	if room.CoinFlip != nil {
+		response.CoinFlipCommitment = room.CoinFlip.Commitment
 	}
 
 	return response, nil
 }
 
-// GetRoom obtiene una room por su ID, primero busca en RAM, luego en Firebase
+// roomExistsError construye el error para un ID de room ya en uso
+func (s *RoomService) roomExistsError(roomId string) error {
+	return models.NewProtocolError(models.ErrRoomExists, "room %s already exists", roomId).
+		WithDetail(models.DetailRoomId, roomId)
+}
+
+// GetRoom obtiene una room por su ID, primero busca en RAM, luego en el
+// almacén de drafts (Firestore o el fichero local)
 func (s *RoomService) GetRoom(roomId string) (*models.Room, error) {
 	// Primero buscar en RAM
-	room, exists := s.rooms[roomId]
+	room, exists := s.lookupRoom(roomId)
 	if exists {
 		return room, nil
 	}
 
-	// Si no está en RAM, intentar cargar desde Firebase
-	if s.firebaseService != nil {
-		firebaseRoom, err := s.firebaseService.LoadRoom(roomId)
+	// Si no está en RAM, intentar cargar desde el almacén
+	if s.draftStore != nil {
+		storedRoom, err := s.draftStore.LoadRoom(roomId)
 		if err == nil {
-			// Room encontrada en Firebase, cargarla en RAM
-			s.rooms[roomId] = firebaseRoom
-			log.Printf("Room %s loaded from Firestore and cached in RAM", roomId)
-			return firebaseRoom, nil
+			// Room encontrada, cargarla en RAM salvo que otra petición se haya adelantado
+			storedRoom.LastActivity.Store(time.Now().UnixNano())
+			s.roomsMutex.Lock()
+			if existing, loaded := s.rooms[roomId]; loaded {
+				storedRoom = existing
+			} else {
+				s.rooms[roomId] = storedRoom
+			}
+			s.roomsMutex.Unlock()
+			log.Printf("Room %s loaded from the draft store and cached in RAM", roomId)
+			return storedRoom, nil
 		}
-		log.Printf("Room %s not found in Firestore: %v", roomId, err)
+		log.Printf("Room %s not found in the draft store: %v", roomId, err)
 	}
 
-	return nil, fmt.Errorf("room not found")
+	return nil, models.NewProtocolError(models.ErrRoomNotFound, "room %s not found", roomId).
+		WithDetail(models.DetailRoomId, roomId)
 }
 
 // GetRooms obtiene todas las rooms (para debugging)
 func (s *RoomService) GetRooms() map[string]*models.Room {
+	s.roomsMutex.RLock()
+	defer s.roomsMutex.RUnlock()
+
 	roomsCopy := make(map[string]*models.Room)
 	for k, v := range s.rooms {
 		roomsCopy[k] = v
@@ -191,63 +344,141 @@ func (s *RoomService) GetRooms() map[string]*models.Room {
 	return roomsCopy
 }
 
-// JoinRoom añade un cliente a una room y determina su equipo basado en la key
-func (s *RoomService) JoinRoom(roomId string, conn *websocket.Conn, key string) (string, error) {
+// JoinRoom añade un cliente a una room y determina su asiento basado en la
+// key o en la invitación
+func (s *RoomService) JoinRoom(conn *websocket.Conn, joinMsg models.JoinMessage) (string, error) {
+	roomId := joinMsg.RoomId
+
 	// Usar GetRoom que maneja tanto RAM como Firebase
+	// Las rooms inexistentes no se crean: los espectadores pueden esperar a
+	// que existan con SubscribeToRoom
 	room, err := s.GetRoom(roomId)
 	if err != nil {
-		// Si no se encuentra la room, crear una nueva room sin empezar
-		if key == "" { // Solo espectadores pueden unirse a rooms no existentes
-			room = s.createEmptyRoom(roomId)
-			s.rooms[roomId] = room
-		} else {
-			return "", fmt.Errorf("room not found")
-		}
+		return "", err
 	}
 
-	// Determinar el equipo basado en la key
-	var team string
-	if key == room.BlueTeamKey {
-		team = "blue"
-	} else if key == room.RedTeamKey {
-		team = "red"
-	} else if key == "" {
-		team = "" // spectator
+	// Determinar el asiento basado en la invitación o en la key
+	var seat string
+	if joinMsg.Invite != "" {
+		seat, err = s.seatFromInvite(room, joinMsg.Invite)
+		if err != nil {
+			return "", err
+		}
 	} else {
-		return "", fmt.Errorf("invalid key")
+		seat, err = s.seatFromKey(room, joinMsg.Key)
+		if err != nil {
+			return "", err
+		}
+	}
+	team := seat
+	if seat == models.SeatReferee {
+		team = ""
+	}
+
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
+
+	// Limitar el número de conexiones por room
+	if _, rejoining := room.Clients[conn]; !rejoining && s.maxClientsPerRoom > 0 && len(room.Clients) >= s.maxClientsPerRoom {
+		return "", models.NewProtocolError(models.ErrRoomFull, "room %s is full", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	// Solo hay un capitán por equipo; sin rol pedido se ocupa el asiento
+	// de capitán si está libre
+	role, err := s.assignRole(room, conn, team, joinMsg.Role)
+	if err != nil {
+		return "", err
 	}
 
 	// Crear el cliente y añadirlo a la room
 	client := &models.Client{
-		Conn: conn,
-		Team: team,
+		Conn:     conn,
+		Team:     team,
+		Referee:  seat == models.SeatReferee,
+		Role:     role,
+		Nickname: joinMsg.Nickname,
 	}
 	room.Clients[conn] = client
+	room.LastActivity.Store(time.Now().UnixNano())
+	s.revealCoinFlipLocked(room)
+	s.broadcastPresenceLocked(room)
 
-	log.Printf("Cliente añadido a la room %s como %s", roomId, team)
+	log.Printf("Cliente añadido a la room %s como %s %s", roomId, seat, role)
 	return team, nil
 }
 
+// seatFromKey determina el asiento que concede una key; sin key se entra
+// como espectador
+func (s *RoomService) seatFromKey(room *models.Room, key string) (string, error) {
+	switch {
+	case key == "":
+		return "", nil
+	case keyMatches(key, room.BlueTeamKeyHash):
+		return models.SeatBlue, nil
+	case keyMatches(key, room.RedTeamKeyHash):
+		return models.SeatRed, nil
+	case keyMatches(key, room.RefereeKeyHash):
+		return models.SeatReferee, nil
+	}
+	return "", models.NewProtocolError(models.ErrInvalidKey, "invalid key for room %s", room.Id).
+		WithDetail(models.DetailRoomId, room.Id)
+}
+
+// seatFromInvite determina el asiento que concede una invitación firmada
+func (s *RoomService) seatFromInvite(room *models.Room, token string) (string, error) {
+	claims, err := s.invites.Verify(token)
+	if err != nil {
+		return "", err
+	}
+	if claims.RoomId != room.Id {
+		return "", models.NewProtocolError(models.ErrInvalidInvite, "invite is not valid for room %s", room.Id).
+			WithDetail(models.DetailRoomId, room.Id)
+	}
+
+	room.ClientsMutex.Lock()
+	seat := claims.Seat
+	if claims.Swapped != room.SidesSwapped {
+		// Los equipos cambiaron de lado desde que se emitió la invitación
+		seat = otherSide(seat)
+	}
+	generation := room.SeatGenerations[seat]
+	room.ClientsMutex.Unlock()
+	if claims.Generation != generation {
+		return "", models.NewProtocolError(models.ErrInvalidInvite, "invite was revoked by a key rotation").
+			WithDetail(models.DetailRoomId, room.Id).
+			WithDetail(models.DetailTeam, seat)
+	}
+	return seat, nil
+}
+
 // RemoveClient elimina un cliente de una room
 func (s *RoomService) RemoveClient(roomId string, conn *websocket.Conn) {
-	room, exists := s.rooms[roomId]
+	room, exists := s.lookupRoom(roomId)
 	if !exists {
+		s.unsubscribe(conn)
 		return
 	}
 
+	room.ClientsMutex.Lock()
 	delete(room.Clients, conn)
+	s.broadcastPresenceLocked(room)
+	room.ClientsMutex.Unlock()
+	room.LastActivity.Store(time.Now().UnixNano())
 	log.Printf("Cliente eliminado de la room %s", roomId)
 }
 
 // BroadcastToRoom envía un mensaje a todos los clientes conectados en una room
 func (s *RoomService) BroadcastToRoom(roomId string, message interface{}) {
-	room, exists := s.rooms[roomId]
+	room, exists := s.lookupRoom(roomId)
 	if !exists {
 		return
 	}
 
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
 	for conn, _ := range room.Clients {
-		if err := conn.WriteJSON(message); err != nil {
+		if err := WriteJSON(conn, message); err != nil {
 			log.Printf("Error enviando mensaje a cliente: %v", err)
 			// Eliminar cliente con conexión rota
 			delete(room.Clients, conn)
@@ -257,35 +488,76 @@ func (s *RoomService) BroadcastToRoom(roomId string, message interface{}) {
 
 // ProcessAction procesa una acción de un equipo y actualiza el estado de la room
 func (s *RoomService) ProcessAction(roomId string, conn *websocket.Conn, action models.ActionMessage) error {
-	room, exists := s.rooms[roomId]
+	room, exists := s.lookupRoom(roomId)
 	if !exists {
-		return fmt.Errorf("room not found")
+		return models.NewProtocolError(models.ErrRoomNotFound, "room %s not found", roomId).
+			WithDetail(models.DetailRoomId, roomId)
 	}
+	room.LastActivity.Store(time.Now().UnixNano())
 
 	// Obtener el cliente que envió la acción
+	room.ClientsMutex.Lock()
 	client, exists := room.Clients[conn]
+	room.ClientsMutex.Unlock()
 	if !exists {
-		return fmt.Errorf("client not found in room")
+		return models.NewProtocolError(models.ErrClientNotFound, "client not found in room %s", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	// Las acciones del árbitro no dependen de los equipos
+	if isRefereeAction(action.Action) {
+		return s.processRefereeAction(room, client, action)
+	}
+
+	// La elección de lado y el resultado los puede dar también el árbitro
+	switch action.Action {
+	case "choose_side":
+		return s.processChooseSide(room, client, action.Side)
+	case "report_result":
+		return s.reportResult(room, client, action)
 	}
 
 	// Verificar que el cliente pertenece a un equipo
 	if client.Team == "" {
-		return fmt.Errorf("spectators cannot perform actions")
+		return models.NewProtocolError(models.ErrSpectatorAction, "spectators cannot perform actions").
+			WithDetail(models.DetailAction, action.Action)
 	}
 
 	switch action.Action {
 	case "ready":
+		if err := s.requireCaptain(client, action.Action); err != nil {
+			return err
+		}
 		return s.processReadyAction(room, client.Team)
+	case "unready":
+		if err := s.requireCaptain(client, action.Action); err != nil {
+			return err
+		}
+		return s.processUnreadyAction(room, client.Team)
 	case "champ_select":
+		// Los jugadores y el coach sugieren solo a su equipo
+		if client.Role != models.RoleCaptain {
+			return s.processTeamHover(room, client, action.Champion)
+		}
 		return s.processChampSelectAction(room, client.Team, action.Champion)
 	case "champ_pick":
+		if err := s.requireCaptain(client, action.Action); err != nil {
+			return err
+		}
 		return s.processChampPickAction(room, client.Team, action.Champion)
+	case "trade_reorder", "trade_assign", "trade_confirm":
+		if err := s.requireCaptain(client, action.Action); err != nil {
+			return err
+		}
+		return s.processTradeAction(room, client.Team, action)
 	default:
-		return fmt.Errorf("unknown action type: %s", action.Action)
+		return models.NewProtocolError(models.ErrUnknownAction, "unknown action type: %s", action.Action).
+			WithDetail(models.DetailAction, action.Action)
 	}
 }
 
-// processReadyAction maneja la acción "ready"
+// processReadyAction maneja la acción "ready". Cuando los dos equipos
+// están listos empieza la cuenta atrás antes del primer turno.
 func (s *RoomService) processReadyAction(room *models.Room, team string) error {
 	switch room.CurrentPhase {
 	case models.NoReady:
@@ -294,40 +566,105 @@ func (s *RoomService) processReadyAction(room *models.Room, team string) error {
 		} else if team == "red" {
 			room.CurrentPhase = models.RedReady
 		}
-	case models.BlueReady:
-		if team == "red" {
-			// Determinar la primera fase basado en si hay bans
-			if room.BlueTeamHasBans || room.RedTeamHasBans {
-				room.CurrentPhase = models.BanBlue1
-			} else {
-				room.CurrentPhase = models.PickBlue1
-			}
-			// Iniciar timer para la nueva fase
-			s.startTimerForPhase(room)
+		s.setReadyAt(room, team, time.Now().UnixMilli())
+	case models.BlueReady, models.RedReady:
+		if s.isTeamReady(room.CurrentPhase, team) {
+			return s.readyStateError(models.ErrAlreadyReady, "team %s is already ready", team, room.CurrentPhase)
 		}
-	case models.RedReady:
+		s.setReadyAt(room, team, time.Now().UnixMilli())
+		s.startCountdown(room)
+	case models.Countdown:
+		return s.readyStateError(models.ErrAlreadyReady, "team %s is already ready", team, room.CurrentPhase)
+	default:
+		return s.invalidPhaseError("ready", room.CurrentPhase)
+	}
+	return nil
+}
+
+// processUnreadyAction maneja la acción "unready", que deshace un "ready"
+// y cancela la cuenta atrás si ya había empezado
+func (s *RoomService) processUnreadyAction(room *models.Room, team string) error {
+	switch room.CurrentPhase {
+	case models.NoReady:
+		return s.readyStateError(models.ErrNotReady, "team %s is not ready", team, room.CurrentPhase)
+	case models.BlueReady, models.RedReady:
+		if !s.isTeamReady(room.CurrentPhase, team) {
+			return s.readyStateError(models.ErrNotReady, "team %s is not ready", team, room.CurrentPhase)
+		}
+		room.CurrentPhase = models.NoReady
+		s.setReadyAt(room, team, 0)
+	case models.Countdown:
+		s.stopTimer(room)
 		if team == "blue" {
-			// Determinar la primera fase basado en si hay bans
-			if room.BlueTeamHasBans || room.RedTeamHasBans {
-				room.CurrentPhase = models.BanBlue1
-			} else {
-				room.CurrentPhase = models.PickBlue1
-			}
-			// Iniciar timer para la nueva fase
-			s.startTimerForPhase(room)
+			room.CurrentPhase = models.RedReady
+		} else {
+			room.CurrentPhase = models.BlueReady
 		}
+		s.setReadyAt(room, team, 0)
 	default:
-		return fmt.Errorf("ready action not allowed in current phase: %s", room.CurrentPhase)
+		return s.invalidPhaseError("unready", room.CurrentPhase)
 	}
 	return nil
 }
 
-// processChampSelectAction maneja la acción "champ_select" (no afecta la fase)
-func (s *RoomService) processChampSelectAction(room *models.Room, team string, champion string) error {
-	if champion == "" {
-		return fmt.Errorf("champion name is required for champ_select action")
+// setReadyAt guarda cuándo marcó "ready" un equipo, 0 si lo deshizo
+func (s *RoomService) setReadyAt(room *models.Room, team string, at int64) {
+	if team == "blue" {
+		room.Times.BlueReadyAt = at
+	} else {
+		room.Times.RedReadyAt = at
+	}
+}
+
+// isTeamReady indica si el equipo ya marcó "ready" en una fase de lobby
+func (s *RoomService) isTeamReady(phase models.Phase, team string) bool {
+	return phase == models.BlueReady && team == "blue" || phase == models.RedReady && team == "red" || phase == models.Countdown
+}
+
+// readyStateError construye el error de un ready o unready inválido
+func (s *RoomService) readyStateError(code models.ErrorCode, format string, team string, phase models.Phase) error {
+	return models.NewProtocolError(code, format, team).
+		WithDetail(models.DetailTeam, team).
+		WithDetail(models.DetailPhase, string(phase))
+}
+
+// startCountdown empieza la cuenta atrás previa al draft, o el draft
+// directamente si la cuenta atrás está desactivada
+func (s *RoomService) startCountdown(room *models.Room) {
+	if s.countdown <= 0 {
+		s.startDraft(room)
+		return
 	}
+	room.CurrentPhase = models.Countdown
+	s.startTimerForPhase(room)
+}
+
+// startDraft pasa a la primera fase del draft e inicia su timer
+func (s *RoomService) startDraft(room *models.Room) {
+	s.enterFirstPhase(room)
+	s.startTimerForPhase(room)
+}
 
+// enterFirstPhase pasa a la primera fase del draft y avisa a los hooks, sin
+// iniciar el timer
+func (s *RoomService) enterFirstPhase(room *models.Room) {
+	room.CurrentPhase = s.firstDraftPhase(room)
+	room.Times.StartedAt = time.Now().UnixMilli()
+	room.LastActivity.Store(time.Now().UnixNano())
+	for _, hook := range s.startedHooks {
+		hook(room)
+	}
+}
+
+// firstDraftPhase determina la primera fase basado en si hay bans
+func (s *RoomService) firstDraftPhase(room *models.Room) models.Phase {
+	first, _ := models.NextPhase(models.Countdown, room.BlueTeamHasBans || room.RedTeamHasBans, room.TradePhase)
+	return first
+}
+
+// checkSelectable comprueba que el equipo esté en su turno y que el campeón
+// siga disponible (incluso para selección temporal)
+func (s *RoomService) checkSelectable(room *models.Room, team string, champion string) error {
 	// Verificar si el equipo puede actuar en esta fase
 	canAct := false
 	if team == "blue" && (s.isBluePhase(room.CurrentPhase)) {
@@ -337,30 +674,50 @@ func (s *RoomService) processChampSelectAction(room *models.Room, team string, c
 	}
 
 	if !canAct {
-		return fmt.Errorf("team %s cannot act in phase %s", team, room.CurrentPhase)
+		return models.NewProtocolError(models.ErrNotYourTurn, "team %s cannot act in phase %s", team, room.CurrentPhase).
+			WithDetail(models.DetailTeam, team).
+			WithDetail(models.DetailPhase, string(room.CurrentPhase))
 	}
 
-	// Verificar que el campeón no esté ya baneado o pickeado (incluso para selección temporal)
+	// Verificar que el campeón no esté ya baneado o pickeado
 	if s.isChampionBanned(room, champion, -1) {
-		return fmt.Errorf("champion %s is already banned", champion)
+		return s.championError(models.ErrChampionBanned, "champion %s is already banned", champion, room.CurrentPhase)
 	}
 	
 	if s.isChampionPicked(room, champion, -1) {
-		return fmt.Errorf("champion %s is already picked", champion)
+		return s.championError(models.ErrChampionPicked, "champion %s is already picked", champion, room.CurrentPhase)
 	}
 	
 	// Verificar que el campeón no esté en los fearless bans
 	if s.isChampionInFearlessBans(room, champion) {
-		return fmt.Errorf("champion %s is disabled (fearless ban)", champion)
+		return s.championError(models.ErrChampionFearless, "champion %s is disabled (fearless ban)", champion, room.CurrentPhase)
+	}
+
+	// Verificar que el campeón esté en el pool del torneo, si lo hay
+	if !s.isChampionInPool(room, champion) {
+		return s.championError(models.ErrChampionNotInPool, "champion %s is not in the champion pool", champion, room.CurrentPhase)
+	}
+	return nil
+}
+
+// processChampSelectAction maneja la acción "champ_select" (no afecta la fase)
+func (s *RoomService) processChampSelectAction(room *models.Room, team string, champion string) error {
+	if champion == "" {
+		return models.NewProtocolError(models.ErrChampionRequired, "champion name is required for champ_select action").
+			WithDetail(models.DetailAction, "champ_select")
+	}
+
+	if err := s.checkSelectable(room, team, champion); err != nil {
+		return err
 	}
 	
 	// Obtener la posición correspondiente a la fase actual
 	position := s.getPhasePosition(room.CurrentPhase)
 	if position == -1 {
-		return fmt.Errorf("champ_select not allowed in phase %s", room.CurrentPhase)
+		return s.invalidPhaseError("champ_select", room.CurrentPhase)
 	}
 	
-	newChampion := models.Champion{Name: champion}
+	newChampion := models.Champion{Name: champion, Turn: models.PickTurn(room.CurrentPhase)}
 	if s.isBanPhase(room.CurrentPhase) {
 		if team == "blue" {
 			room.BlueTeam.Bans[position] = newChampion
@@ -374,7 +731,7 @@ func (s *RoomService) processChampSelectAction(room *models.Room, team string, c
 			room.RedTeam.Picks[position] = newChampion
 		}
 	} else {
-		return fmt.Errorf("champ_select not allowed in phase %s", room.CurrentPhase)
+		return s.invalidPhaseError("champ_select", room.CurrentPhase)
 	}
 	
 	// La acción champ_select modifica el estado temporalmente
@@ -385,7 +742,8 @@ func (s *RoomService) processChampSelectAction(room *models.Room, team string, c
 // processChampPickAction maneja la acción "champ_pick"
 func (s *RoomService) processChampPickAction(room *models.Room, team string, champion string) error {
 	if champion == "" {
-		return fmt.Errorf("champion name is required for champ_pick action")
+		return models.NewProtocolError(models.ErrChampionRequired, "champion name is required for champ_pick action").
+			WithDetail(models.DetailAction, "champ_pick")
 	}
 
 	// Verificar si el equipo puede actuar en esta fase
@@ -397,31 +755,37 @@ func (s *RoomService) processChampPickAction(room *models.Room, team string, cha
 	}
 
 	if !canAct {
-		return fmt.Errorf("team %s cannot act in phase %s", team, room.CurrentPhase)
+		return models.NewProtocolError(models.ErrNotYourTurn, "team %s cannot act in phase %s", team, room.CurrentPhase).
+			WithDetail(models.DetailTeam, team).
+			WithDetail(models.DetailPhase, string(room.CurrentPhase))
 	}
 	position := s.getPhasePosition(room.CurrentPhase)
 
 	// Verificar que el campeón no esté ya baneado o pickeado
 	if s.isChampionBanned(room, champion, position) {
-		return fmt.Errorf("champion %s is already banned", champion)
+		return s.championError(models.ErrChampionBanned, "champion %s is already banned", champion, room.CurrentPhase)
 	}
 	
 	if s.isChampionPicked(room, champion, position) {
-		return fmt.Errorf("champion %s is already picked", champion)
+		return s.championError(models.ErrChampionPicked, "champion %s is already picked", champion, room.CurrentPhase)
 	}
 	
 	// Verificar que el campeón no esté en los fearless bans
 	if s.isChampionInFearlessBans(room, champion) {
-		return fmt.Errorf("champion %s is disabled (fearless ban)", champion)
+		return s.championError(models.ErrChampionFearless, "champion %s is disabled (fearless ban)", champion, room.CurrentPhase)
+	}
+
+	if !s.isChampionInPool(room, champion) {
+		return s.championError(models.ErrChampionNotInPool, "champion %s is not in the champion pool", champion, room.CurrentPhase)
 	}
 
 	// Obtener la posición correspondiente a la fase actual
 	if position == -1 {
-		return fmt.Errorf("champ_pick not allowed in phase %s", room.CurrentPhase)
+		return s.invalidPhaseError("champ_pick", room.CurrentPhase)
 	}
 	
 	// Añadir el campeón al estado del equipo en la posición específica
-	newChampion := models.Champion{Name: champion}
+	newChampion := models.Champion{Name: champion, Turn: models.PickTurn(room.CurrentPhase)}
 	
 	if s.isBanPhase(room.CurrentPhase) {
 		if team == "blue" {
@@ -436,7 +800,7 @@ func (s *RoomService) processChampPickAction(room *models.Room, team string, cha
 			room.RedTeam.Picks[position] = newChampion
 		}
 	} else {
-		return fmt.Errorf("champ_pick not allowed in phase %s", room.CurrentPhase)
+		return s.invalidPhaseError("champ_pick", room.CurrentPhase)
 	}
 
 	// Avanzar a la siguiente fase (esto ya incluye parar y reiniciar el timer)
@@ -444,6 +808,20 @@ func (s *RoomService) processChampPickAction(room *models.Room, team string, cha
 	return nil
 }
 
+// invalidPhaseError construye el error para una acción no permitida en la fase actual
+func (s *RoomService) invalidPhaseError(action string, phase models.Phase) error {
+	return models.NewProtocolError(models.ErrInvalidPhase, "%s not allowed in phase %s", action, phase).
+		WithDetail(models.DetailAction, action).
+		WithDetail(models.DetailPhase, string(phase))
+}
+
+// championError construye un error de validación de campeón con sus detalles
+func (s *RoomService) championError(code models.ErrorCode, format string, champion string, phase models.Phase) error {
+	return models.NewProtocolError(code, format, champion).
+		WithDetail(models.DetailChampion, champion).
+		WithDetail(models.DetailPhase, string(phase))
+}
+
 // Funciones auxiliares para determinar el tipo de fase
 func (s *RoomService) isBluePhase(phase models.Phase) bool {
 	bluePhases := []models.Phase{
@@ -630,56 +1008,85 @@ func (s *RoomService) isChampionInFearlessBans(room *models.Room, championName s
 	return false
 }
 
+// isChampionInPool verifica si un campeón está permitido; sin pool lo están todos
+func (s *RoomService) isChampionInPool(room *models.Room, championName string) bool {
+	if len(room.ChampionPool) == 0 {
+		return true
+	}
+	championNameLower := strings.ToLower(strings.TrimSpace(championName))
+	for _, champion := range room.ChampionPool {
+		if strings.ToLower(strings.TrimSpace(champion)) == championNameLower {
+			return true
+		}
+	}
+	return false
+}
+
 // advanceToNextPhase avanza a la siguiente fase en la secuencia
 func (s *RoomService) advanceToNextPhase(room *models.Room) {
 	// Parar el timer actual antes de cambiar de fase
 	s.stopTimer(room)
-	
-	// Secuencia completa con bans
-	fullPhaseSequence := []models.Phase{
-		models.BanBlue1, models.BanRed1, models.BanBlue2, models.BanRed2, models.BanBlue3, models.BanRed3,
-		models.PickBlue1, models.PickRed1, models.PickRed2, models.PickBlue2,
-		models.BanRed4, models.BanBlue4, models.BanRed5, models.BanBlue5,
-		models.PickBlue3, models.PickRed3, models.Finished,
-	}
+	s.stampTurn(room, false)
 
-	// Secuencia sin bans (solo picks)
-	noBanPhaseSequence := []models.Phase{
-		models.PickBlue1, models.PickRed1, models.PickRed2, models.PickBlue2, models.PickBlue3, models.PickRed3, models.Finished,
-	}
+	if next, ok := s.nextPhase(room); ok {
+		room.CurrentPhase = next
+		room.LastActivity.Store(time.Now().UnixNano())
 
-	var phaseSequence []models.Phase
-	
-	// Determinar qué secuencia usar basado en si los equipos tienen bans
-	if room.BlueTeamHasBans || room.RedTeamHasBans {
-		// Si al menos un equipo tiene bans, usar secuencia completa
-		phaseSequence = fullPhaseSequence
-	} else {
-		// Si ningún equipo tiene bans, usar secuencia solo de picks
-		phaseSequence = noBanPhaseSequence
-	}
-
-	for i, phase := range phaseSequence {
-		if room.CurrentPhase == phase && i < len(phaseSequence)-1 {
-			room.CurrentPhase = phaseSequence[i+1]
-			
-			// Si la nueva fase es Finished, guardar en Firebase y limpiar de RAM
-			if room.CurrentPhase == models.Finished {
-				s.handleFinishedRoom(room)
-				return // No iniciar timer para fase terminada
-			}
-			break
+		// Si la nueva fase es Finished, guardar en Firebase
+		if room.CurrentPhase == models.Finished {
+			s.handleFinishedRoom(room)
+			return // No iniciar timer para fase terminada
 		}
 	}
-	
+
 	// Iniciar timer para la nueva fase si es una fase de pick/ban
 	s.startTimerForPhase(room)
 }
 
+// nextPhase devuelve la fase que sigue a la actual según models.NextPhase,
+// con la secuencia de bans y los intercambios que tenga la room
+func (s *RoomService) nextPhase(room *models.Room) (models.Phase, bool) {
+	return models.NextPhase(room.CurrentPhase, room.BlueTeamHasBans || room.RedTeamHasBans, room.TradePhase)
+}
+
+// stampTurn guarda en el hueco del turno actual cuándo se bloqueó y cuánto
+// tiempo del turno usó el equipo, y avisa a los hooks del bloqueo
+func (s *RoomService) stampTurn(room *models.Room, timedOut bool) {
+	position := s.getPhasePosition(room.CurrentPhase)
+	if position == -1 {
+		return
+	}
+	team := &room.RedTeam
+	if s.isBluePhase(room.CurrentPhase) {
+		team = &room.BlueTeam
+	}
+	slots := team.Picks
+	if s.isBanPhase(room.CurrentPhase) {
+		slots = team.Bans
+	}
+	if position >= len(slots) {
+		return
+	}
+
+	room.TimerMutex.RLock()
+	startedAt := room.TurnStartedAt
+	room.TimerMutex.RUnlock()
+
+	now := time.Now().UnixMilli()
+	slots[position].LockedAt = now
+	slots[position].TimedOut = timedOut
+	if startedAt != 0 {
+		slots[position].TimeUsed = int(now - startedAt)
+	}
+	for _, hook := range s.lockHooks {
+		hook(room, room.CurrentPhase, slots[position])
+	}
+}
+
 // startTimerForPhase inicia el timer para una fase específica
 func (s *RoomService) startTimerForPhase(room *models.Room) {
-	// Solo iniciar timer para fases de pick y ban
-	if !s.isBanPhase(room.CurrentPhase) && !s.isPickPhase(room.CurrentPhase) {
+	// Solo iniciar timer para fases de pick y ban, la cuenta atrás y los intercambios
+	if !s.isBanPhase(room.CurrentPhase) && !s.isPickPhase(room.CurrentPhase) && room.CurrentPhase != models.Countdown && room.CurrentPhase != models.Trading {
 		return
 	}
 	
@@ -688,7 +1095,11 @@ func (s *RoomService) startTimerForPhase(room *models.Room) {
 	
 	// Determinar el tiempo inicial basado en la fase
 	var initialTime int
-	if s.isBanPhase(room.CurrentPhase) {
+	if room.CurrentPhase == models.Countdown {
+		initialTime = s.countdown
+	} else if room.CurrentPhase == models.Trading {
+		initialTime = room.TradeTime
+	} else if s.isBanPhase(room.CurrentPhase) {
 		initialTime = room.TimePerBan
 	} else {
 		initialTime = room.TimePerPick
@@ -696,14 +1107,17 @@ func (s *RoomService) startTimerForPhase(room *models.Room) {
 	
 	room.TimeRemaining = initialTime
 	room.TimerActive = true
-	
-	// Crear un nuevo canal para cancelar si no existe
-	if room.TimerCancel == nil {
-		room.TimerCancel = make(chan bool, 1)
+	if s.isBanPhase(room.CurrentPhase) || s.isPickPhase(room.CurrentPhase) {
+		room.TurnStartedAt = time.Now().UnixMilli()
 	}
 	
+	// Cada timer tiene su propio canal de cancelación, para que una
+	// cancelación pendiente no detenga al timer siguiente
+	cancel := make(chan bool, 1)
+	room.TimerCancel = cancel
+	
 	// Iniciar el timer en una goroutine
-	go s.runTimer(room)
+	go s.runTimer(room, cancel)
 }
 
 // stopTimer detiene el timer actual
@@ -743,13 +1157,13 @@ func (s *RoomService) resetTimer(room *models.Room) {
 }
 
 // runTimer ejecuta el countdown del timer
-func (s *RoomService) runTimer(room *models.Room) {
+func (s *RoomService) runTimer(room *models.Room, cancel chan bool) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 	
 	for {
 		select {
-		case <-room.TimerCancel:
+		case <-cancel:
 			// Timer cancelado
 			return
 		case <-ticker.C:
@@ -792,24 +1206,50 @@ func (s *RoomService) runTimer(room *models.Room) {
 
 // broadcastRoomUpdate envía el estado actualizado de la room a todos los clientes
 func (s *RoomService) broadcastRoomUpdate(room *models.Room) {
+	s.BroadcastToRoom(room.Id, s.RoomStatus(room))
+}
+
+// RoomStatus construye el mensaje de estado público de una room
+func (s *RoomService) RoomStatus(room *models.Room) models.StatusMessage {
+	room.ClientsMutex.Lock()
+	blueConfirmed := room.TradeConfirmed[models.SeatBlue]
+	redConfirmed := room.TradeConfirmed[models.SeatRed]
+	room.ClientsMutex.Unlock()
+
 	room.TimerMutex.RLock()
-	
-	// Convert team data to only include champion names
-	blueTeamStatus := models.TeamStatus{
-		Name:  room.BlueTeam.Name,
-		Bans:  s.extractChampionNames(room.BlueTeam.Bans),
-		Picks: s.extractChampionNames(room.BlueTeam.Picks),
+	defer room.TimerMutex.RUnlock()
+
+	// Copia de los reportes, el mapa cambia cuando reporta el otro capitán
+	var reports map[string]*models.GameResult
+	if len(room.ResultReports) > 0 {
+		reports = make(map[string]*models.GameResult, len(room.ResultReports))
+		for seat, report := range room.ResultReports {
+			reports[seat] = report
+		}
 	}
-	
-	redTeamStatus := models.TeamStatus{
-		Name:  room.RedTeam.Name,
-		Bans:  s.extractChampionNames(room.RedTeam.Bans),
-		Picks: s.extractChampionNames(room.RedTeam.Picks),
+
+	// Convert team data to only include champion names
+	blueTeamStatus := s.teamStatus(room.BlueTeam, blueConfirmed)
+	redTeamStatus := s.teamStatus(room.RedTeam, redConfirmed)
+
+	// El inicio del turno solo tiene sentido durante los bans y picks
+	var turnStartedAt int64
+	if s.isBanPhase(room.CurrentPhase) || s.isPickPhase(room.CurrentPhase) {
+		turnStartedAt = room.TurnStartedAt
 	}
 	
-	statusMsg := models.StatusMessage{
+	return models.StatusMessage{
 		Type:          "status",
 		CurrentPhase:  room.CurrentPhase,
+		SideChooser:   room.SideChooser,
+		CoinFlip:      room.CoinFlip,
+		Match:         room.Match,
+		Patch:         room.Patch,
+		Result:        room.Result,
+		ResultReports: reports,
+		Times:         room.Times,
+		TurnStartedAt: turnStartedAt,
+		ResultDisputed: room.ResultDisputed,
 		TimePerPick:   room.TimePerPick,
 		TimePerBan:    room.TimePerBan,
 		TimeRemaining: room.TimeRemaining,
@@ -818,9 +1258,52 @@ func (s *RoomService) broadcastRoomUpdate(room *models.Room) {
 		RedTeam:       redTeamStatus,
 		FearlessBans:  s.extractChampionNames(room.FearlessBans),
 	}
-	room.TimerMutex.RUnlock()
-	
-	s.BroadcastToRoom(room.Id, statusMsg)
+}
+
+// teamStatus convierte un equipo al formato de estado con solo los nombres
+// de los campeones, el roster y, si hay alguno asignado, los jugadores de
+// cada pick
+func (s *RoomService) teamStatus(team models.Team, tradeConfirmed bool) models.TeamStatus {
+	status := models.TeamStatus{
+		Name:           team.Name,
+		Roster:         team.Roster,
+		Bans:           s.extractChampionNames(team.Bans),
+		Picks:          s.extractChampionNames(team.Picks),
+		TradeConfirmed: tradeConfirmed,
+	}
+	status.BanTimes = s.extractTimesUsed(team.Bans)
+	status.PickTimes = s.extractTimesUsed(team.Picks)
+	for i, pick := range team.Picks {
+		if pick.Player != "" {
+			if status.Players == nil {
+				status.Players = make([]string, len(team.Picks))
+			}
+			status.Players[i] = pick.Player
+		}
+		if pick.PlayerId != "" {
+			if status.PlayerIds == nil {
+				status.PlayerIds = make([]string, len(team.Picks))
+			}
+			status.PlayerIds[i] = pick.PlayerId
+		}
+	}
+	return status
+}
+
+// extractTimesUsed devuelve los milisegundos usados en el turno de cada
+// hueco, nil si todavía no se ha jugado ninguno
+func (s *RoomService) extractTimesUsed(champions []models.Champion) []int {
+	var times []int
+	for i, champion := range champions {
+		if champion.LockedAt == 0 {
+			continue
+		}
+		if times == nil {
+			times = make([]int, len(champions))
+		}
+		times[i] = champion.TimeUsed
+	}
+	return times
 }
 
 // extractChampionNames extrae solo los nombres de los campeones de una lista de Champion
@@ -839,69 +1322,81 @@ func (s *RoomService) extractChampionNames(champions []models.Champion) []string
 
 // manualAdvanceToNextPhase avanza a la siguiente fase sin manejar timers (para uso interno)
 func (s *RoomService) manualAdvanceToNextPhase(room *models.Room) {
-	// Secuencia completa con bans
-	fullPhaseSequence := []models.Phase{
-		models.BanBlue1, models.BanRed1, models.BanBlue2, models.BanRed2, models.BanBlue3, models.BanRed3,
-		models.PickBlue1, models.PickRed1, models.PickRed2, models.PickBlue2,
-		models.BanRed4, models.BanBlue4, models.BanRed5, models.BanBlue5,
-		models.PickBlue3, models.PickRed3, models.Finished,
+	// Al terminar el tiempo de intercambios termina el draft, salvo que la
+	// última confirmación se haya adelantado al timer y ya lo haya terminado
+	if room.CurrentPhase == models.Trading || room.CurrentPhase == models.Finished {
+		room.ClientsMutex.Lock()
+		finishing := leaveTrading(room)
+		room.ClientsMutex.Unlock()
+		if finishing {
+			s.finishTrading(room)
+		}
+		return
 	}
 
-	// Secuencia sin bans (solo picks)
-	noBanPhaseSequence := []models.Phase{
-		models.PickBlue1, models.PickRed1, models.PickRed2, models.PickBlue2, models.PickBlue3, models.PickRed3, models.Finished,
+	// Al terminar la cuenta atrás empieza el draft
+	if room.CurrentPhase == models.Countdown {
+		s.enterFirstPhase(room)
+		log.Printf("Advanced to phase: %s", room.CurrentPhase)
+		return
 	}
 
-	var phaseSequence []models.Phase
-	
-	// Determinar qué secuencia usar basado en si los equipos tienen bans
-	if room.BlueTeamHasBans || room.RedTeamHasBans {
-		// Si al menos un equipo tiene bans, usar secuencia completa
-		phaseSequence = fullPhaseSequence
-	} else {
-		// Si ningún equipo tiene bans, usar secuencia solo de picks
-		phaseSequence = noBanPhaseSequence
-	}
+	// El timer bloquea el campeón seleccionado, o ninguno
+	s.stampTurn(room, true)
 
-	for i, phase := range phaseSequence {
-		if room.CurrentPhase == phase && i < len(phaseSequence)-1 {
-			room.CurrentPhase = phaseSequence[i+1]
-			log.Printf("Advanced to phase: %s", room.CurrentPhase)
-			
-			// Si la nueva fase es Finished, guardar en Firebase y limpiar de RAM
-			if room.CurrentPhase == models.Finished {
-				s.handleFinishedRoom(room)
-			}
-			break
+	if next, ok := s.nextPhase(room); ok {
+		room.CurrentPhase = next
+		room.LastActivity.Store(time.Now().UnixNano())
+		log.Printf("Advanced to phase: %s", room.CurrentPhase)
+
+		// Si la nueva fase es Finished, guardar en Firebase
+		if room.CurrentPhase == models.Finished {
+			s.handleFinishedRoom(room)
 		}
 	}
 }
 
 // handleFinishedRoom maneja una room que ha terminado el draft
 func (s *RoomService) handleFinishedRoom(room *models.Room) {
-	log.Printf("Draft finished for room %s, saving to Firestore and cleaning from RAM", room.Id)
+	log.Printf("Draft finished for room %s, saving to Firestore", room.Id)
+	room.Times.FinishedAt = time.Now().UnixMilli()
 	
 	// Guardar en Firebase si está configurado
 	if s.firebaseService != nil {
 		log.Printf("Firebase service available, attempting to save room %s", room.Id)
 		if err := s.firebaseService.SaveRoom(room); err != nil {
 			log.Printf("Error saving room %s to Firestore: %v", room.Id, err)
-			return
+		} else {
+			log.Printf("Room %s saved to Firestore successfully", room.Id)
 		}
-		log.Printf("Room %s saved to Firestore successfully", room.Id)
 	} else {
 		log.Printf("Firebase service not available, skipping save for room %s", room.Id)
 	}
-	
-	// Programar limpieza de RAM después de un breve delay para permitir que los clientes reciban el estado final
-	go func() {
-		time.Sleep(5 * time.Second) // Esperar 5 segundos antes de limpiar
-		s.removeRoomFromRAM(room.Id)
-	}()
+
+	for _, hook := range s.finishedHooks {
+		hook(room)
+	}
+
+	// La room se queda en RAM para que los clientes vean el estado final;
+	// el janitor la elimina cuando expira su TTL de Finished
+}
+
+// recordResult guarda el resultado junto al draft y avisa a los hooks
+func (s *RoomService) recordResult(room *models.Room) {
+	if s.firebaseService != nil {
+		if err := s.firebaseService.SaveRoom(room); err != nil {
+			log.Printf("Error saving result of room %s to Firestore: %v", room.Id, err)
+		}
+	}
+	for _, hook := range s.resultHooks {
+		hook(room)
+	}
 }
 
 // removeRoomFromRAM elimina una room de la memoria RAM
 func (s *RoomService) removeRoomFromRAM(roomId string) {
+	s.roomsMutex.Lock()
 	delete(s.rooms, roomId)
+	s.roomsMutex.Unlock()
 	log.Printf("Room %s removed from RAM", roomId)
 }
\ No newline at end of file
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"picks3w2a/internal/models"
+)
+
+// blueBanPhases are the ban phases of the blue team, by ban index
+var blueBanPhases = []models.Phase{models.BanBlue1, models.BanBlue2, models.BanBlue3, models.BanBlue4, models.BanBlue5}
+
+// redBanPhases are the ban phases of the red team, by ban index
+var redBanPhases = []models.Phase{models.BanRed1, models.BanRed2, models.BanRed3, models.BanRed4, models.BanRed5}
+
+// ScoutingReport builds the scouting report of filter.Team, which is
+// required. A non-empty opponent limits the head-to-head history to the
+// drafts against that team.
+func (as *AnalyticsService) ScoutingReport(filter models.DraftFilter, opponent string) (models.ScoutingReport, error) {
+	if strings.TrimSpace(filter.Team) == "" {
+		return models.ScoutingReport{}, models.NewProtocolError(models.ErrValidation, "team: is required").
+			WithDetail(models.DetailField, "team")
+	}
+	drafts, err := as.store.FinishedDrafts(filter)
+	if err != nil {
+		return models.ScoutingReport{}, err
+	}
+	return scoutingReport(drafts, filter, opponent), nil
+}
+
+// championCounter counts the champions of a list of a scouting report
+type championCounter map[string]int
+
+// add counts a champion, skipping empty slots
+func (c championCounter) add(champion string) {
+	if champion != "-1" && champion != "" {
+		c[champion]++
+	}
+}
+
+// list returns the counted champions by count, then name, with their rate
+// over total
+func (c championCounter) list(total int) []models.ChampionCount {
+	counts := make([]models.ChampionCount, 0, len(c))
+	for champion, count := range c {
+		counts = append(counts, models.ChampionCount{Champion: champion, Count: count, Rate: rate(count, total)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Champion < counts[j].Champion
+	})
+	return counts
+}
+
+// scoutingReport sums up the drafts of filter.Team, which must already
+// match filter
+func scoutingReport(drafts []RoomData, filter models.DraftFilter, opponent string) models.ScoutingReport {
+	report := models.ScoutingReport{
+		Team:       filter.Team,
+		Filter:     filter,
+		Drafts:     len(drafts),
+		Phases:     []models.PhaseTendency{},
+		Series:     []models.SeriesDepletion{},
+		HeadToHead: []models.HeadToHead{},
+	}
+	picks, firstPicks, bans, against := championCounter{}, championCounter{}, championCounter{}, championCounter{}
+	phaseChampions := make(map[models.Phase]championCounter)
+	phaseDrafts := make(map[models.Phase]int)
+	addToPhase := func(phase models.Phase, champion string) {
+		if phaseChampions[phase] == nil {
+			phaseChampions[phase] = championCounter{}
+		}
+		phaseChampions[phase].add(champion)
+		phaseDrafts[phase]++
+	}
+	series := make(map[string]*models.SeriesDepletion)
+	var seriesOrder []string
+	headToHead := make(map[string]*models.HeadToHead)
+
+	for _, draft := range drafts {
+		side := draftSide(draft, filter.Team)
+		own, rival := draft.BlueTeam, draft.RedTeam
+		banPhases, pickPhases := blueBanPhases, bluePickPhases
+		if side == models.SeatRed {
+			own, rival = draft.RedTeam, draft.BlueTeam
+			banPhases, pickPhases = redBanPhases, redPickPhases
+			report.RedDrafts++
+		} else {
+			report.BlueDrafts++
+		}
+		result := draftResult(draft, side)
+		switch result {
+		case models.DraftWon:
+			report.Wins++
+		case models.DraftLost:
+			report.Losses++
+		}
+
+		for i, ban := range own.Bans {
+			if ban.Name == "-1" || ban.Name == "" {
+				continue
+			}
+			bans.add(ban.Name)
+			if i < len(banPhases) {
+				addToPhase(banPhases[i], ban.Name)
+			}
+		}
+		for _, ban := range rival.Bans {
+			against.add(ban.Name)
+		}
+
+		first, firstTurn := "", 0
+		for i, pick := range own.Picks {
+			if pick.Name == "-1" || pick.Name == "" {
+				continue
+			}
+			picks.add(pick.Name)
+			turn := pick.Turn
+			if turn == 0 && i < len(pickPhases) {
+				turn = models.PickTurn(pickPhases[i])
+			}
+			if turn > 0 && turn <= len(models.PickPhases) {
+				addToPhase(models.PickPhases[turn-1], pick.Name)
+			}
+			if first == "" || turn > 0 && turn < firstTurn {
+				first, firstTurn = pick.Name, turn
+			}
+		}
+		firstPicks.add(first)
+
+		if draft.Match != nil {
+			key := draft.Match.TournamentId + "/" + draft.Match.MatchId
+			if series[key] == nil {
+				series[key] = &models.SeriesDepletion{
+					TournamentId: draft.Match.TournamentId,
+					MatchId:      draft.Match.MatchId,
+					Opponent:     rival.Name,
+				}
+				seriesOrder = append(seriesOrder, key)
+			}
+			series[key].Games = append(series[key].Games, models.SeriesGame{
+				Game:         draft.Match.Game,
+				RoomId:       draft.Id,
+				Side:         side,
+				FearlessBans: len(lockedNames(draft.FearlessBans)),
+				Picks:        lockedNames(own.Picks),
+				Result:       result,
+			})
+		}
+
+		if opponent != "" && !strings.EqualFold(rival.Name, opponent) {
+			continue
+		}
+		key := strings.ToLower(rival.Name)
+		if headToHead[key] == nil {
+			headToHead[key] = &models.HeadToHead{Opponent: rival.Name}
+		}
+		record := headToHead[key]
+		record.Drafts++
+		switch result {
+		case models.DraftWon:
+			record.Wins++
+		case models.DraftLost:
+			record.Losses++
+		}
+		// Los drafts llegan del más antiguo al más reciente
+		record.History = append([]models.DraftSummary{{
+			RoomId:        draft.Id,
+			CompletedAt:   draft.CompletedAt,
+			Patch:         draft.Patch,
+			Match:         draft.Match,
+			Side:          side,
+			Result:        result,
+			Picks:         lockedNames(own.Picks),
+			Bans:          lockedNames(own.Bans),
+			OpponentPicks: lockedNames(rival.Picks),
+			OpponentBans:  lockedNames(rival.Bans),
+		}}, record.History...)
+	}
+
+	report.Picks = picks.list(report.Drafts)
+	report.FirstPicks = firstPicks.list(report.Drafts)
+	report.Bans = bans.list(report.Drafts)
+	report.BannedAgainst = against.list(report.Drafts)
+	for _, phase := range models.AllPhases {
+		if phaseDrafts[phase] > 0 {
+			report.Phases = append(report.Phases, models.PhaseTendency{
+				Phase:     phase,
+				Drafts:    phaseDrafts[phase],
+				Champions: phaseChampions[phase].list(phaseDrafts[phase]),
+			})
+		}
+	}
+	report.Series = fearlessSeries(series, seriesOrder)
+	for _, record := range headToHead {
+		report.HeadToHead = append(report.HeadToHead, *record)
+	}
+	sort.Slice(report.HeadToHead, func(i, j int) bool {
+		a, b := report.HeadToHead[i], report.HeadToHead[j]
+		if a.Drafts != b.Drafts {
+			return a.Drafts > b.Drafts
+		}
+		return a.Opponent < b.Opponent
+	})
+	report.Timing = teamTiming(drafts, filter.Team)
+	return report
+}
+
+// fearlessSeries keeps the series with fearless bans in any of their games,
+// with the games in order and the champions the team used up
+func fearlessSeries(series map[string]*models.SeriesDepletion, order []string) []models.SeriesDepletion {
+	fearless := []models.SeriesDepletion{}
+	for _, key := range order {
+		s := series[key]
+		isFearless := false
+		for _, game := range s.Games {
+			if game.FearlessBans > 0 {
+				isFearless = true
+			}
+		}
+		if !isFearless {
+			continue
+		}
+		sort.Slice(s.Games, func(i, j int) bool { return s.Games[i].Game < s.Games[j].Game })
+		seen := make(map[string]bool)
+		s.Depleted = []string{}
+		for _, game := range s.Games {
+			for _, pick := range game.Picks {
+				if !seen[pick] {
+					seen[pick] = true
+					s.Depleted = append(s.Depleted, pick)
+				}
+			}
+		}
+		fearless = append(fearless, *s)
+	}
+	return fearless
+}
+
+// draftResult returns whether the team on side won or lost a draft, "" if
+// no result was reported
+func draftResult(draft RoomData, side string) string {
+	switch {
+	case draft.Result == nil:
+		return ""
+	case draft.Result.Winner == side:
+		return models.DraftWon
+	}
+	return models.DraftLost
+}
+
+// lockedNames lists the names of the locked champions, skipping empty slots
+func lockedNames(champions []models.Champion) []string {
+	names := []string{}
+	for _, champion := range champions {
+		if champion.Name != "-1" && champion.Name != "" {
+			names = append(names, champion.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"log"
+	"sort"
+
+	"github.com/gorilla/websocket"
+	"picks3w2a/internal/models"
+)
+
+// assignRole decides the role of a client joining a team. There is a single
+// captain per team; without a requested role the captain seat is taken if it
+// is free. Must be called with room.ClientsMutex held.
+func (s *RoomService) assignRole(room *models.Room, conn *websocket.Conn, team, requested string) (string, error) {
+	if team == "" {
+		return "", nil // Spectators and the referee have no team role
+	}
+
+	captainTaken := false
+	for c, client := range room.Clients {
+		if c != conn && client.Team == team && client.Role == models.RoleCaptain {
+			captainTaken = true
+			break
+		}
+	}
+
+	switch {
+	case requested == "" && captainTaken:
+		return models.RolePlayer, nil
+	case requested == "":
+		return models.RoleCaptain, nil
+	case requested == models.RoleCaptain && captainTaken:
+		return "", models.NewProtocolError(models.ErrSeatTaken, "team %s already has a captain", team).
+			WithDetail(models.DetailTeam, team).
+			WithDetail(models.DetailRole, models.RoleCaptain)
+	}
+	return requested, nil
+}
+
+// requireCaptain returns an error if the client is not its team captain
+func (s *RoomService) requireCaptain(client *models.Client, action string) error {
+	if client.Role == models.RoleCaptain {
+		return nil
+	}
+	return models.NewProtocolError(models.ErrCaptainOnly, "only the team captain can %s", action).
+		WithDetail(models.DetailAction, action).
+		WithDetail(models.DetailRole, client.Role)
+}
+
+// processTeamHover shares the champion hovered by a player or coach with
+// their own team, without changing the draft
+func (s *RoomService) processTeamHover(room *models.Room, client *models.Client, champion string) error {
+	if err := s.checkSelectable(room, client.Team, champion); err != nil {
+		return err
+	}
+
+	hover := models.TeamHoverMessage{
+		Type:     "team_hover",
+		Team:     client.Team,
+		Nickname: client.Nickname,
+		Role:     client.Role,
+		Champion: champion,
+		Phase:    room.CurrentPhase,
+	}
+	s.broadcastToTeam(room, client.Team, hover)
+	return nil
+}
+
+// broadcastToTeam sends a message to the members of a team only
+func (s *RoomService) broadcastToTeam(room *models.Room, team string, message interface{}) {
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
+	for conn, client := range room.Clients {
+		if client.Team != team {
+			continue
+		}
+		if err := WriteJSON(conn, message); err != nil {
+			log.Printf("Error enviando mensaje a cliente: %v", err)
+		}
+	}
+}
+
+// presence builds the presence list of a room. Must be called with
+// room.ClientsMutex held.
+func (s *RoomService) presence(room *models.Room) models.PresenceMessage {
+	msg := models.PresenceMessage{
+		Type:    "presence",
+		RoomId:  room.Id,
+		Members: []models.PresenceEntry{},
+	}
+	for _, client := range room.Clients {
+		switch {
+		case client.Referee:
+			msg.Members = append(msg.Members, models.PresenceEntry{Nickname: client.Nickname, Role: models.SeatReferee})
+		case client.Team != "":
+			msg.Members = append(msg.Members, models.PresenceEntry{Nickname: client.Nickname, Team: client.Team, Role: client.Role})
+		default:
+			msg.Spectators++
+		}
+	}
+
+	// Stable order so the list does not jump around in the clients
+	sort.Slice(msg.Members, func(i, j int) bool {
+		a, b := msg.Members[i], msg.Members[j]
+		if a.Team != b.Team {
+			return a.Team < b.Team
+		}
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		return a.Nickname < b.Nickname
+	})
+	return msg
+}
+
+// broadcastPresenceLocked sends the presence list to everyone in the room.
+// Must be called with room.ClientsMutex held.
+func (s *RoomService) broadcastPresenceLocked(room *models.Room) {
+	msg := s.presence(room)
+	for conn := range room.Clients {
+		if err := WriteJSON(conn, msg); err != nil {
+			log.Printf("Error enviando mensaje a cliente: %v", err)
+		}
+	}
+}
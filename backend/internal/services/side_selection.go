@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// setupSideSelection prepares the optional side selection phase of a new
+// room. Without a designated chooser a coin flip is committed to now and
+// revealed once both captains are connected.
+func (s *RoomService) setupSideSelection(room *models.Room, createMsg models.CreateMessage) {
+	if !createMsg.SideSelection {
+		return
+	}
+	room.CurrentPhase = models.SideSelection
+	room.SideChooser = createMsg.SideChooser
+	if room.SideChooser != "" {
+		return
+	}
+
+	seed := make([]byte, 32)
+	rand.Read(seed)
+	commitment := sha256.Sum256(seed)
+	room.CoinFlipSeed = hex.EncodeToString(seed)
+	room.CoinFlip = &models.CoinFlip{Commitment: hex.EncodeToString(commitment[:])}
+}
+
+// coinFlipWinner derives the winner of a coin flip from its seed
+func coinFlipWinner(seed []byte, roomId string) string {
+	sum := sha256.Sum256(append(append([]byte{}, seed...), roomId...))
+	if sum[0]%2 == 0 {
+		return models.SeatBlue
+	}
+	return models.SeatRed
+}
+
+// revealCoinFlipLocked flips the coin once both teams have a captain in the
+// room. Must be called with room.ClientsMutex held.
+func (s *RoomService) revealCoinFlipLocked(room *models.Room) {
+	if room.CurrentPhase != models.SideSelection || room.SideChooser != "" || room.CoinFlip == nil {
+		return
+	}
+	captains := map[string]bool{}
+	for _, client := range room.Clients {
+		if client.Role == models.RoleCaptain {
+			captains[client.Team] = true
+		}
+	}
+	if !captains[models.SeatBlue] || !captains[models.SeatRed] {
+		return
+	}
+
+	seed, _ := hex.DecodeString(room.CoinFlipSeed)
+	room.CoinFlip.Seed = room.CoinFlipSeed
+	room.CoinFlip.Winner = coinFlipWinner(seed, room.Id)
+	room.SideChooser = room.CoinFlip.Winner
+	appendHistoryLocked(room, models.HistoryEntry{
+		At:   time.Now().UnixMilli(),
+		Kind: models.HistoryCoinFlip,
+		Team: room.CoinFlip.Winner,
+		Text: room.CoinFlip.Seed,
+	})
+	log.Printf("Coin flip of room %s won by %s", room.Id, room.CoinFlip.Winner)
+}
+
+// processChooseSide handles the "choose_side" action of the side chooser's
+// captain or the referee
+func (s *RoomService) processChooseSide(room *models.Room, client *models.Client, side string) error {
+	if room.CurrentPhase != models.SideSelection {
+		return s.invalidPhaseError("choose_side", room.CurrentPhase)
+	}
+
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
+
+	// El equipo que elige se guarda como se introdujo al crear la room, antes
+	// de cualquier cambio de lado
+	chooser := room.SideChooser
+	if chooser == "" {
+		return models.NewProtocolError(models.ErrNotYourTurn, "the coin flip happens when both captains are connected").
+			WithDetail(models.DetailPhase, string(room.CurrentPhase))
+	}
+	if !client.Referee {
+		if client.Team != chooser {
+			return models.NewProtocolError(models.ErrNotYourTurn, "team %s picks side", chooser).
+				WithDetail(models.DetailTeam, client.Team).
+				WithDetail(models.DetailPhase, string(room.CurrentPhase))
+		}
+		if err := s.requireCaptain(client, "choose_side"); err != nil {
+			return err
+		}
+	}
+
+	swapped := side != chooser
+	if swapped {
+		s.swapSidesLocked(room)
+	}
+	room.CurrentPhase, _ = s.nextPhase(room)
+	room.LastActivity.Store(time.Now().UnixNano())
+	appendHistoryLocked(room, models.HistoryEntry{
+		At:   time.Now().UnixMilli(),
+		Kind: models.HistorySideSelected,
+		Team: chooser,
+		Side: side,
+	})
+
+	selected := models.SideSelectedMessage{
+		Type:    "side_selected",
+		Chooser: chooser,
+		Side:    side,
+		Swapped: swapped,
+	}
+	for conn := range room.Clients {
+		if err := WriteJSON(conn, selected); err != nil {
+			log.Printf("Error enviando mensaje a cliente: %v", err)
+		}
+	}
+	s.broadcastPresenceLocked(room)
+
+	log.Printf("Team %s chose side %s in room %s", chooser, side, room.Id)
+	return nil
+}
+
+// swapSidesLocked swaps the teams between blue and red, with their names,
+// settings, keys and connected clients. Must be called with
+// room.ClientsMutex held.
+func (s *RoomService) swapSidesLocked(room *models.Room) {
+	room.BlueTeamName, room.RedTeamName = room.RedTeamName, room.BlueTeamName
+	room.BlueTeamHasBans, room.RedTeamHasBans = room.RedTeamHasBans, room.BlueTeamHasBans
+	room.BlueTeamKeyHash, room.RedTeamKeyHash = room.RedTeamKeyHash, room.BlueTeamKeyHash
+	room.BlueTeam, room.RedTeam = room.RedTeam, room.BlueTeam
+	if room.SeatGenerations != nil {
+		blue, red := room.SeatGenerations[models.SeatBlue], room.SeatGenerations[models.SeatRed]
+		room.SeatGenerations[models.SeatBlue], room.SeatGenerations[models.SeatRed] = red, blue
+	}
+	room.SidesSwapped = !room.SidesSwapped
+
+	for _, client := range room.Clients {
+		client.Team = otherSide(client.Team)
+	}
+}
+
+// otherSide returns the opposite side; anything else is returned unchanged
+func otherSide(side string) string {
+	switch side {
+	case models.SeatBlue:
+		return models.SeatRed
+	case models.SeatRed:
+		return models.SeatBlue
+	}
+	return side
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"picks3w2a/internal/models"
+)
+
+// TeamChat delivers a chat line to the sender's team and records it in the
+// room history
+func (s *RoomService) TeamChat(roomId string, conn *websocket.Conn, chatMsg models.TeamChatMessage) error {
+	room, client, err := s.teamMember(roomId, conn)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.postToTeam(room, client, models.HistoryEntry{
+		At:       now.UnixMilli(),
+		Kind:     models.HistoryTeamChat,
+		Team:     client.Team,
+		Nickname: client.Nickname,
+		Role:     client.Role,
+		Text:     chatMsg.Text,
+	}, models.TeamChatPostedMessage{
+		Type:     "team_chat",
+		Team:     client.Team,
+		Nickname: client.Nickname,
+		Role:     client.Role,
+		Text:     chatMsg.Text,
+		At:       now.UnixMilli(),
+	})
+	return nil
+}
+
+// Suggest proposes a champion to the sender's team and records it in the
+// room history. Unlike a hover it does not depend on the team's turn.
+func (s *RoomService) Suggest(roomId string, conn *websocket.Conn, suggestMsg models.SuggestMessage) error {
+	room, client, err := s.teamMember(roomId, conn)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	s.postToTeam(room, client, models.HistoryEntry{
+		At:       now.UnixMilli(),
+		Kind:     models.HistorySuggestion,
+		Team:     client.Team,
+		Nickname: client.Nickname,
+		Role:     client.Role,
+		Text:     suggestMsg.Text,
+		Champion: suggestMsg.Champion,
+		Phase:    room.CurrentPhase,
+	}, models.SuggestionPostedMessage{
+		Type:     "suggestion",
+		Team:     client.Team,
+		Nickname: client.Nickname,
+		Role:     client.Role,
+		Champion: suggestMsg.Champion,
+		Text:     suggestMsg.Text,
+		Phase:    room.CurrentPhase,
+		At:       now.UnixMilli(),
+	})
+	return nil
+}
+
+// teamMember returns the room and client of a connection that belongs to a
+// team; spectators and the referee have no team channel
+func (s *RoomService) teamMember(roomId string, conn *websocket.Conn) (*models.Room, *models.Client, error) {
+	room, exists := s.lookupRoom(roomId)
+	if !exists {
+		return nil, nil, models.NewProtocolError(models.ErrRoomNotFound, "room %s not found", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	room.ClientsMutex.Lock()
+	client, exists := room.Clients[conn]
+	room.ClientsMutex.Unlock()
+	if !exists {
+		return nil, nil, models.NewProtocolError(models.ErrClientNotFound, "client not found in room %s", roomId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+	if client.Team == "" {
+		return nil, nil, models.NewProtocolError(models.ErrSpectatorAction, "only team members can use the team channel")
+	}
+	return room, client, nil
+}
+
+// postToTeam records entry in the room history and sends message to the
+// connections of the client's team only
+func (s *RoomService) postToTeam(room *models.Room, client *models.Client, entry models.HistoryEntry, message interface{}) {
+	room.ClientsMutex.Lock()
+	defer room.ClientsMutex.Unlock()
+
+	appendHistoryLocked(room, entry)
+	for conn, member := range room.Clients {
+		if member.Team != client.Team {
+			continue
+		}
+		if err := WriteJSON(conn, message); err != nil {
+			log.Printf("Error enviando mensaje a cliente: %v", err)
+		}
+	}
+}
+
+// appendHistoryLocked adds an entry to the room history, dropping the oldest
+// entries beyond MaxHistoryEntries. Must be called with room.ClientsMutex held.
+func appendHistoryLocked(room *models.Room, entry models.HistoryEntry) {
+	room.History = append(room.History, entry)
+	if excess := len(room.History) - models.MaxHistoryEntries; excess > 0 {
+		room.History = append(room.History[:0:0], room.History[excess:]...)
+	}
+}
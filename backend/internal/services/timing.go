@@ -0,0 +1,138 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"picks3w2a/internal/models"
+)
+
+// TimingReport computes how long each team takes over its turns in the
+// drafts matching filter, leaving out teams without recorded turn times
+func (as *AnalyticsService) TimingReport(filter models.DraftFilter) (models.TimingReport, error) {
+	drafts, err := as.store.FinishedDrafts(filter)
+	if err != nil {
+		return models.TimingReport{}, err
+	}
+	return timingReport(drafts, filter), nil
+}
+
+// timingTally accumulates the turn times of a team, in milliseconds
+type timingTally struct {
+	timing     models.TeamTiming
+	pickSum    int
+	pickMax    int
+	banSum     int
+	banMax     int
+	draftSum   int64
+	draftCount int
+}
+
+// add counts the turns of team, which played roomData. Slots never locked,
+// as in drafts saved before turn times were recorded, are skipped.
+func (t *timingTally) add(roomData RoomData, team models.Team) {
+	timed := false
+	for _, ban := range team.Bans {
+		if ban.LockedAt == 0 {
+			continue
+		}
+		timed = true
+		t.timing.Bans++
+		t.banSum += ban.TimeUsed
+		t.banMax = max(t.banMax, ban.TimeUsed)
+		if ban.TimedOut {
+			t.timing.Timeouts++
+		}
+	}
+	for _, pick := range team.Picks {
+		if pick.LockedAt == 0 {
+			continue
+		}
+		timed = true
+		t.timing.Picks++
+		t.pickSum += pick.TimeUsed
+		t.pickMax = max(t.pickMax, pick.TimeUsed)
+		if pick.TimedOut {
+			t.timing.Timeouts++
+		}
+	}
+	if !timed {
+		return
+	}
+	t.timing.Drafts++
+	if times := roomData.Times; times.StartedAt != 0 && times.FinishedAt > times.StartedAt {
+		t.draftSum += times.FinishedAt - times.StartedAt
+		t.draftCount++
+	}
+}
+
+// result returns the timing of the team in seconds
+func (t *timingTally) result() models.TeamTiming {
+	timing := t.timing
+	timing.AvgPickTime = seconds(int64(t.pickSum), timing.Picks)
+	timing.MaxPickTime = seconds(int64(t.pickMax), 1)
+	timing.AvgBanTime = seconds(int64(t.banSum), timing.Bans)
+	timing.MaxBanTime = seconds(int64(t.banMax), 1)
+	timing.AvgDraftTime = seconds(t.draftSum, t.draftCount)
+	return timing
+}
+
+// seconds returns the average of n times adding up to ms milliseconds, in
+// seconds rounded to the hundredth
+func seconds(ms int64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return math.Round(float64(ms)/float64(n)/10) / 100
+}
+
+// timingReport tallies drafts, which must already match filter. Teams are
+// grouped by name, case-insensitively, under the first spelling found.
+func timingReport(drafts []RoomData, filter models.DraftFilter) models.TimingReport {
+	report := models.TimingReport{Filter: filter, Drafts: len(drafts), Teams: []models.TeamTiming{}}
+	tallies := make(map[string]*timingTally)
+	for _, draft := range drafts {
+		sides := []string{models.SeatBlue, models.SeatRed}
+		if filter.Team != "" {
+			sides = []string{draftSide(draft, filter.Team)}
+		}
+		for _, side := range sides {
+			team := draft.BlueTeam
+			if side == models.SeatRed {
+				team = draft.RedTeam
+			}
+			key := strings.ToLower(strings.TrimSpace(team.Name))
+			tally, exists := tallies[key]
+			if !exists {
+				tally = &timingTally{timing: models.TeamTiming{Team: team.Name}}
+				tallies[key] = tally
+			}
+			tally.add(draft, team)
+		}
+	}
+
+	for _, tally := range tallies {
+		if tally.timing.Drafts > 0 {
+			report.Teams = append(report.Teams, tally.result())
+		}
+	}
+	sort.Slice(report.Teams, func(i, j int) bool {
+		return strings.ToLower(report.Teams[i].Team) < strings.ToLower(report.Teams[j].Team)
+	})
+	return report
+}
+
+// teamTiming computes the timing of a team over drafts it played
+func teamTiming(drafts []RoomData, name string) models.TeamTiming {
+	tally := timingTally{timing: models.TeamTiming{Team: name}}
+	for _, draft := range drafts {
+		switch draftSide(draft, name) {
+		case models.SeatBlue:
+			tally.add(draft, draft.BlueTeam)
+		case models.SeatRed:
+			tally.add(draft, draft.RedTeam)
+		}
+	}
+	return tally.result()
+}
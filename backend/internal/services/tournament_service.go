@@ -0,0 +1,675 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"picks3w2a/internal/models"
+
+	"github.com/gorilla/websocket"
+)
+
+// TournamentService keeps the registry of tournaments with their teams,
+// brackets and matches, persisted to a JSON file, creates the draft rooms of
+// the games and feeds the standings to the connections watching them
+type TournamentService struct {
+	mu          sync.Mutex
+	tournaments map[string]*models.Tournament
+	path        string // JSON file, the registry is only kept in memory if empty
+	roomService *RoomService
+
+	// Connections watching the standings of a tournament. Taken after mu.
+	watchers map[string]map[*websocket.Conn]*standingsWatcher
+	watchMu  sync.Mutex
+}
+
+// tournamentsFile is the content of the registry file
+type tournamentsFile struct {
+	Tournaments []*models.Tournament `json:"tournaments"`
+}
+
+// NewTournamentService loads the registry from path and links the drafts
+// finished by roomService back to their matches
+func NewTournamentService(path string, roomService *RoomService) (*TournamentService, error) {
+	ts := &TournamentService{
+		tournaments: make(map[string]*models.Tournament),
+		path:        path,
+		roomService: roomService,
+		watchers:    make(map[string]map[*websocket.Conn]*standingsWatcher),
+	}
+	if path == "" {
+		log.Println("Warning: TOURNAMENTS_FILE is empty, tournaments are lost when the server restarts")
+	} else if err := ts.load(); err != nil {
+		return nil, err
+	}
+	roomService.OnDraftFinished(ts.recordDraft)
+	roomService.OnGameResult(ts.recordResult)
+	roomService.ReserveRoomIds(ts.reservesRoom)
+	return ts, nil
+}
+
+// load reads the registry file, which may not exist yet
+func (ts *TournamentService) load() error {
+	data, err := os.ReadFile(ts.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading tournaments file: %v", err)
+	}
+	var file tournamentsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("error parsing tournaments file %s: %v", ts.path, err)
+	}
+	for _, tournament := range file.Tournaments {
+		ts.tournaments[tournament.Id] = tournament
+	}
+	log.Printf("Loaded %d tournaments from %s", len(file.Tournaments), ts.path)
+	return nil
+}
+
+// save writes the registry file atomically. Must be called with mu held.
+// Failures are logged: the registry in memory stays authoritative and is
+// written in full by the next successful save.
+func (ts *TournamentService) save() {
+	if ts.path == "" {
+		return
+	}
+	file := tournamentsFile{Tournaments: ts.sorted()}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding tournaments: %v", err)
+		return
+	}
+
+	if err := writeFileAtomic(ts.path, data); err != nil {
+		log.Printf("Error saving tournaments: %v", err)
+	}
+}
+
+// sorted lists the tournaments by creation time. Must be called with mu held.
+func (ts *TournamentService) sorted() []*models.Tournament {
+	tournaments := make([]*models.Tournament, 0, len(ts.tournaments))
+	for _, tournament := range ts.tournaments {
+		tournaments = append(tournaments, tournament)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		if tournaments[i].CreatedAt != tournaments[j].CreatedAt {
+			return tournaments[i].CreatedAt < tournaments[j].CreatedAt
+		}
+		return tournaments[i].Id < tournaments[j].Id
+	})
+	return tournaments
+}
+
+// Tournaments lists all tournaments, oldest first
+func (ts *TournamentService) Tournaments() []models.Tournament {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournaments := make([]models.Tournament, 0, len(ts.tournaments))
+	for _, tournament := range ts.sorted() {
+		tournaments = append(tournaments, cloneTournament(tournament))
+	}
+	return tournaments
+}
+
+// Tournament returns a tournament by ID
+func (ts *TournamentService) Tournament(tournamentId string) (models.Tournament, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.Tournament{}, err
+	}
+	return cloneTournament(tournament), nil
+}
+
+// Match returns a match of a tournament by ID
+func (ts *TournamentService) Match(tournamentId, matchId string) (models.Match, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.Match{}, err
+	}
+	match, err := findMatch(tournament, matchId)
+	if err != nil {
+		return models.Match{}, err
+	}
+	return cloneMatch(*match), nil
+}
+
+// CreateTournament registers a new tournament
+func (ts *TournamentService) CreateTournament(req models.CreateTournamentRequest) (models.Tournament, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	id := req.Id
+	if id == "" {
+		id = ts.roomService.generateRandomID()
+	}
+	if _, exists := ts.tournaments[id]; exists {
+		return models.Tournament{}, duplicateIdError("tournament", id)
+	}
+
+	tournament := &models.Tournament{
+		Id:           id,
+		Name:         req.Name,
+		Draft:        req.Draft,
+		ChampionPool: req.ChampionPool,
+		Teams:        []models.TournamentTeam{},
+		Matches:      []models.Match{},
+		CreatedAt:    time.Now().Unix(),
+	}
+	ts.tournaments[id] = tournament
+	ts.save()
+	return cloneTournament(tournament), nil
+}
+
+// RegisterTeam adds a team to a tournament
+func (ts *TournamentService) RegisterTeam(tournamentId string, req models.RegisterTeamRequest) (models.TournamentTeam, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.TournamentTeam{}, err
+	}
+	id := req.Id
+	if id == "" {
+		id = ts.roomService.generateRandomID()
+	}
+	if findTeam(tournament, id) != nil {
+		return models.TournamentTeam{}, duplicateIdError("team", id)
+	}
+
+	team := models.TournamentTeam{
+		Id:     id,
+		Name:   req.Name,
+		Tag:    req.Tag,
+		Logo:   req.Logo,
+		Roster: req.Roster,
+	}
+	tournament.Teams = append(tournament.Teams, team)
+	ts.save()
+	ts.broadcastStandings(tournament)
+	return team, nil
+}
+
+// ScheduleMatch adds a match between two registered teams to a tournament.
+// Match IDs are unique across tournaments because they name the game rooms.
+func (ts *TournamentService) ScheduleMatch(tournamentId string, req models.ScheduleMatchRequest) (models.Match, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.Match{}, err
+	}
+	for _, teamId := range []string{req.BlueTeamId, req.RedTeamId} {
+		if findTeam(tournament, teamId) == nil {
+			return models.Match{}, teamNotFoundError(teamId)
+		}
+	}
+	id := req.Id
+	if id == "" {
+		id = ts.roomService.generateRandomID()
+	}
+	for _, other := range ts.tournaments {
+		if _, err := findMatch(other, id); err == nil {
+			return models.Match{}, duplicateIdError("match", id)
+		}
+	}
+
+	match := models.Match{
+		Id:            id,
+		BlueTeamId:    req.BlueTeamId,
+		RedTeamId:     req.RedTeamId,
+		BestOf:        req.BestOf,
+		Fearless:      req.Fearless,
+		SideSelection: req.SideSelection,
+		ScheduledAt:   req.ScheduledAt,
+		Status:        models.MatchScheduled,
+		Games:         []models.MatchGame{},
+	}
+	if match.BestOf == 0 {
+		match.BestOf = 1
+	}
+	tournament.Matches = append(tournament.Matches, match)
+	ts.save()
+	ts.broadcastStandings(tournament)
+	return cloneMatch(match), nil
+}
+
+// StartGame creates the room of the next game of a match, filled in with the
+// teams, rosters, draft settings and champion pool of the tournament and, in
+// fearless matches, the picks of the previous games as fearless bans
+func (ts *TournamentService) StartGame(tournamentId, matchId string, req models.StartGameRequest) (models.GameCreatedResponse, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		return models.GameCreatedResponse{}, err
+	}
+	match, err := findMatch(tournament, matchId)
+	if err != nil {
+		return models.GameCreatedResponse{}, err
+	}
+	if match.BlueTeamId == "" || match.RedTeamId == "" {
+		return models.GameCreatedResponse{}, models.NewProtocolError(models.ErrMatchNotReady, "match %s is waiting for the matches before it", match.Id).
+			WithDetail(models.DetailId, match.Id)
+	}
+
+	number, err := ts.nextGame(match)
+	if err != nil {
+		return models.GameCreatedResponse{}, err
+	}
+
+	blueId, redId := match.BlueTeamId, match.RedTeamId
+	if req.BlueTeamId == redId {
+		blueId, redId = redId, blueId
+	} else if req.BlueTeamId != "" && req.BlueTeamId != blueId {
+		return models.GameCreatedResponse{}, models.NewProtocolError(models.ErrTeamNotFound, "team %s does not play match %s", req.BlueTeamId, match.Id).
+			WithDetail(models.DetailTeam, req.BlueTeamId)
+	}
+	blueTeam, redTeam := findTeam(tournament, blueId), findTeam(tournament, redId)
+
+	roomId := gameRoomId(match, number)
+
+	createMsg := models.CreateMessage{
+		Type:            "create",
+		BlueTeamName:    blueTeam.Name,
+		RedTeamName:     redTeam.Name,
+		BlueTeamHasBans: tournament.Draft.Bans,
+		RedTeamHasBans:  tournament.Draft.Bans,
+		TimePerPick:     tournament.Draft.TimePerPick,
+		TimePerBan:      tournament.Draft.TimePerBan,
+		ChampionPool:    tournament.ChampionPool,
+		BlueRoster:      blueTeam.Roster,
+		RedRoster:       redTeam.Roster,
+		TradePhase:      tournament.Draft.TradePhase,
+		TradeTime:       tournament.Draft.TradeTime,
+		Patch:           tournament.Draft.Patch,
+		RoomId:          roomId,
+		SideSelection:   match.SideSelection,
+	}
+	if match.Fearless {
+		createMsg.FearlessBans = fearlessBans(match)
+	}
+	ref := models.MatchRef{
+		TournamentId: tournament.Id,
+		MatchId:      match.Id,
+		Game:         number,
+	}
+	room, err := ts.roomService.CreateMatchRoom(createMsg, ref)
+	if err != nil {
+		return models.GameCreatedResponse{}, err
+	}
+
+	game := models.MatchGame{
+		Number:     number,
+		RoomId:     roomId,
+		BlueTeamId: blueId,
+		RedTeamId:  redId,
+		Status:     models.GameDrafting,
+		CreatedAt:  time.Now().Unix(),
+	}
+	match.Games = append(match.Games, game)
+	match.Status = models.MatchInProgress
+	ts.save()
+	ts.broadcastStandings(tournament)
+	return models.GameCreatedResponse{Match: ref, Game: game, Room: *room}, nil
+}
+
+// nextGame returns the number of the next game of a match, once the match
+// is not over and no game is being drafted. Must be called with mu held.
+func (ts *TournamentService) nextGame(match *models.Match) (int, error) {
+	// Una partida sin terminar cuya room ya no existe se da por abandonada
+	finished := 0
+	for i := range match.Games {
+		game := &match.Games[i]
+		if game.Status == models.GameDrafting {
+			if _, live := ts.roomService.lookupRoom(game.RoomId); live {
+				return 0, models.NewProtocolError(models.ErrGameInProgress, "game %d of match %s is still being drafted", game.Number, match.Id).
+					WithDetail(models.DetailRoomId, game.RoomId)
+			}
+			game.Status = models.GameAbandoned
+		}
+		if game.Status == models.GameFinished {
+			finished++
+		}
+	}
+	if match.Status == models.MatchFinished || finished >= match.BestOf {
+		return 0, models.NewProtocolError(models.ErrSeriesOver, "match %s is over", match.Id).
+			WithDetail(models.DetailId, match.Id)
+	}
+	return finished + 1, nil
+}
+
+// reservesRoom reports whether roomId is the room of the next game of a
+// match, which can be invited to before the game is started
+func (ts *TournamentService) reservesRoom(roomId string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, tournament := range ts.tournaments {
+		for i := range tournament.Matches {
+			match := &tournament.Matches[i]
+			if match.Status == models.MatchFinished || match.BlueTeamId == "" || match.RedTeamId == "" {
+				continue
+			}
+			if next, ok := ts.nextGameRoom(match); ok && next == roomId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextGameRoom returns the room StartGame would create for the next game of
+// a match, counting drafts whose room is gone as abandoned like nextGame
+// does, without marking them. Must be called with mu held.
+func (ts *TournamentService) nextGameRoom(match *models.Match) (string, bool) {
+	finished, attempts := 0, make(map[int]int)
+	for _, game := range match.Games {
+		switch game.Status {
+		case models.GameFinished:
+			finished++
+		case models.GameDrafting:
+			if _, live := ts.roomService.lookupRoom(game.RoomId); live {
+				return "", false
+			}
+			attempts[game.Number]++
+		case models.GameAbandoned:
+			attempts[game.Number]++
+		}
+	}
+	if finished >= match.BestOf {
+		return "", false
+	}
+	return gameRoomName(match.Id, finished+1, attempts[finished+1]), true
+}
+
+// recordDraft links a finished draft back to its match game and keeps its
+// picks for the fearless bans of the next games
+func (ts *TournamentService) recordDraft(room *models.Room) {
+	if room.Match == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, _, game := ts.roomGame(room)
+	if game == nil {
+		return
+	}
+	finishGame(game, room)
+	ts.save()
+	ts.broadcastStandings(tournament)
+}
+
+// SubmitResult records the winner of a game of a match through the admin
+// API, for results the captains and the referee did not report, and returns
+// the updated match
+func (ts *TournamentService) SubmitResult(tournamentId, matchId, roomId string, req models.SubmitResultRequest) (models.Match, error) {
+	ts.mu.Lock()
+	tournament, err := ts.tournament(tournamentId)
+	if err != nil {
+		ts.mu.Unlock()
+		return models.Match{}, err
+	}
+	match, err := findMatch(tournament, matchId)
+	if err != nil {
+		ts.mu.Unlock()
+		return models.Match{}, err
+	}
+	found := false
+	for _, game := range match.Games {
+		found = found || game.RoomId == roomId
+	}
+	ts.mu.Unlock()
+	if !found {
+		return models.Match{}, models.NewProtocolError(models.ErrRoomNotFound, "room %s is not a game of match %s", roomId, matchId).
+			WithDetail(models.DetailRoomId, roomId)
+	}
+
+	// The result hooks, recordResult among them, take mu
+	if err := ts.roomService.SubmitResult(roomId, req); err != nil {
+		return models.Match{}, err
+	}
+	return ts.Match(tournamentId, matchId)
+}
+
+// recordResult gives the game of a room to the winning team and, once a team
+// has won most of the games of the match, moves both teams on in the bracket
+func (ts *TournamentService) recordResult(room *models.Room) {
+	if room.Match == nil || room.Result == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	tournament, match, game := ts.roomGame(room)
+	if game == nil {
+		return
+	}
+	// El resultado puede llegar antes que el aviso de draft terminado
+	finishGame(game, room)
+	if room.Result.Winner == models.SeatBlue {
+		game.Winner = game.BlueTeamId
+	} else {
+		game.Winner = game.RedTeamId
+	}
+	decideMatch(tournament, match)
+	ts.save()
+	ts.broadcastStandings(tournament)
+}
+
+// roomGame looks up the tournament, match and game of a room. Must be called
+// with mu held.
+func (ts *TournamentService) roomGame(room *models.Room) (*models.Tournament, *models.Match, *models.MatchGame) {
+	tournament, err := ts.tournament(room.Match.TournamentId)
+	if err != nil {
+		log.Printf("Room %s belongs to unknown tournament %s", room.Id, room.Match.TournamentId)
+		return nil, nil, nil
+	}
+	match, err := findMatch(tournament, room.Match.MatchId)
+	if err != nil {
+		log.Printf("Room %s belongs to unknown match %s", room.Id, room.Match.MatchId)
+		return nil, nil, nil
+	}
+	for i := range match.Games {
+		if match.Games[i].RoomId == room.Id {
+			return tournament, match, &match.Games[i]
+		}
+	}
+	log.Printf("Room %s is not a game of match %s", room.Id, match.Id)
+	return nil, nil, nil
+}
+
+// finishGame marks a game as finished with the picks of its room, once
+func finishGame(game *models.MatchGame, room *models.Room) {
+	if game.Status == models.GameFinished {
+		return
+	}
+	game.Status = models.GameFinished
+	game.FinishedAt = time.Now().Unix()
+	if room.Times.FinishedAt != 0 {
+		game.FinishedAt = room.Times.FinishedAt / 1000
+	}
+	game.Picks = pickedChampions(room)
+	// La selección de lado pudo cambiar los equipos de lado
+	if room.SidesSwapped {
+		game.BlueTeamId, game.RedTeamId = game.RedTeamId, game.BlueTeamId
+	}
+}
+
+// decideMatch finishes a match once a team has won most of its games and
+// moves the winner, and the loser in double elimination, on in the bracket
+func decideMatch(tournament *models.Tournament, match *models.Match) {
+	if match.Winner != "" {
+		return
+	}
+	wins := make(map[string]int)
+	for _, game := range match.Games {
+		if game.Winner != "" {
+			wins[game.Winner]++
+		}
+	}
+	for _, teamId := range []string{match.BlueTeamId, match.RedTeamId} {
+		if wins[teamId] > match.BestOf/2 {
+			match.Winner = teamId
+		}
+	}
+	if match.Winner == "" {
+		return
+	}
+	match.Status = models.MatchFinished
+
+	loser := match.BlueTeamId
+	if loser == match.Winner {
+		loser = match.RedTeamId
+	}
+	fillSlot(tournament, match.WinnerTo, match.Winner)
+	fillSlot(tournament, match.LoserTo, loser)
+}
+
+// fillSlot puts a team on its side of the next bracket match, if any
+func fillSlot(tournament *models.Tournament, slot *models.BracketSlot, teamId string) {
+	if slot == nil {
+		return
+	}
+	next, err := findMatch(tournament, slot.MatchId)
+	if err != nil {
+		log.Printf("Bracket slot points to unknown match %s", slot.MatchId)
+		return
+	}
+	setSide(next, slot.Side, teamId)
+}
+
+// tournament looks a tournament up. Must be called with mu held.
+func (ts *TournamentService) tournament(tournamentId string) (*models.Tournament, error) {
+	tournament, exists := ts.tournaments[tournamentId]
+	if !exists {
+		return nil, models.NewProtocolError(models.ErrTournamentNotFound, "tournament %s not found", tournamentId).
+			WithDetail(models.DetailId, tournamentId)
+	}
+	return tournament, nil
+}
+
+// findMatch looks a match of a tournament up
+func findMatch(tournament *models.Tournament, matchId string) (*models.Match, error) {
+	for i := range tournament.Matches {
+		if tournament.Matches[i].Id == matchId {
+			return &tournament.Matches[i], nil
+		}
+	}
+	return nil, models.NewProtocolError(models.ErrMatchNotFound, "match %s not found", matchId).
+		WithDetail(models.DetailId, matchId)
+}
+
+// findTeam looks a team of a tournament up, nil if it is not registered
+func findTeam(tournament *models.Tournament, teamId string) *models.TournamentTeam {
+	for i := range tournament.Teams {
+		if tournament.Teams[i].Id == teamId {
+			return &tournament.Teams[i]
+		}
+	}
+	return nil
+}
+
+// countAttempts counts the abandoned attempts at a game of a match
+func countAttempts(match *models.Match, number int) int {
+	attempts := 0
+	for _, game := range match.Games {
+		if game.Number == number && game.Status == models.GameAbandoned {
+			attempts++
+		}
+	}
+	return attempts
+}
+
+// gameRoomId names the room of a game of a match. Retries of an abandoned
+// game use another room.
+func gameRoomId(match *models.Match, number int) string {
+	return gameRoomName(match.Id, number, countAttempts(match, number))
+}
+
+// gameRoomName names the room of a game after its abandoned attempts
+func gameRoomName(matchId string, number, attempts int) string {
+	roomId := fmt.Sprintf("%s-g%d", matchId, number)
+	if attempts > 0 {
+		roomId = fmt.Sprintf("%s-%d", roomId, attempts+1)
+	}
+	return roomId
+}
+
+// fearlessBans lists the champions picked in the finished games of a match
+func fearlessBans(match *models.Match) []string {
+	seen := make(map[string]bool)
+	var bans []string
+	for _, game := range match.Games {
+		if game.Status != models.GameFinished {
+			continue
+		}
+		for _, champion := range game.Picks {
+			if !seen[champion] {
+				seen[champion] = true
+				bans = append(bans, champion)
+			}
+		}
+	}
+	return bans
+}
+
+// pickedChampions lists the champions picked by both teams of a room
+func pickedChampions(room *models.Room) []string {
+	var picks []string
+	for _, team := range []models.Team{room.BlueTeam, room.RedTeam} {
+		for _, pick := range team.Picks {
+			if pick.Name != "-1" && pick.Name != "" {
+				picks = append(picks, pick.Name)
+			}
+		}
+	}
+	return picks
+}
+
+// cloneTournament copies a tournament so it can be encoded without holding mu
+func cloneTournament(tournament *models.Tournament) models.Tournament {
+	clone := *tournament
+	clone.Teams = append(make([]models.TournamentTeam, 0, len(tournament.Teams)), tournament.Teams...)
+	clone.Brackets = append([]models.Bracket(nil), tournament.Brackets...)
+	clone.Matches = make([]models.Match, len(tournament.Matches))
+	for i, match := range tournament.Matches {
+		clone.Matches[i] = cloneMatch(match)
+	}
+	return clone
+}
+
+// cloneMatch copies the games of a match
+func cloneMatch(match models.Match) models.Match {
+	match.Games = append(make([]models.MatchGame, 0, len(match.Games)), match.Games...)
+	return match
+}
+
+// teamNotFoundError builds the error for a team that is not registered
+func teamNotFoundError(teamId string) error {
+	return models.NewProtocolError(models.ErrTeamNotFound, "team %s is not registered", teamId).
+		WithDetail(models.DetailTeam, teamId)
+}
+
+// duplicateIdError builds the error for an ID already in use
+func duplicateIdError(kind, id string) error {
+	return models.NewProtocolError(models.ErrDuplicateId, "%s %s already exists", kind, id).
+		WithDetail(models.DetailId, id)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// defaultTradeTime is the length of the trade phase when the room does not set it
+const defaultTradeTime = 60
+
+// teamOf returns the team of a room by side
+func teamOf(room *models.Room, team string) *models.Team {
+	if team == models.SeatBlue {
+		return &room.BlueTeam
+	}
+	return &room.RedTeam
+}
+
+// processTradeAction handles the actions of the trade phase. Changing the
+// picks after confirming withdraws the confirmation of the team.
+func (s *RoomService) processTradeAction(room *models.Room, team string, action models.ActionMessage) error {
+	room.ClientsMutex.Lock()
+	if room.CurrentPhase != models.Trading {
+		phase := room.CurrentPhase
+		room.ClientsMutex.Unlock()
+		return s.invalidPhaseError(action.Action, phase)
+	}
+	if room.TradeConfirmed == nil {
+		room.TradeConfirmed = make(map[string]bool)
+	}
+
+	var err error
+	switch action.Action {
+	case "trade_reorder":
+		err = s.reorderPicks(teamOf(room, team), action.Order)
+	case "trade_assign":
+		err = s.assignPick(teamOf(room, team), action.Champion, action.Player)
+	case "trade_confirm":
+		room.TradeConfirmed[team] = true
+	}
+	if err == nil && action.Action != "trade_confirm" {
+		room.TradeConfirmed[team] = false
+	}
+	// When both teams are done the draft ends without waiting for the timer
+	done := room.TradeConfirmed[models.SeatBlue] && room.TradeConfirmed[models.SeatRed] && leaveTrading(room)
+	room.ClientsMutex.Unlock()
+
+	if done {
+		s.stopTimer(room)
+		s.finishTrading(room)
+	}
+	return err
+}
+
+// reorderPicks reorders the picks of a team; order lists the current index
+// of each pick in its new position
+func (s *RoomService) reorderPicks(team *models.Team, order []int) error {
+	if len(order) != len(team.Picks) {
+		return models.NewProtocolError(models.ErrInvalidTrade, "order must list the %d picks", len(team.Picks)).
+			WithDetail(models.DetailField, "order")
+	}
+	seen := make([]bool, len(order))
+	reordered := make([]models.Champion, len(order))
+	for position, index := range order {
+		if index < 0 || index >= len(order) || seen[index] {
+			return models.NewProtocolError(models.ErrInvalidTrade, "order is not a permutation of the picks: %v", order).
+				WithDetail(models.DetailField, "order")
+		}
+		seen[index] = true
+		reordered[position] = team.Picks[index]
+	}
+	team.Picks = reordered
+	return nil
+}
+
+// assignPick assigns one of the team picks to a player. With a roster the
+// player must be in it, by ID or name; otherwise any name is accepted. A
+// player gets a single champion, so assigning them a second one clears the
+// first.
+func (s *RoomService) assignPick(team *models.Team, champion, player string) error {
+	assignee, err := s.findRosterPlayer(team, player)
+	if err != nil {
+		return err
+	}
+
+	championLower := strings.ToLower(strings.TrimSpace(champion))
+	target := -1
+	for i, pick := range team.Picks {
+		if strings.ToLower(strings.TrimSpace(pick.Name)) == championLower {
+			target = i
+		}
+	}
+	if target == -1 {
+		return models.NewProtocolError(models.ErrInvalidTrade, "champion %s is not one of the team picks", champion).
+			WithDetail(models.DetailChampion, champion)
+	}
+	for i := range team.Picks {
+		if team.Picks[i].Player == assignee.Name {
+			team.Picks[i].Player = ""
+			team.Picks[i].PlayerId = ""
+		}
+	}
+	team.Picks[target].Player = assignee.Name
+	team.Picks[target].PlayerId = assignee.Id
+	return nil
+}
+
+// findRosterPlayer resolves a player by roster ID or name. Teams without a
+// roster accept any name.
+func (s *RoomService) findRosterPlayer(team *models.Team, player string) (models.Player, error) {
+	if len(team.Roster) == 0 {
+		return models.Player{Name: player}, nil
+	}
+	for _, member := range team.Roster {
+		if member.Id != "" && member.Id == player || member.Name == player {
+			return member, nil
+		}
+	}
+	return models.Player{}, models.NewProtocolError(models.ErrInvalidTrade, "player %s is not in the roster of %s", player, team.Name).
+		WithDetail(models.DetailField, "player")
+}
+
+// leaveTrading moves a room from the trade phase to Finished. The last
+// trade_confirm and the timer race to end the phase; only the caller that
+// still finds the room trading gets true and must call finishTrading. Must
+// be called with ClientsMutex held.
+func leaveTrading(room *models.Room) bool {
+	if room.CurrentPhase != models.Trading {
+		return false
+	}
+	room.CurrentPhase = models.Finished
+	return true
+}
+
+// finishTrading ends the draft of a room that left the trade phase
+func (s *RoomService) finishTrading(room *models.Room) {
+	room.LastActivity.Store(time.Now().UnixNano())
+	log.Printf("Trade phase of room %s finished", room.Id)
+	s.handleFinishedRoom(room)
+}
@@ -0,0 +1,543 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+// Headers of a webhook delivery. The signature is "sha256=" and the hex
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed by the secret of
+// the webhook; see SignWebhook.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookQueueSize is how many deliveries can wait behind the one being
+// sent, or retried, to a webhook before new events are dropped
+const webhookQueueSize = 256
+
+// maxWebhookBackoff caps the wait between two attempts of a delivery
+const maxWebhookBackoff = 5 * time.Minute
+
+// WebhookOptions configure how deliveries are sent
+type WebhookOptions struct {
+	Timeout     time.Duration // Of each request
+	MaxAttempts int
+	Backoff     time.Duration // Before the first retry, doubled on each of the next ones
+	LogSize     int           // Deliveries kept in the delivery log
+}
+
+// WebhookService keeps the webhooks, persisted to a JSON file, and sends them
+// the draft lifecycle events of the rooms. Each webhook has a worker that
+// sends its deliveries in order, retrying failed ones with exponential
+// backoff; the latest deliveries are kept in memory as the delivery log.
+type WebhookService struct {
+	mu          sync.Mutex
+	webhooks    map[string]*webhookEndpoint
+	path        string // JSON file, webhooks are only kept in memory if empty
+	options     WebhookOptions
+	client      *http.Client
+	roomService *RoomService
+	tournaments *TournamentService
+
+	// Delivery log, oldest first. Protects the deliveries too, which the
+	// workers update while the API reads them.
+	logMu      sync.Mutex
+	deliveries []*models.WebhookDelivery
+}
+
+// webhookEndpoint is a webhook with the queue of its worker
+type webhookEndpoint struct {
+	webhook models.Webhook
+	queue   chan webhookJob
+	stop    chan struct{} // Closed when the webhook is deleted
+}
+
+// webhookJob is a delivery waiting to be sent, with its encoded event
+type webhookJob struct {
+	delivery *models.WebhookDelivery
+	event    string
+	body     []byte
+}
+
+// webhooksFile is the content of the webhooks file
+type webhooksFile struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+}
+
+// NewWebhookService loads the webhooks from path and subscribes them to the
+// lifecycle of the rooms of roomService
+func NewWebhookService(path string, options WebhookOptions, roomService *RoomService, tournaments *TournamentService) (*WebhookService, error) {
+	ws := &WebhookService{
+		webhooks:    make(map[string]*webhookEndpoint),
+		path:        path,
+		options:     options,
+		client:      newWebhookClient(options.Timeout),
+		roomService: roomService,
+		tournaments: tournaments,
+	}
+	if path == "" {
+		log.Println("Warning: WEBHOOKS_FILE is empty, webhooks are lost when the server restarts")
+	} else if err := ws.load(); err != nil {
+		return nil, err
+	}
+	roomService.OnDraftStarted(ws.draftStarted)
+	roomService.OnChampionLocked(ws.championLocked)
+	roomService.OnDraftFinished(ws.draftFinished)
+	roomService.OnGameResult(ws.gameResult)
+	return ws, nil
+}
+
+// newWebhookClient creates the client that sends the deliveries
+func newWebhookClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		// A redirected POST would be replayed as a GET, so redirects count as failures
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// load reads the webhooks file, which may not exist yet, and starts the
+// workers of its webhooks
+func (ws *WebhookService) load() error {
+	data, err := os.ReadFile(ws.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading webhooks file: %v", err)
+	}
+	var file webhooksFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("error parsing webhooks file %s: %v", ws.path, err)
+	}
+	for _, webhook := range file.Webhooks {
+		ws.start(webhook)
+	}
+	log.Printf("Loaded %d webhooks from %s", len(file.Webhooks), ws.path)
+	return nil
+}
+
+// save writes the webhooks file. Must be called with mu held. Failures are
+// logged, as for the tournament registry.
+func (ws *WebhookService) save() {
+	if ws.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(webhooksFile{Webhooks: ws.sorted(true)}, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding webhooks: %v", err)
+		return
+	}
+	if err := writeFileAtomic(ws.path, data); err != nil {
+		log.Printf("Error saving webhooks: %v", err)
+	}
+}
+
+// start registers a webhook and starts its worker. Must be called with mu
+// held, or before the service is shared.
+func (ws *WebhookService) start(webhook models.Webhook) {
+	endpoint := &webhookEndpoint{
+		webhook: webhook,
+		queue:   make(chan webhookJob, webhookQueueSize),
+		stop:    make(chan struct{}),
+	}
+	ws.webhooks[webhook.Id] = endpoint
+	go ws.work(endpoint)
+}
+
+// sorted lists the webhooks by creation time, without their secrets unless
+// withSecrets. Must be called with mu held.
+func (ws *WebhookService) sorted(withSecrets bool) []models.Webhook {
+	webhooks := make([]models.Webhook, 0, len(ws.webhooks))
+	for _, endpoint := range ws.webhooks {
+		webhook := endpoint.webhook
+		if !withSecrets {
+			webhook.Secret = ""
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].CreatedAt != webhooks[j].CreatedAt {
+			return webhooks[i].CreatedAt < webhooks[j].CreatedAt
+		}
+		return webhooks[i].Id < webhooks[j].Id
+	})
+	return webhooks
+}
+
+// Webhooks lists the webhooks by creation time, without their secrets
+func (ws *WebhookService) Webhooks() []models.Webhook {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.sorted(false)
+}
+
+// Webhook returns a webhook without its secret
+func (ws *WebhookService) Webhook(webhookId string) (models.Webhook, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	endpoint, err := ws.endpoint(webhookId)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	webhook := endpoint.webhook
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// CreateWebhook registers a webhook. The response is the only place its
+// secret is returned.
+func (ws *WebhookService) CreateWebhook(req models.CreateWebhookRequest) (models.Webhook, error) {
+	if req.TournamentId != "" {
+		if _, err := ws.tournaments.Tournament(req.TournamentId); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	id := ws.roomService.generateRandomID()
+	for ws.webhooks[id] != nil {
+		id = ws.roomService.generateRandomID()
+	}
+	webhook := models.Webhook{
+		Id:           id,
+		Url:          req.Url,
+		TournamentId: req.TournamentId,
+		Events:       req.Events,
+		Secret:       req.Secret,
+		CreatedAt:    time.Now().Unix(),
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = generateKey()
+	}
+	ws.start(webhook)
+	ws.save()
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook. Its pending deliveries fail.
+func (ws *WebhookService) DeleteWebhook(webhookId string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	endpoint, err := ws.endpoint(webhookId)
+	if err != nil {
+		return err
+	}
+	delete(ws.webhooks, webhookId)
+	close(endpoint.stop)
+	ws.save()
+	return nil
+}
+
+// Ping sends a ping event to a webhook to test it
+func (ws *WebhookService) Ping(webhookId string) (models.WebhookDelivery, error) {
+	ws.mu.Lock()
+	endpoint, err := ws.endpoint(webhookId)
+	ws.mu.Unlock()
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery := ws.enqueue(endpoint, models.WebhookEvent{Event: models.EventPing, At: time.Now().UnixMilli()})
+	return ws.snapshot(delivery), nil
+}
+
+// endpoint finds a webhook. Must be called with mu held.
+func (ws *WebhookService) endpoint(webhookId string) (*webhookEndpoint, error) {
+	endpoint, exists := ws.webhooks[webhookId]
+	if !exists {
+		return nil, models.NewProtocolError(models.ErrWebhookNotFound, "webhook %s not found", webhookId).
+			WithDetail(models.DetailId, webhookId)
+	}
+	return endpoint, nil
+}
+
+// Deliveries returns the delivery log, newest first, optionally only of a
+// webhook, an event or a status, and at most limit entries if limit > 0
+func (ws *WebhookService) Deliveries(webhookId, event, status string, limit int) []models.WebhookDelivery {
+	ws.logMu.Lock()
+	defer ws.logMu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	for i := len(ws.deliveries) - 1; i >= 0 && (limit <= 0 || len(deliveries) < limit); i-- {
+		delivery := ws.deliveries[i]
+		if webhookId != "" && delivery.WebhookId != webhookId || event != "" && delivery.Event != event || status != "" && delivery.Status != status {
+			continue
+		}
+		copied := *delivery
+		copied.Attempts = slices.Clone(delivery.Attempts)
+		deliveries = append(deliveries, copied)
+	}
+	return deliveries
+}
+
+// snapshot copies a delivery of the log
+func (ws *WebhookService) snapshot(delivery *models.WebhookDelivery) models.WebhookDelivery {
+	ws.logMu.Lock()
+	defer ws.logMu.Unlock()
+	copied := *delivery
+	copied.Attempts = slices.Clone(delivery.Attempts)
+	return copied
+}
+
+// draftStarted notifies the first turn of a draft
+func (ws *WebhookService) draftStarted(room *models.Room) {
+	ws.dispatch(models.EventDraftStarted, room, func() interface{} {
+		return models.DraftStarted{
+			BlueTeam: room.BlueTeam.Name,
+			RedTeam:  room.RedTeam.Name,
+			Patch:    room.Patch,
+			Bans:     room.BlueTeamHasBans || room.RedTeamHasBans,
+			Times:    room.Times,
+		}
+	})
+}
+
+// championLocked notifies the end of a ban or pick turn as a draft action
+func (ws *WebhookService) championLocked(room *models.Room, phase models.Phase, champion models.Champion) {
+	ws.dispatch(models.EventDraftLocked, room, func() interface{} {
+		side, kind, _ := phaseSlot(phase)
+		team := room.BlueTeam.Name
+		if side == models.SeatRed {
+			team = room.RedTeam.Name
+		}
+		action := models.DraftAction{
+			Seq:      slices.Index(models.DraftSequence(room.BlueTeamHasBans || room.RedTeamHasBans), phase) + 1,
+			Phase:    phase,
+			Side:     side,
+			Team:     team,
+			Kind:     kind,
+			LockedAt: champion.LockedAt,
+			TimeUsed: champion.TimeUsed,
+			TimedOut: champion.TimedOut,
+		}
+		if champion.Name != "-1" {
+			action.Champion = champion.Name
+		}
+		return action
+	})
+}
+
+// draftFinished notifies a finished draft with its export
+func (ws *WebhookService) draftFinished(room *models.Room) {
+	ws.dispatch(models.EventDraftFinished, room, func() interface{} {
+		return draftExport(newRoomData(room))
+	})
+}
+
+// gameResult notifies the result of a game with the export of its draft
+func (ws *WebhookService) gameResult(room *models.Room) {
+	ws.dispatch(models.EventGameResult, room, func() interface{} {
+		return draftExport(newRoomData(room))
+	})
+}
+
+// dispatch queues an event of a room for the webhooks subscribed to it. The
+// data is only built if a webhook wants the event, and is encoded before
+// returning, while the room still holds the state of the event.
+func (ws *WebhookService) dispatch(event string, room *models.Room, data func() interface{}) {
+	ws.mu.Lock()
+	var targets []*webhookEndpoint
+	for _, endpoint := range ws.webhooks {
+		if endpoint.webhook.Wants(event, room.Match) {
+			targets = append(targets, endpoint)
+		}
+	}
+	ws.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	payload := models.WebhookEvent{
+		Event:  event,
+		At:     time.Now().UnixMilli(),
+		RoomId: room.Id,
+		Match:  room.Match,
+		Data:   data(),
+	}
+	for _, endpoint := range targets {
+		ws.enqueue(endpoint, payload)
+	}
+}
+
+// enqueue adds a delivery of event to the log and the queue of a webhook
+func (ws *WebhookService) enqueue(endpoint *webhookEndpoint, event models.WebhookEvent) *models.WebhookDelivery {
+	event.Id = newDeliveryId()
+	delivery := &models.WebhookDelivery{
+		Id:        event.Id,
+		WebhookId: endpoint.webhook.Id,
+		Event:     event.Event,
+		RoomId:    event.RoomId,
+		Status:    models.DeliveryPending,
+		CreatedAt: time.Now().UnixMilli(),
+		Attempts:  []models.WebhookAttempt{},
+	}
+	ws.record(delivery)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		ws.fail(delivery, fmt.Sprintf("encoding event: %v", err))
+		return delivery
+	}
+	select {
+	case endpoint.queue <- webhookJob{delivery: delivery, event: event.Event, body: body}:
+	default:
+		ws.fail(delivery, "queue full")
+	}
+	return delivery
+}
+
+// record adds a delivery to the log, dropping the oldest ones past its size
+func (ws *WebhookService) record(delivery *models.WebhookDelivery) {
+	ws.logMu.Lock()
+	defer ws.logMu.Unlock()
+	ws.deliveries = append(ws.deliveries, delivery)
+	if excess := len(ws.deliveries) - max(ws.options.LogSize, 1); excess > 0 {
+		ws.deliveries = slices.Delete(ws.deliveries, 0, excess)
+	}
+}
+
+// fail marks a delivery as failed without sending it
+func (ws *WebhookService) fail(delivery *models.WebhookDelivery, reason string) {
+	ws.logMu.Lock()
+	delivery.Status = models.DeliveryFailed
+	delivery.NextAttempt = 0
+	delivery.Attempts = append(delivery.Attempts, models.WebhookAttempt{At: time.Now().UnixMilli(), Error: reason})
+	ws.logMu.Unlock()
+	log.Printf("Webhook delivery %s (%s) to %s failed: %s", delivery.Id, delivery.Event, delivery.WebhookId, reason)
+}
+
+// work sends the deliveries of a webhook in order until it is deleted
+func (ws *WebhookService) work(endpoint *webhookEndpoint) {
+	for {
+		select {
+		case <-endpoint.stop:
+			// Queued deliveries fail with the webhook
+			for {
+				select {
+				case job := <-endpoint.queue:
+					ws.fail(job.delivery, "webhook deleted")
+				default:
+					return
+				}
+			}
+		case job := <-endpoint.queue:
+			ws.deliver(endpoint, job)
+		}
+	}
+}
+
+// deliver sends a delivery, retrying it with exponential backoff while the
+// endpoint fails with a network error, a timeout, 408, 429 or a 5xx status
+func (ws *WebhookService) deliver(endpoint *webhookEndpoint, job webhookJob) {
+	for attempt := 1; ; attempt++ {
+		result, retry := ws.send(endpoint.webhook, job)
+		delivered := result.Error == ""
+
+		ws.logMu.Lock()
+		job.delivery.Attempts = append(job.delivery.Attempts, result)
+		job.delivery.NextAttempt = 0
+		var wait time.Duration
+		switch {
+		case delivered:
+			job.delivery.Status = models.DeliveryDelivered
+		case !retry || attempt >= ws.options.MaxAttempts:
+			job.delivery.Status = models.DeliveryFailed
+		default:
+			wait = min(ws.options.Backoff<<(attempt-1), maxWebhookBackoff)
+			job.delivery.NextAttempt = time.Now().Add(wait).UnixMilli()
+		}
+		status := job.delivery.Status
+		ws.logMu.Unlock()
+
+		if status != models.DeliveryPending {
+			if status == models.DeliveryFailed {
+				log.Printf("Webhook delivery %s (%s) to %s failed after %d attempts: %s", job.delivery.Id, job.event, endpoint.webhook.Id, attempt, result.Error)
+			}
+			return
+		}
+		select {
+		case <-time.After(wait):
+		case <-endpoint.stop:
+			ws.fail(job.delivery, "webhook deleted")
+			return
+		}
+	}
+}
+
+// send makes an attempt of a delivery. The attempt has an error unless the
+// endpoint answered with a 2xx status; retry tells if it is worth retrying.
+func (ws *WebhookService) send(webhook models.Webhook, job webhookJob) (models.WebhookAttempt, bool) {
+	start := time.Now()
+	attempt := models.WebhookAttempt{At: start.UnixMilli()}
+	req, err := http.NewRequest(http.MethodPost, webhook.Url, bytes.NewReader(job.body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "picks3w2a-webhooks")
+	req.Header.Set(WebhookEventHeader, job.event)
+	req.Header.Set(WebhookDeliveryHeader, job.delivery.Id)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(webhook.Secret, timestamp, job.body))
+
+	resp, err := ws.client.Do(req)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, true
+	}
+	// Draining the body lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return attempt, false
+	}
+	attempt.Error = resp.Status
+	retry := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return attempt, retry
+}
+
+// SignWebhook returns the signature header of a delivery body sent at
+// timestamp, in Unix seconds
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryId generates the ID of a delivery, also sent to the endpoint so
+// it can skip the retries of a delivery it already processed
+func newDeliveryId() string {
+	bytes := make([]byte, 12)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"picks3w2a/internal/models"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"event":"ping"}`)
+	signature := SignWebhook(testWebhookSecret, "1700000000", body)
+	if want := "sha256=aa5e99c564e20420ee92d14459487b345c80fffe779f1e71de508602a0c1f675"; signature != want {
+		t.Fatalf("SignWebhook = %s, want %s", signature, want)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+	}{
+		{"other secret", "fedcba9876543210", "1700000000", `{"event":"ping"}`},
+		{"other timestamp", testWebhookSecret, "1700000001", `{"event":"ping"}`},
+		{"other body", testWebhookSecret, "1700000000", `{"event":"pong"}`},
+		{"timestamp moved into the body", testWebhookSecret, "170000000", `0.{"event":"ping"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if SignWebhook(tt.secret, tt.timestamp, []byte(tt.body)) == signature {
+				t.Fatalf("signature does not change")
+			}
+		})
+	}
+}
+
+// webhookRecorder is an endpoint answering with a status per request, the
+// last one repeated, and recording when the requests arrived
+type webhookRecorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+	times    []time.Time
+}
+
+func (rec *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	status := rec.statuses[min(len(rec.requests), len(rec.statuses)-1)]
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, body)
+	rec.times = append(rec.times, time.Now())
+	if status >= 300 && status < 400 {
+		w.Header().Set("Location", "/elsewhere")
+	}
+	w.WriteHeader(status)
+}
+
+// deliverTo sends a ping to url with the worker's retry loop and returns the
+// delivery once it is delivered or failed
+func deliverTo(options WebhookOptions, url string) *models.WebhookDelivery {
+	ws := &WebhookService{options: options, client: newWebhookClient(options.Timeout)}
+	endpoint := &webhookEndpoint{
+		webhook: models.Webhook{Id: "w1", Url: url, Secret: testWebhookSecret},
+		queue:   make(chan webhookJob, 1),
+		stop:    make(chan struct{}),
+	}
+	delivery := &models.WebhookDelivery{
+		Id:        "d1",
+		WebhookId: "w1",
+		Event:     models.EventPing,
+		Status:    models.DeliveryPending,
+		Attempts:  []models.WebhookAttempt{},
+	}
+	ws.deliver(endpoint, webhookJob{delivery: delivery, event: models.EventPing, body: []byte(`{"event":"ping"}`)})
+	return delivery
+}
+
+func TestDeliverRetries(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+	tests := []struct {
+		name     string
+		statuses []int
+		status   string
+		attempts int
+	}{
+		{"delivered at once", []int{http.StatusNoContent}, models.DeliveryDelivered, 1},
+		{"retried after 5xx", []int{http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK}, models.DeliveryDelivered, 3},
+		{"retried after 429", []int{http.StatusTooManyRequests, http.StatusOK}, models.DeliveryDelivered, 2},
+		{"retried after 408", []int{http.StatusRequestTimeout, http.StatusAccepted}, models.DeliveryDelivered, 2},
+		{"out of attempts", []int{http.StatusBadGateway}, models.DeliveryFailed, 4},
+		{"no retry after 400", []int{http.StatusBadRequest, http.StatusOK}, models.DeliveryFailed, 1},
+		{"no retry after 401", []int{http.StatusUnauthorized, http.StatusOK}, models.DeliveryFailed, 1},
+		{"no retry after 404", []int{http.StatusNotFound, http.StatusOK}, models.DeliveryFailed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &webhookRecorder{statuses: tt.statuses}
+			server := httptest.NewServer(rec)
+			defer server.Close()
+
+			delivery := deliverTo(WebhookOptions{Timeout: time.Second, MaxAttempts: 4, Backoff: backoff}, server.URL)
+			if delivery.Status != tt.status {
+				t.Fatalf("expected status %s, got %s", tt.status, delivery.Status)
+			}
+			if len(delivery.Attempts) != tt.attempts || len(rec.requests) != tt.attempts {
+				t.Fatalf("expected %d attempts, logged %d and received %d", tt.attempts, len(delivery.Attempts), len(rec.requests))
+			}
+			if delivery.NextAttempt != 0 {
+				t.Errorf("finished delivery still has a next attempt")
+			}
+			for i, attempt := range delivery.Attempts {
+				status := tt.statuses[min(i, len(tt.statuses)-1)]
+				if attempt.StatusCode != status {
+					t.Errorf("attempt %d logged status %d, want %d", i+1, attempt.StatusCode, status)
+				}
+				if failed := attempt.Error != ""; failed != (status >= 300) {
+					t.Errorf("attempt %d with status %d logged error %q", i+1, status, attempt.Error)
+				}
+			}
+
+			// The wait doubles after each failed attempt
+			for i := 1; i < len(rec.times); i++ {
+				if gap, want := rec.times[i].Sub(rec.times[i-1]), backoff<<(i-1); gap < want {
+					t.Errorf("attempt %d sent %s after the previous one, want at least %s", i+1, gap, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliverRequest(t *testing.T) {
+	rec := &webhookRecorder{statuses: []int{http.StatusOK}}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	deliverTo(WebhookOptions{Timeout: time.Second, MaxAttempts: 1}, server.URL)
+	if len(rec.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(rec.requests))
+	}
+	r, body := rec.requests[0], rec.bodies[0]
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+	}
+	if r.Header.Get(WebhookEventHeader) != models.EventPing || r.Header.Get(WebhookDeliveryHeader) != "d1" {
+		t.Errorf("unexpected event headers %s=%q %s=%q", WebhookEventHeader, r.Header.Get(WebhookEventHeader), WebhookDeliveryHeader, r.Header.Get(WebhookDeliveryHeader))
+	}
+	timestamp := r.Header.Get(WebhookTimestampHeader)
+	if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhook(testWebhookSecret, timestamp, body); timestamp == "" || got != want {
+		t.Errorf("signature %q of timestamp %q does not match %q", got, timestamp, want)
+	}
+}
+
+func TestDeliverRedirectFails(t *testing.T) {
+	for _, status := range []int{http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			rec := &webhookRecorder{statuses: []int{status, http.StatusOK}}
+			server := httptest.NewServer(rec)
+			defer server.Close()
+
+			delivery := deliverTo(WebhookOptions{Timeout: time.Second, MaxAttempts: 3, Backoff: time.Millisecond}, server.URL)
+			if delivery.Status != models.DeliveryFailed {
+				t.Fatalf("expected the redirected delivery to fail, got %s", delivery.Status)
+			}
+			if len(rec.requests) != 1 {
+				t.Fatalf("expected the redirect not to be followed nor retried, got %d requests", len(rec.requests))
+			}
+			if attempt := delivery.Attempts[0]; attempt.StatusCode != status || attempt.Error == "" {
+				t.Errorf("expected a failed attempt with status %d, got %+v", status, attempt)
+			}
+		})
+	}
+}
+
+func TestDeliverUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	delivery := deliverTo(WebhookOptions{Timeout: time.Second, MaxAttempts: 2, Backoff: time.Millisecond}, url)
+	if delivery.Status != models.DeliveryFailed || len(delivery.Attempts) != 2 {
+		t.Fatalf("expected a network error to be retried until out of attempts, got %s after %d attempts", delivery.Status, len(delivery.Attempts))
+	}
+	if attempt := delivery.Attempts[0]; attempt.StatusCode != 0 || attempt.Error == "" {
+		t.Errorf("expected a network error, got %+v", attempt)
+	}
+}
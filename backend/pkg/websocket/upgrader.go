@@ -2,13 +2,14 @@ package websocket
 
 import (
 	"net/http"
+
 	"github.com/gorilla/websocket"
 )
 
-// Upgrader configures the WebSocket upgrader
-var Upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Para pruebas aceptamos todo. En producción deberías validar origen.
-		return true
-	},
+// NewUpgrader configures the WebSocket upgrader. checkOrigin decides which
+// browser origins may connect; if nil only same-origin requests are accepted.
+func NewUpgrader(checkOrigin func(r *http.Request) bool) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		CheckOrigin: checkOrigin,
+	}
 }
@@ -1,6 +1,7 @@
 "use client";
 
 import { useEffect, useRef, useState, useCallback } from "react";
+import { MessageTypes, PROTOCOL_VERSION } from "../types/messages";
 
 interface UseWebSocketOptions {
   onMessage?: (event: MessageEvent) => void;
@@ -43,6 +44,8 @@ export const useWebSocket = (url: string, options: UseWebSocketOptions = {}) =>
       const ws = new WebSocket(url);
 
       ws.onopen = (event) => {
+        // Negotiate the protocol version before any other message
+        ws.send(JSON.stringify({ type: MessageTypes.HELLO, versions: [PROTOCOL_VERSION], client: "picks3-frontend" }));
         setState(prev => ({
           ...prev,
           socket: ws,
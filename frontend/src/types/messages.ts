@@ -1,5 +1,27 @@
 // WebSocket Message Types
-// These types match the Go server message definitions
+// These types match the Go server message definitions.
+// The authoritative JSON Schema is served by the backend at /schema; a copy
+// is kept in backend/internal/handlers/testdata/schema, and a test fails when
+// the models change without it being regenerated (go test -update).
+
+export const PROTOCOL_VERSION = 1;
+
+export interface HelloMessage {
+  type: string;
+  request_id?: string;
+  versions: number[];
+  client?: string;
+  session?: string; // From the welcome of a previous connection, to keep its retries recognised
+}
+
+export interface WelcomeMessage {
+  type: string;
+  request_id?: string;
+  version: number;
+  supported_versions: number[];
+  schema_path: string;
+  session: string;
+}
 
 export interface CreateMessage {
   type: string;
@@ -10,25 +32,59 @@ export interface CreateMessage {
   time_per_pick: number;
   time_per_ban: number;
   fearless_bans: string[]
+  champion_pool?: string[]; // champions allowed in the draft, all if empty
+  patch?: string; // game patch, the server default if empty
+  blue_roster?: Player[];
+  red_roster?: Player[];
+  side_selection?: boolean; // start with a side selection phase
+  side_chooser?: "blue" | "red"; // e.g. the higher seed; a coin flip decides if empty
+  trade_phase?: boolean; // let teams reorder and assign picks after the draft
+  trade_time?: number; // seconds, defaults to 60
+  request_id?: string;
+}
+
+// A rostered player; id is optional, e.g. a tournament player ID
+export interface Player {
+  id?: string;
+  name: string;
 }
 
 export interface CreateResponseMessage {
   type: string;
+  request_id?: string;
   room_id: string;
   red_team_key: string;
   blue_team_key: string;
+  referee_key: string; // only sent here, the server keeps a hash
+  coin_flip_commitment?: string; // sha256 of the coin flip seed
+  duplicate?: boolean; // retried create, answered with the room of the first one
 }
 
 export interface JoinMessage {
   type: string;
   room_id: string;
   key?: string;
+  invite?: string; // signed invite token, used instead of key
+  role?: TeamRole; // defaults to captain if the seat is free
+  nickname?: string; // shown in the presence list
+  request_id?: string;
 }
 
 export interface ActionMessage {
   type: string;
-  action: "ready" | "champ_select" | "champ_pick";
+  action:
+    | "ready" | "unready" | "champ_select" | "champ_pick" | "choose_side"
+    | "trade_reorder" | "trade_assign" | "trade_confirm"
+    | "force_start" | "report_result" | "rotate_key" | "issue_invite";
   champion?: string;
+  side?: "blue" | "red"; // for choose_side, and the winner for report_result
+  order?: number[]; // for trade_reorder, current index of each pick in its new position
+  player?: string; // for trade_assign
+  seat?: Seat; // target of referee actions
+  expires_in?: number; // seconds, for issue_invite
+  duration?: number; // seconds of game time, for report_result
+  notes?: string; // for report_result
+  request_id?: string; // echoed back in the ack or error response
 }
 
 export interface TeamMessage {
@@ -37,9 +93,36 @@ export interface TeamMessage {
   picks: string[];
 }
 
+// Links a room to a tournament match
+export interface MatchRef {
+  tournament_id: string;
+  match_id: string;
+  game: number;
+}
+
+// Winner of a finished draft, reported by the referee, agreed by both
+// captains or entered with an imported draft; also the shape of each
+// captain's pending report
+export interface GameResult {
+  winner: "blue" | "red";
+  submitted_by: "referee" | "captains" | "import" | "blue" | "red";
+  duration?: number; // seconds of game time
+  notes?: string;
+  at: number; // unix milliseconds
+}
+
 export interface StatusMessage {
   type: string;
   current_phase: typeof PossiblePhases[keyof typeof PossiblePhases];
+  side_chooser?: "blue" | "red"; // team picking side, as entered when creating the room
+  coin_flip?: CoinFlip;
+  match?: MatchRef; // tournament match drafted in the room
+  patch?: string;
+  result?: GameResult;
+  result_reports?: { blue?: GameResult; red?: GameResult }; // captain reports while the result is pending
+  result_disputed?: boolean; // captains reported different winners, the referee decides
+  times: DraftTimes;
+  turn_started_at?: number; // unix milliseconds, during bans and picks
   time_per_pick: number;
   time_per_ban: number;
   time_remaining: number; 
@@ -55,24 +138,311 @@ export interface UserJoinedMessage {
   team?: "blue" | "red" | ""; // empty string for spectator
 }
 
+export type ErrorCode =
+  | "invalid_message"
+  | "unknown_message_type"
+  | "unknown_action"
+  | "validation_failed"
+  | "rate_limited"
+  | "unsupported_version"
+  | "handshake_repeated"
+  | "room_not_found"
+  | "not_in_room"
+  | "client_not_found"
+  | "invalid_key"
+  | "spectator_action"
+  | "room_limit_reached"
+  | "room_full"
+  | "room_exists"
+  | "subscription_limit_reached"
+  | "invalid_invite"
+  | "invite_expired"
+  | "not_referee"
+  | "seat_taken"
+  | "captain_only"
+  | "invalid_phase"
+  | "already_ready"
+  | "not_ready"
+  | "not_your_turn"
+  | "champion_required"
+  | "champion_banned"
+  | "champion_picked"
+  | "champion_fearless_banned"
+  | "invalid_trade"
+  | "champion_not_in_pool"
+  | "tournament_not_found"
+  | "team_not_found"
+  | "match_not_found"
+  | "duplicate_id"
+  | "game_in_progress"
+  | "series_over"
+  | "result_exists"
+  | "unknown_champion"
+  | "invalid_draft"
+  | "match_not_ready"
+  | "webhook_not_found"
+  | "internal_error";
+
+export interface ErrorMessage {
+  type: string;
+  request_id?: string;
+  code: ErrorCode;
+  message: string;
+  details?: Record<string, string>; // e.g. champion, phase, team
+  request_type?: string; // type of the message that caused the error
+  action?: string; // action of the message that caused the error
+}
+
+export interface AckMessage {
+  type: string;
+  request_id?: string;
+  request_type: string;
+  action?: string;
+  duplicate?: boolean; // the request had already been applied
+}
+
+export interface RoomClosedMessage {
+  type: string;
+  room_id: string;
+  reason: "expired" | "subscription_expired" | "room_full";
+  phase: GamePhase;
+}
+
+// Sent to spectators joining a reserved room, such as the next game of a
+// tournament match, that has not been created yet
+export interface WaitingForRoomMessage {
+  type: string;
+  room_id: string;
+  expires_at: number; // unix seconds
+}
+
+export type Seat = "blue" | "red" | "referee";
+
+// Only the captain may ready up and lock; players and coaches hover for their team
+export type TeamRole = "captain" | "player" | "coach";
+
+export interface PresenceEntry {
+  nickname?: string;
+  team?: "blue" | "red";
+  role: TeamRole | "referee";
+}
+
+// Broadcast when someone joins, leaves or changes seat
+export interface PresenceMessage {
+  type: string;
+  room_id: string;
+  members: PresenceEntry[];
+  spectators: number;
+}
+
+// Champion hovered by a player or coach, sent to their own team only
+export interface TeamHoverMessage {
+  type: string;
+  team: "blue" | "red";
+  nickname?: string;
+  role: TeamRole;
+  champion: string;
+  phase: GamePhase;
+}
+
+// Sent only to the referee after rotate_key
+export interface KeyRotatedMessage {
+  type: string;
+  request_id?: string;
+  seat: Seat;
+  key: string;
+}
+
+// Sent only to the referee after issue_invite
+export interface InviteMessage {
+  type: string;
+  request_id?: string;
+  room_id: string;
+  seat: Seat;
+  token: string;
+  expires_at: number; // unix seconds
+}
+
+// Sent to clients whose key was rotated, they are now spectators
+export interface SeatRevokedMessage {
+  type: string;
+  room_id: string;
+  seat: Seat;
+}
+
+// Team-only channel; the server delivers it to the sender's team and keeps it
+// in the room history for post-match review
+export interface TeamChatMessage {
+  type: string;
+  request_id?: string;
+  text: string;
+}
+
+export interface SuggestMessage {
+  type: string;
+  request_id?: string;
+  champion: string;
+  text?: string;
+}
+
+export interface TeamChatPostedMessage {
+  type: string;
+  team: "blue" | "red";
+  nickname?: string;
+  role: TeamRole;
+  text: string;
+  at: number; // unix milliseconds
+}
+
+export interface SuggestionPostedMessage {
+  type: string;
+  team: "blue" | "red";
+  nickname?: string;
+  role: TeamRole;
+  champion: string;
+  text?: string;
+  phase: GamePhase;
+  at: number; // unix milliseconds
+}
+
+// Verifiable coin flip: sha256(seed) == commitment, and the winner is blue if
+// the first byte of sha256(seed bytes + room id) is even
+export interface CoinFlip {
+  commitment: string;
+  seed?: string; // revealed once both captains are connected
+  winner?: "blue" | "red";
+}
+
+// Milestones of a room in unix milliseconds, missing until they happen
+export interface DraftTimes {
+  created_at: number;
+  blue_ready_at?: number;
+  red_ready_at?: number;
+  started_at?: number; // first turn of the draft
+  finished_at?: number; // after the trade phase, if any
+}
+
+// When swapped, the team that joined as blue now plays red and vice versa
+export interface SideSelectedMessage {
+  type: string;
+  chooser: "blue" | "red";
+  side: "blue" | "red";
+  swapped: boolean;
+}
+
+// Subscribes to the standings of a tournament; the connection leaves its room
+export interface WatchTournamentMessage {
+  type: string;
+  tournament_id: string;
+  request_id?: string;
+}
+
+export interface BracketSlot {
+  match_id: string;
+  side: "blue" | "red";
+}
+
+export interface MatchGame {
+  number: number;
+  room_id: string;
+  blue_team_id: string;
+  red_team_id: string;
+  status: "drafting" | "finished" | "abandoned";
+  winner?: string; // team id
+  picks?: string[];
+  created_at: number; // unix seconds
+  finished_at?: number;
+}
+
+export interface TournamentMatch {
+  id: string;
+  blue_team_id: string; // empty until the match before it is decided
+  red_team_id: string;
+  winner?: string; // team id
+  bracket_id?: string;
+  stage?: "upper" | "lower" | "grand_final" | "group";
+  round?: number;
+  winner_to?: BracketSlot;
+  loser_to?: BracketSlot;
+  best_of: number;
+  fearless?: boolean;
+  side_selection?: boolean;
+  scheduled_at?: number; // unix seconds
+  status: "scheduled" | "in_progress" | "finished";
+  games: MatchGame[];
+}
+
+export interface Standing {
+  rank: number; // tied teams share a rank
+  team_id: string;
+  name: string;
+  tag?: string;
+  played: number;
+  wins: number;
+  losses: number;
+  game_wins: number;
+  game_losses: number;
+  eliminated?: boolean;
+}
+
+// Standings of a bracket, or of the whole tournament when bracket_id is empty
+export interface BracketStandings {
+  bracket_id?: string;
+  name?: string;
+  format?: "single_elimination" | "double_elimination" | "round_robin";
+  standings: Standing[];
+}
+
+export interface StandingsMessage {
+  type: string;
+  tournament_id: string;
+  overall: BracketStandings;
+  brackets: BracketStandings[];
+  matches: TournamentMatch[];
+}
+
 // Additional types referenced in the messages
 export interface Team {
   name: string;
   bans: string[];
   picks: string[];
+  roster?: Player[];
+  players?: string[]; // player assigned to each pick, "" if unassigned
+  player_ids?: string[]; // roster id of the player of each pick
+  trade_confirmed?: boolean;
+  ban_times?: number[]; // milliseconds each ban took, 0 until played
+  pick_times?: number[]; // milliseconds each pick took, in the order of picks
 }
 
 // Union type for all possible incoming messages
 export type IncomingMessage = 
   | CreateResponseMessage
   | StatusMessage
-  | UserJoinedMessage;
+  | UserJoinedMessage
+  | ErrorMessage
+  | AckMessage
+  | WelcomeMessage
+  | RoomClosedMessage
+  | WaitingForRoomMessage
+  | KeyRotatedMessage
+  | InviteMessage
+  | SeatRevokedMessage
+  | PresenceMessage
+  | TeamHoverMessage
+  | TeamChatPostedMessage
+  | SuggestionPostedMessage
+  | SideSelectedMessage
+  | StandingsMessage;
 
 // Union type for all possible outgoing messages
 export type OutgoingMessage = 
+  | HelloMessage
   | CreateMessage
   | JoinMessage
-  | ActionMessage;
+  | ActionMessage
+  | TeamChatMessage
+  | SuggestMessage
+  | WatchTournamentMessage;
 
 // Message type constants for easier usage
 export const MessageTypes = {
@@ -82,6 +452,23 @@ export const MessageTypes = {
   ACTION: "action",
   STATUS: "status",
   USER_JOINED: "user_joined",
+  ERROR: "error",
+  ACK: "ack",
+  HELLO: "hello",
+  WELCOME: "welcome",
+  ROOM_CLOSED: "room_closed",
+  WAITING_FOR_ROOM: "waiting_for_room",
+  KEY_ROTATED: "key_rotated",
+  INVITE: "invite",
+  SEAT_REVOKED: "seat_revoked",
+  PRESENCE: "presence",
+  TEAM_HOVER: "team_hover",
+  TEAM_CHAT: "team_chat",
+  SUGGEST: "suggest",
+  SUGGESTION: "suggestion",
+  SIDE_SELECTED: "side_selected",
+  WATCH_TOURNAMENT: "watch_tournament",
+  STANDINGS: "standings",
 } as const;
 
 export const Status = {
@@ -93,9 +480,11 @@ export const Status = {
 } as const;
 
 export const PossiblePhases = {
+  SIDE_SELECTION: "SideSelection",
   NO_READY: "NoReady",
   BLUE_READY: "BlueReady", 
   RED_READY: "RedReady",
+  COUNTDOWN: "Countdown", // both ready, time_remaining counts down to the first turn
   BAN_BLUE_1: "BanBlue1",
   BAN_RED_1: "BanRed1",
   BAN_BLUE_2: "BanBlue2",
@@ -112,6 +501,7 @@ export const PossiblePhases = {
   BAN_BLUE_5: "BanBlue5",
   PICK_BLUE_3: "PickBlue3",
   PICK_RED_3: "PickRed3",
+  TRADING: "Trading", // optional, teams reorder and assign their picks
   FINISHED: "Finished",
 } as const;
 